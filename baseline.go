@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// Smallest standard deviation deviations are measured against, so a host
+// whose usage has barely moved, such as an idle one, does not alert on a
+// fraction of a percent
+const baselineMinStddev = 1.0
+
+// Struct to hold the running mean and variance of the CPU usage seen in one
+// hour of the day, updated with Welford's algorithm
+type BaselineBucket struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	M2    float64 `json:"m2"`
+}
+
+// Function to learn a value into the bucket
+func (b *BaselineBucket) add(v float64) {
+	b.Count++
+	delta := v - b.Mean
+	b.Mean += delta / float64(b.Count)
+	b.M2 += delta * (v - b.Mean)
+}
+
+// Function to get the standard deviation of the learned values, no smaller
+// than baselineMinStddev
+func (b BaselineBucket) stddev() float64 {
+	if b.Count == 0 {
+		return baselineMinStddev
+	}
+	return math.Max(math.Sqrt(b.M2/float64(b.Count)), baselineMinStddev)
+}
+
+// Function to get how many standard deviations a value is above the learned
+// mean, negative when below it
+func (b BaselineBucket) sigmas(v float64) float64 {
+	return (v - b.Mean) / b.stddev()
+}
+
+// Function to get the baseline bucket for the local hour of day of a time,
+// allocating the buckets on first use
+func (s *State) baselineBucket(t time.Time) *BaselineBucket {
+	if len(s.Baseline) != 24 {
+		s.Baseline = make([]BaselineBucket, 24)
+	}
+	return &s.Baseline[t.Hour()]
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaselineBucket(t *testing.T) {
+	assert := assert.New(t)
+
+	var b BaselineBucket
+	assert.Equal(baselineMinStddev, b.stddev())
+	for _, v := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		b.add(v)
+	}
+	assert.Equal(8, b.Count)
+	assert.InDelta(5, b.Mean, 1e-9)
+	assert.InDelta(2, b.stddev(), 1e-9)
+	assert.InDelta(3, b.sigmas(11), 1e-9)
+	assert.InDelta(-1, b.sigmas(3), 1e-9)
+
+	// A flat baseline is measured against the minimum deviation
+	var idle BaselineBucket
+	for i := 0; i < 10; i++ {
+		idle.add(0.5)
+	}
+	assert.Equal(baselineMinStddev, idle.stddev())
+	assert.InDelta(3, idle.sigmas(3.5), 1e-9)
+}
+
+func TestStateBaselineBucket(t *testing.T) {
+	assert := assert.New(t)
+
+	var state State
+	at3 := time.Date(2024, 5, 2, 3, 12, 0, 0, time.Local)
+	state.baselineBucket(at3).add(40)
+	state.baselineBucket(at3.Add(30 * time.Minute)).add(60)
+	state.baselineBucket(at3.Add(time.Hour)).add(10)
+	assert.Len(state.Baseline, 24)
+	assert.Equal(BaselineBucket{Count: 2, Mean: 50, M2: 200}, state.Baseline[3])
+	assert.Equal(1, state.Baseline[4].Count)
+	assert.Equal(0, state.Baseline[5].Count)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/sensu/sensu-go/types"
+)
+
+// Struct to hold the options of the calibrate subcommand
+type CalibrateConfig struct {
+	Margin float64
+}
+
+var (
+	calibrate = CalibrateConfig{}
+
+	// The range defaults to a week rather than the hour of history and
+	// replay, so the thresholds cover the daily and weekly peaks
+	calibrateOptions = []*sensu.PluginConfigOption{
+		{
+			Path:     "since",
+			Argument: "since",
+			Default:  "168h",
+			Usage:    "Start of the results of --history-file or --history-db to calibrate on, as a duration ago, an RFC 3339 time or a local YYYY-MM-DD HH:MM time",
+			Value:    &historyRange.Since,
+		},
+		{
+			Path:     "until",
+			Argument: "until",
+			Default:  "",
+			Usage:    "End of the results of --history-file or --history-db to calibrate on, in the same forms as --since, now when empty",
+			Value:    &historyRange.Until,
+		},
+		{
+			Path:     "margin",
+			Argument: "margin",
+			Default:  float64(5),
+			Usage:    "Percentage points added to the recorded usage to get the suggested thresholds",
+			Value:    &calibrate.Margin,
+		},
+	}
+)
+
+// Struct to hold the overall CPU thresholds suggested from the used CPU
+// percentages of recorded results
+type Calibration struct {
+	Results  int
+	Stats    SampleStats
+	Warning  float64
+	Critical float64
+}
+
+// Function to suggest overall CPU thresholds from the used CPU percentages
+// of recorded results: the warning threshold sits above the 95th percentile
+// and the critical one above the highest usage, both raised by the margin
+// and rounded up to a whole percentage, at most 100. Usage as recorded would
+// then only warn at its busiest and never be critical.
+func calibrateThresholds(used []float64, margin float64) Calibration {
+	stats := sampleStats(used)
+	return Calibration{
+		Results:  len(used),
+		Stats:    stats,
+		Warning:  math.Min(math.Ceil(stats.P95+margin), 100),
+		Critical: math.Min(math.Ceil(stats.Max+margin), 100),
+	}
+}
+
+// Function to validate the arguments of the calibrate subcommand, which only
+// reads the recorded results
+func calibrateArgs(event *types.Event) (int, error) {
+	warnDeprecatedAnnotations(event)
+	if calibrate.Margin < 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--margin cannot be negative")
+	}
+	return historyRangeArgs(time.Now())
+}
+
+// Function to print the overall CPU thresholds suggested by the results of
+// the recorded results in the time range, along with how they would have
+// alerted on those results
+func executeCalibrate(event *types.Event) (int, error) {
+	records, err := readHistory(historyRange.from, historyRange.to)
+	if err != nil {
+		return sensu.CheckStateCritical, err
+	}
+	from, to := historyRange.from.Format(time.RFC3339), historyRange.to.Format(time.RFC3339)
+	if len(records) == 0 {
+		fmt.Printf("%s OK: no results from %s to %s\n", plugin.PluginConfig.Name, from, to)
+		return sensu.CheckStateOK, nil
+	}
+
+	used := make([]float64, len(records))
+	for i, r := range records {
+		used[i] = r.Usage.Used
+	}
+	c := calibrateThresholds(used, calibrate.Margin)
+	_, now := countReplayed(replayRecords(records, nil, c.Warning, c.Critical, plugin.BreachCount))
+	fmt.Printf("%s OK: suggest --warning %.0f --critical %.0f from %d results from %s to %s (avg %.2f%%, p95 %.2f%%, max %.2f%%), which would have been %d Warning and %d Critical\n",
+		plugin.PluginConfig.Name, c.Warning, c.Critical, c.Results, from, to, c.Stats.Avg, c.Stats.P95, c.Stats.Max,
+		now[sensu.CheckStateWarning], now[sensu.CheckStateCritical])
+	return sensu.CheckStateOK, nil
+}
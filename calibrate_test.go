@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalibrateThresholds(t *testing.T) {
+	assert := assert.New(t)
+	used := make([]float64, 0, 100)
+	for i := 1; i <= 100; i++ {
+		used = append(used, float64(i)*0.8)
+	}
+	c := calibrateThresholds(used, 5)
+	assert.Equal(100, c.Results)
+	assert.InDelta(76, c.Stats.P95, 0.001)
+	assert.Equal(float64(81), c.Warning)
+	assert.Equal(float64(85), c.Critical)
+
+	// Never above 100
+	c = calibrateThresholds([]float64{90, 97, 99}, 5)
+	assert.Equal(float64(100), c.Warning)
+	assert.Equal(float64(100), c.Critical)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sampling frequency of --capture-profile, off the timer tick so samples do
+// not line up with periodic work
+const captureFrequency = 99
+
+// Function to capture a profile of the top offender into --capture-profile:
+// a CPU profile from its pprof endpoint for a Go process with a port mapped
+// in --pprof-port, and otherwise its stacks recorded with perf. Returns the
+// path of the file written.
+func captureTopOffender(pid int32, name string) (string, error) {
+	if port, ok := pprofPortFor(plugin.pprofPorts, name); ok && isGoProcess(pid) {
+		return fetchPprofProfile(port, pid, plugin.captureDuration, plugin.CaptureProfile)
+	}
+	return captureProfile(pid, plugin.captureDuration, plugin.CaptureProfile)
+}
+
+// Function to name the file a capture of a process is written to, after
+// what it holds, the process and the time
+func captureFileName(dir, kind string, pid int32, ext string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s-%d-%s.%s", kind, pid, time.Now().Format("20060102T150405"), ext))
+}
+
+// Function to fold the stacks printed by "perf script -F comm,ip,sym,dso"
+// into one line per distinct stack, the command and then the frames from the
+// outermost in, separated by semicolons, with how many samples had it. This
+// is the input flamegraph.pl and most flame graph viewers take.
+func foldPerfScript(r io.Reader) (map[string]int, error) {
+	stacks := make(map[string]int)
+	var comm string
+	var frames []string
+	flush := func() {
+		if comm != "" {
+			for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+				frames[i], frames[j] = frames[j], frames[i]
+			}
+			stacks[strings.Join(append([]string{comm}, frames...), ";")]++
+		}
+		comm, frames = "", nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+		case line[0] != ' ' && line[0] != '\t':
+			flush()
+			comm = strings.ReplaceAll(strings.TrimSpace(line), ";", "_")
+		default:
+			frames = append(frames, perfFrame(line))
+		}
+	}
+	flush()
+	return stacks, scanner.Err()
+}
+
+// Function to get the frame of a perf script stack line such as
+// "	    7f3a1c2b4d5e do_work (/usr/bin/app)", falling back to the name of the
+// object for an unknown symbol
+func perfFrame(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return "[unknown]"
+	}
+	sym := strings.Join(fields[1:], " ")
+	dso := ""
+	if i := strings.LastIndex(sym, " ("); i >= 0 && strings.HasSuffix(sym, ")") {
+		sym, dso = sym[:i], sym[i+2:len(sym)-1]
+	}
+	if sym == "[unknown]" && dso != "" && dso != "[unknown]" {
+		sym = "[" + path.Base(dso) + "]"
+	}
+	return strings.ReplaceAll(sym, ";", "_")
+}
+
+// Function to write folded stacks, sorted so captures can be diffed
+func writeFoldedStacks(w io.Writer, stacks map[string]int) error {
+	keys := make([]string, 0, len(stacks))
+	for k := range stacks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "%s %d\n", k, stacks[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
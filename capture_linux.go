@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Function to record the on-CPU stacks of a process with perf for the given
+// duration and write them folded into dir. Returns the path of the file
+// written.
+func captureProfile(pid int32, d time.Duration, dir string) (string, error) {
+	data, err := os.CreateTemp("", "cpu-process-profiler-*.data")
+	if err != nil {
+		return "", err
+	}
+	data.Close()
+	defer os.Remove(data.Name())
+
+	ctx := context.Background()
+	if plugin.execTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d+plugin.execTimeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, "perf", "record", "-F", strconv.Itoa(captureFrequency), "-g",
+		"-p", strconv.Itoa(int(pid)), "-o", data.Name(), "--", "sleep", fmt.Sprintf("%.3f", d.Seconds()))
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("perf record: %v: %s", err, msg)
+		}
+		return "", fmt.Errorf("perf record: %v", err)
+	}
+
+	out, err := runCommand("perf", "script", "-F", "comm,ip,sym,dso", "-i", data.Name())
+	if err != nil {
+		return "", fmt.Errorf("perf script: %v", err)
+	}
+	stacks, err := foldPerfScript(out)
+	if err != nil {
+		return "", err
+	}
+
+	name := captureFileName(dir, "cpu-profile", pid, "folded")
+	f, err := os.Create(name)
+	if err != nil {
+		return "", err
+	}
+	if err := writeFoldedStacks(f, stacks); err != nil {
+		f.Close()
+		return "", err
+	}
+	return name, f.Close()
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "time"
+
+// Function to record the on-CPU stacks of a process for the given duration
+// and write them folded into dir
+func captureProfile(pid int32, d time.Duration, dir string) (string, error) {
+	return "", errUnsupported
+}
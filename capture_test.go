@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFoldPerfScript(t *testing.T) {
+	assert := assert.New(t)
+	out := `java
+	    7f3a1c2b4d5e Compressor::deflate (/opt/app/libzip.so)
+	    7f3a1c2b4000 Worker::run (/opt/app/libapp.so)
+	    7f3a1c200000 start_thread (/usr/lib/libc.so.6)
+
+java
+	    7f3a1c2b4d5e Compressor::deflate (/opt/app/libzip.so)
+	    7f3a1c2b4000 Worker::run (/opt/app/libapp.so)
+	    7f3a1c200000 start_thread (/usr/lib/libc.so.6)
+
+C2 CompilerThre
+	    7f3a1d000000 [unknown] (/usr/lib/jvm/libjvm.so)
+	    7f3a1c200000 start_thread (/usr/lib/libc.so.6)
+
+java
+	              0 [unknown] ([unknown])
+`
+	stacks, err := foldPerfScript(strings.NewReader(out))
+	assert.NoError(err)
+	assert.Equal(map[string]int{
+		"java;start_thread;Worker::run;Compressor::deflate": 2,
+		"C2 CompilerThre;start_thread;[libjvm.so]":          1,
+		"java;[unknown]": 1,
+	}, stacks)
+
+	var b bytes.Buffer
+	assert.NoError(writeFoldedStacks(&b, stacks))
+	assert.Equal("C2 CompilerThre;start_thread;[libjvm.so] 1\n"+
+		"java;[unknown] 1\n"+
+		"java;start_thread;Worker::run;Compressor::deflate 2\n", b.String())
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Modes for computing utilization against the cgroup CPU limit
+const (
+	cgroupModeHost   = "host"
+	cgroupModeAuto   = "auto"
+	cgroupModeCgroup = "cgroup"
+)
+
+// Struct to hold a read of the CPU accounting of a cgroup. LimitCores is
+// the CPU quota in cores, or the number of CPUs the cgroup may run on when
+// no quota is set, and Quota tells which of the two it is.
+type CgroupCPU struct {
+	Usage      time.Duration
+	LimitCores float64
+	Quota      bool
+}
+
+// Function to parse a cgroup v2 cpu.max file ("$MAX $PERIOD"), returning the
+// quota in cores or 0 when unlimited
+func parseCPUMax(s string) (float64, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("unexpected cpu.max contents %q", s)
+	}
+	if fields[0] == "max" {
+		return 0, nil
+	}
+	return parseCFSQuota(fields[0], fields[1])
+}
+
+// Function to compute the quota in cores from a CFS quota and period,
+// returning 0 when unlimited
+func parseCFSQuota(quota, period string) (float64, error) {
+	q, err := strconv.ParseInt(strings.TrimSpace(quota), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	p, err := strconv.ParseInt(strings.TrimSpace(period), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if q <= 0 || p <= 0 {
+		return 0, nil
+	}
+	return float64(q) / float64(p), nil
+}
+
+// Function to read usage_usec from a cgroup v2 cpu.stat file
+func parseCPUStatUsage(r io.Reader) (time.Duration, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			us, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(us) * time.Microsecond, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("usage_usec not found in cpu.stat")
+}
+
+// Function to count the CPUs of a cpuset list such as "0-3,8,10-11"
+func parseCPUSetCount(s string) (int, error) {
+	cpus, err := parseCPUList(s)
+	return len(cpus), err
+}
+
+// Function to list the CPUs of a cpuset list such as "0-3,8,10-11"
+func parseCPUList(s string) ([]int, error) {
+	var cpus []int
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		lo, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		hi := lo
+		if len(bounds) == 2 {
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, err
+			}
+		}
+		if hi < lo {
+			return nil, fmt.Errorf("invalid cpu range %q", part)
+		}
+		for cpu := lo; cpu <= hi; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// Function to parse /proc/self/cgroup, returning the cgroup v2 path and the
+// cgroup v1 path of the cpu controller, either of which may be empty
+func parseProcCgroup(r io.Reader) (v2, v1cpu string, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			v2 = parts[2]
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			if controller == "cpu" {
+				v1cpu = parts[2]
+			}
+		}
+	}
+	return v2, v1cpu, scanner.Err()
+}
+
+// Function to compute utilization of the cgroup limit between two reads
+func cgroupUsedPct(start, end CgroupCPU, elapsed time.Duration) float64 {
+	if elapsed <= 0 || end.LimitCores <= 0 {
+		return 0
+	}
+	return float64(end.Usage-start.Usage) / (float64(elapsed) * end.LimitCores) * 100
+}
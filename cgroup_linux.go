@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Root of the cgroup filesystem
+const cgroupRoot = "/sys/fs/cgroup"
+
+// Function to read the CPU accounting of the cgroup this process runs in
+func readCgroupCPU() (CgroupCPU, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return CgroupCPU{}, err
+	}
+	v2, v1cpu, err := parseProcCgroup(f)
+	f.Close()
+	if err != nil {
+		return CgroupCPU{}, err
+	}
+
+	if v2 != "" {
+		if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err == nil {
+			return readCgroupV2CPU(cgroupDir(cgroupRoot, v2, "cpu.max"))
+		}
+	}
+	if v1cpu != "" {
+		// The cpu and cpuacct controllers are usually co-mounted, but some
+		// hosts mount them separately
+		cpuBase := cgroupMount("cpu,cpuacct", "cpu")
+		acctBase := cgroupMount("cpu,cpuacct", "cpuacct")
+		if cpuBase != "" && acctBase != "" {
+			return readCgroupV1CPU(cgroupDir(cpuBase, v1cpu, "cpu.cfs_quota_us"), cgroupDir(acctBase, v1cpu, "cpuacct.usage"))
+		}
+	}
+	return CgroupCPU{}, errors.New("no cgroup cpu controller found")
+}
+
+// Function to find the first of the given cgroup v1 controller mounts present
+func cgroupMount(mounts ...string) string {
+	for _, mount := range mounts {
+		base := filepath.Join(cgroupRoot, mount)
+		if _, err := os.Stat(base); err == nil {
+			return base
+		}
+	}
+	return ""
+}
+
+// Function to find the directory of a cgroup. With a cgroup namespace, as in
+// most containers, the path in /proc/self/cgroup is not visible and the
+// mount root is the cgroup itself.
+func cgroupDir(base, path, probe string) string {
+	dir := filepath.Join(base, path)
+	if _, err := os.Stat(filepath.Join(dir, probe)); err == nil {
+		return dir
+	}
+	return base
+}
+
+func readCgroupV2CPU(dir string) (CgroupCPU, error) {
+	var cg CgroupCPU
+	f, err := os.Open(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return cg, err
+	}
+	cg.Usage, err = parseCPUStatUsage(f)
+	f.Close()
+	if err != nil {
+		return cg, err
+	}
+
+	// The root cgroup has no cpu.max
+	if data, err := os.ReadFile(filepath.Join(dir, "cpu.max")); err == nil {
+		if cg.LimitCores, err = parseCPUMax(string(data)); err != nil {
+			return cg, err
+		}
+	}
+	return cgroupLimit(cg, filepath.Join(dir, "cpuset.cpus.effective"))
+}
+
+func readCgroupV1CPU(dir, acctDir string) (CgroupCPU, error) {
+	var cg CgroupCPU
+	data, err := os.ReadFile(filepath.Join(acctDir, "cpuacct.usage"))
+	if err != nil {
+		return cg, err
+	}
+	ns, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return cg, err
+	}
+	cg.Usage = time.Duration(ns)
+
+	quota, qerr := os.ReadFile(filepath.Join(dir, "cpu.cfs_quota_us"))
+	period, perr := os.ReadFile(filepath.Join(dir, "cpu.cfs_period_us"))
+	if qerr == nil && perr == nil {
+		if cg.LimitCores, err = parseCFSQuota(string(quota), string(period)); err != nil {
+			return cg, err
+		}
+	}
+	return cgroupLimit(cg, filepath.Join(cgroupRoot, "cpuset", "cpuset.effective_cpus"))
+}
+
+// Function to fall back to the number of usable CPUs when no quota is set
+func cgroupLimit(cg CgroupCPU, cpusetFile string) (CgroupCPU, error) {
+	if cg.LimitCores > 0 {
+		cg.Quota = true
+		return cg, nil
+	}
+	if data, err := os.ReadFile(cpusetFile); err == nil {
+		if n, err := parseCPUSetCount(string(data)); err == nil && n > 0 {
+			cg.LimitCores = float64(n)
+			return cg, nil
+		}
+	}
+	cg.LimitCores = float64(runtime.NumCPU())
+	return cg, nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// Function to read the CPU accounting of the cgroup this process runs in
+func readCgroupCPU() (CgroupCPU, error) {
+	return CgroupCPU{}, errUnsupported
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCPUMax(t *testing.T) {
+	assert := assert.New(t)
+	cores, err := parseCPUMax("200000 100000\n")
+	assert.NoError(err)
+	assert.Equal(2.0, cores)
+	cores, err = parseCPUMax("max 100000\n")
+	assert.NoError(err)
+	assert.Equal(0.0, cores)
+	_, err = parseCPUMax("garbage")
+	assert.Error(err)
+
+	cores, err = parseCFSQuota("-1\n", "100000\n")
+	assert.NoError(err)
+	assert.Equal(0.0, cores)
+	cores, err = parseCFSQuota("50000", "100000")
+	assert.NoError(err)
+	assert.Equal(0.5, cores)
+}
+
+func TestParseCPUStatUsage(t *testing.T) {
+	assert := assert.New(t)
+	usage, err := parseCPUStatUsage(strings.NewReader("usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\n"))
+	assert.NoError(err)
+	assert.Equal(1500*time.Millisecond, usage)
+	_, err = parseCPUStatUsage(strings.NewReader("user_usec 1\n"))
+	assert.Error(err)
+}
+
+func TestParseCPUSetCount(t *testing.T) {
+	assert := assert.New(t)
+	n, err := parseCPUSetCount("0-3,8,10-11\n")
+	assert.NoError(err)
+	assert.Equal(7, n)
+	_, err = parseCPUSetCount("3-1")
+	assert.Error(err)
+}
+
+func TestParseCPUList(t *testing.T) {
+	assert := assert.New(t)
+	cpus, err := parseCPUList("0-3,8,10-11\n")
+	assert.NoError(err)
+	assert.Equal([]int{0, 1, 2, 3, 8, 10, 11}, cpus)
+	cpus, err = parseCPUList("")
+	assert.NoError(err)
+	assert.Empty(cpus)
+	_, err = parseCPUList("0,x")
+	assert.Error(err)
+}
+
+func TestParseProcCgroup(t *testing.T) {
+	assert := assert.New(t)
+	v2, v1, err := parseProcCgroup(strings.NewReader("0::/system.slice/sensu-agent.service\n"))
+	assert.NoError(err)
+	assert.Equal("/system.slice/sensu-agent.service", v2)
+	assert.Equal("", v1)
+
+	v2, v1, err = parseProcCgroup(strings.NewReader("12:cpuset:/docker/abc\n4:cpu,cpuacct:/docker/abc\n1:name=systemd:/docker/abc\n"))
+	assert.NoError(err)
+	assert.Equal("", v2)
+	assert.Equal("/docker/abc", v1)
+}
+
+func TestCgroupUsedPct(t *testing.T) {
+	assert := assert.New(t)
+	start := CgroupCPU{Usage: time.Second, LimitCores: 2, Quota: true}
+	end := CgroupCPU{Usage: 3 * time.Second, LimitCores: 2, Quota: true}
+	assert.InDelta(50, cgroupUsedPct(start, end, 2*time.Second), 0.001)
+	assert.Equal(0.0, cgroupUsedPct(start, end, 0))
+}
@@ -0,0 +1,802 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// Struct to hold everything collected and evaluated in one check run
+type Result struct {
+	Timestamp   time.Time       `json:"timestamp"`
+	Status      int             `json:"status"`
+	Summary     string          `json:"summary"`
+	Usage       CPUUsage        `json:"usage"`
+	Metrics     []Metric        `json:"metrics"`
+	Processes   []ProcessInfo   `json:"processes"`
+	Threads     []ThreadInfo    `json:"threads,omitempty"`
+	Breached    []string        `json:"breached,omitempty"`
+	Fingerprint string          `json:"fingerprint,omitempty"`
+	Disabled    []string        `json:"disabled,omitempty"`
+	Groups      []ProcessGroup  `json:"process_groups,omitempty"`
+	OffCPU      []OffCPUProcess `json:"off_cpu_processes,omitempty"`
+	Profile     string          `json:"profile,omitempty"`
+	ThreadDump  string          `json:"thread_dump,omitempty"`
+	DState      []DStateProcess `json:"dstate_processes,omitempty"`
+	Zombies     []ZombieParent  `json:"zombie_parents,omitempty"`
+	Watched     []ProcessInfo   `json:"watched_processes,omitempty"`
+	Units       []UnitUsage     `json:"units,omitempty"`
+}
+
+// Function to collect and evaluate everything for one check run, recording
+// its progress as it goes
+func runCheck(progress *checkProgress) (*Result, error) {
+	progress.setStage("waiting for the start jitter")
+	sleepStartJitter(plugin.jitterDuration)
+	if plugin.TargetPID > 0 || plugin.TargetUnit != "" {
+		return runTargetCheck(progress)
+	}
+
+	progress.setStage("sampling CPU")
+
+	start, err := cpu.Times(false)
+	if err != nil {
+		return nil, fmt.Errorf("Error obtaining CPU timings: %v", err)
+	}
+	var coresStart []cpu.TimesStat
+	if plugin.usesPerCPU() {
+		if coresStart, err = cpu.Times(true); err != nil {
+			return nil, fmt.Errorf("Error obtaining per-CPU timings: %v", err)
+		}
+	}
+	startClocks := readClocks()
+	startTime := startClocks.Wall
+	processTimes, err := processCPUTimes()
+	if err != nil {
+		return nil, fmt.Errorf("Error obtaining process CPU timings: %v", err)
+	}
+
+	// System activity counters are only available on Linux, elsewhere the
+	// metrics are left out. They are read along with every sub-sample so
+	// process counts can be tracked during the interval.
+	var statReads []ProcStat
+	if stat, err := readProcStat(); err == nil {
+		statReads = append(statReads, stat)
+	}
+
+	// Frequencies are read along with every sub-sample as well, to catch a
+	// CPU downclocking during the interval
+	var freqReads []CPUFrequencies
+	if plugin.Frequency {
+		freqs, err := readCPUFrequencies()
+		if err != nil {
+			return nil, fmt.Errorf("Error reading CPU frequencies: %v", err)
+		}
+		freqReads = append(freqReads, freqs)
+	}
+
+	// Processes in D state are sampled along with every sub-sample too, as
+	// they usually do not stay in it for long
+	var dstate DStateSamples
+	if plugin.usesDState() {
+		sample, err := readDStateProcesses()
+		if err != nil {
+			return nil, fmt.Errorf("Error reading process states: %v", err)
+		}
+		dstate.add(sample)
+	}
+
+	var raplStart []RAPLZone
+	if plugin.RAPL {
+		if raplStart, err = readRAPL(); err != nil {
+			return nil, fmt.Errorf("Error reading RAPL energy counters: %v", err)
+		}
+	}
+
+	var throttleStart ThrottleCounts
+	if plugin.usesThrottling() {
+		if throttleStart, err = readThrottleCounts(); err != nil {
+			return nil, fmt.Errorf("Error reading throttling counters: %v", err)
+		}
+	}
+
+	// perf stat counts hardware events alongside the sampling, for as long
+	// as the interval
+	var perfCounters PerfCounters
+	var perfDone chan error
+	if plugin.Perf {
+		perfDone = make(chan error, 1)
+		go func() {
+			var err error
+			perfCounters, err = runPerfStat(plugin.intervalDuration)
+			perfDone <- err
+		}()
+	}
+
+	// The off-CPU collector traces the scheduler for as long as the
+	// interval too
+	var offCPU []OffCPUProcess
+	var offCPUDone chan error
+	if plugin.OffCPU {
+		offCPUDone = make(chan error, 1)
+		go func() {
+			var err error
+			offCPU, err = runOffCPU(plugin.intervalDuration, 10)
+			offCPUDone <- err
+		}()
+	}
+
+	var cgStart CgroupCPU
+	if plugin.useCgroup {
+		if cgStart, err = readCgroupCPU(); err != nil {
+			return nil, fmt.Errorf("Error reading cgroup CPU usage: %v", err)
+		}
+	}
+
+	// Split the interval into sub-samples so a brief spike can be told apart
+	// from sustained usage
+	subDuration := plugin.intervalDuration / time.Duration(plugin.sampleCount)
+	subUsed := make([]float64, 0, plugin.sampleCount)
+	prev, cgPrev, prevTime := start, cgStart, startTime
+	for i := 0; i < plugin.sampleCount; i++ {
+		time.Sleep(subDuration)
+
+		cur, err := cpu.Times(false)
+		if err != nil {
+			return nil, fmt.Errorf("Error obtaining CPU timings: %v", err)
+		}
+		if plugin.useCgroup {
+			cgCur, err := readCgroupCPU()
+			if err != nil {
+				return nil, fmt.Errorf("Error reading cgroup CPU usage: %v", err)
+			}
+			now := time.Now()
+			subUsed = append(subUsed, cgroupUsedPct(cgPrev, cgCur, now.Sub(prevTime)))
+			cgPrev, prevTime = cgCur, now
+		} else if totalCPUTime(cur[0]) > totalCPUTime(prev[0]) {
+			// Skip a sub-sample that ended within the clock tick it started in
+			subUsed = append(subUsed, cpuUsage(prev[0], cur[0]).Used)
+		}
+		prev = cur
+
+		if len(statReads) > 0 {
+			if stat, err := readProcStat(); err == nil {
+				statReads = append(statReads, stat)
+			}
+		}
+		if len(freqReads) > 0 {
+			if freqs, err := readCPUFrequencies(); err == nil {
+				freqReads = append(freqReads, freqs)
+			}
+		}
+		if dstate.Samples > 0 {
+			if sample, err := readDStateProcesses(); err == nil {
+				dstate.add(sample)
+			}
+		}
+	}
+	end := prev
+	var cores []CoreUsage
+	if plugin.usesPerCPU() {
+		coresEnd, err := cpu.Times(true)
+		if err != nil {
+			return nil, fmt.Errorf("Error obtaining per-CPU timings: %v", err)
+		}
+		cores = coreUsage(coresStart, coresEnd)
+	}
+	var raplEnd []RAPLZone
+	if plugin.RAPL {
+		if raplEnd, err = readRAPL(); err != nil {
+			return nil, fmt.Errorf("Error reading RAPL energy counters: %v", err)
+		}
+	}
+	var throttleEnd ThrottleCounts
+	if plugin.usesThrottling() {
+		if throttleEnd, err = readThrottleCounts(); err != nil {
+			return nil, fmt.Errorf("Error reading throttling counters: %v", err)
+		}
+	}
+	if perfDone != nil {
+		if err := <-perfDone; err != nil {
+			return nil, fmt.Errorf("Error running perf stat: %v", err)
+		}
+	}
+	if offCPUDone != nil {
+		if err := <-offCPUDone; err != nil {
+			return nil, fmt.Errorf("Error collecting off-CPU time: %v", err)
+		}
+	}
+	endClocks := readClocks()
+	elapsed := endClocks.Wall.Sub(startTime)
+
+	usage := cpuUsage(start[0], end[0])
+	usedPct := usage.Used
+	metrics := usageMetrics(usage)
+
+	// In cgroup mode utilization is measured against the cgroup limit, the
+	// breakdown is still that of the host
+	limit := ""
+	if plugin.useCgroup {
+		usedPct = cgroupUsedPct(cgStart, cgPrev, elapsed)
+		limit = fmt.Sprintf(" of %.2f core cgroup limit", cgPrev.LimitCores)
+		metrics = append(metrics,
+			Metric{"cgroup_cpu_used", usedPct},
+			Metric{"cgroup_cpu_limit_cores", cgPrev.LimitCores},
+		)
+	}
+
+	summary := fmt.Sprintf("%.2f%% CPU usage%s", usedPct, limit)
+	if plugin.sampleCount > 1 {
+		stats := sampleStats(subUsed)
+		usedPct = stats.Avg
+		summary = fmt.Sprintf("%.2f%% CPU usage%s (min %.2f%%, max %.2f%%, p95 %.2f%% over %d samples)", stats.Avg, limit, stats.Min, stats.Max, stats.P95, plugin.sampleCount)
+		metrics = append(metrics,
+			Metric{"cpu_used_avg", stats.Avg},
+			Metric{"cpu_used_min", stats.Min},
+			Metric{"cpu_used_max", stats.Max},
+			Metric{"cpu_used_p95", stats.P95},
+		)
+		if plugin.SpikeThreshold > 0 {
+			spikes := countSpikes(subUsed, plugin.SpikeThreshold)
+			summary += fmt.Sprintf(", %d spikes over %g%%", spikes, plugin.SpikeThreshold)
+			metrics = append(metrics, Metric{"cpu_spikes", float64(spikes)})
+		}
+	}
+
+	metrics = append(metrics, systemActivityMetrics(statReads, elapsed)...)
+	metrics = append(metrics, frequencyMetrics(freqReads)...)
+	metrics = append(metrics, raplMetrics(raplStart, raplEnd, elapsed)...)
+	if plugin.Perf {
+		metrics = append(metrics, perfCounters.metrics()...)
+		if s := perfCounters.String(); s != "" {
+			summary += ", " + s
+		}
+	}
+	progress.update("listing processes", Result{Summary: summary, Usage: usage, Metrics: metrics})
+
+	var state State
+	if plugin.usesState() {
+		state, err = loadState(plugin.StateFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading state file: %v", err)
+		}
+	}
+
+	now := time.Now()
+	specs, windows, err := readSuppressions()
+	if err != nil {
+		return nil, err
+	}
+	suppressWindow, inWindow := activeSuppressWindow(windows, now)
+	suppressions, err := resolveSuppressions(specs, &state, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get top processes irrespective of the CPU state
+	processList, err := getProcesses(processTimes, time.Since(startTime))
+	if err != nil {
+		return nil, fmt.Errorf("Error obtaining top CPU processes: %v", err)
+	}
+	// Rollups need every process attributed, otherwise only the listed ones
+	var containers map[string]ContainerInfo
+	if plugin.DockerSocket != "" {
+		if containers, err = dockerContainers(plugin.DockerSocket); err != nil {
+			return nil, fmt.Errorf("Error listing Docker containers: %v", err)
+		}
+	} else if plugin.CRISocket != "" {
+		if containers, err = criContainers(plugin.CRISocket); err != nil {
+			return nil, fmt.Errorf("Error listing CRI containers: %v", err)
+		}
+	}
+	// The thread count is only read where the kernel keeps it for free
+	threads, err := readThreadCount()
+	if err != nil {
+		threads = -1
+	}
+	metrics = append(metrics, taskMetrics(len(processList), threads)...)
+	if containers != nil && plugin.DockerRollup {
+		attributeContainers(processList, containers)
+		metrics = append(metrics, containerMetrics(processList)...)
+	}
+
+	var topProcesses []ProcessInfo
+	if plugin.RankBy == rankByGrowth {
+		topProcesses = topGrowthProcesses(processList, &state, now, 10)
+	} else {
+		topProcesses = topCPUProcesses(processList, 10)
+	}
+	attributePods(topProcesses)
+	if containers != nil && !plugin.DockerRollup {
+		attributeContainers(topProcesses, containers)
+	}
+	metrics = append(metrics, podMetrics(topProcesses)...)
+
+	// Suppressed processes are still listed, but are never blamed as the top
+	// offender of an alert
+	topOffender := ""
+	var topOffenderPID int32
+	for i, p := range topProcesses {
+		if expires, ok := suppressedUntil(suppressions, p.Name, now); ok {
+			topProcesses[i].SuppressedUntil = &expires
+			continue
+		}
+		if topOffender == "" {
+			topOffender, topOffenderPID = p.Name, p.PID
+		}
+	}
+	progress.update("evaluating thresholds", Result{Summary: summary, Usage: usage, Metrics: metrics, Processes: topProcesses})
+
+	var groups []ProcessGroup
+	if plugin.ProcessEvents {
+		groups = processGroups(processList, suppressions, &state, now)
+		if inWindow {
+			for i := range groups {
+				groups[i].Status = sensu.CheckStateOK
+			}
+			state.AlertingGroups = nil
+		}
+	}
+
+	// The moving average is evaluated including this run, but only kept once
+	// the run is known not to span a suspend
+	evaluated := usedPct
+	var smoothed float64
+	if plugin.EWMAAlpha > 0 {
+		smoothed = state.smoothedUsage(usedPct, plugin.EWMAAlpha)
+		metrics = append(metrics, Metric{"cpu_used_ewma", smoothed})
+		summary += fmt.Sprintf(", %.2f%% moving average", smoothed)
+		if plugin.EWMAThresholds {
+			evaluated = smoothed
+		}
+	}
+
+	var eval Evaluation
+	warning, critical, window := profileThresholds(plugin.thresholdProfiles, now, plugin.Warning, plugin.Critical)
+	if evaluated > critical {
+		eval.breach("cpu_critical", sensu.CheckStateCritical)
+	} else if evaluated > warning {
+		eval.breach("cpu_warning", sensu.CheckStateWarning)
+	}
+	if window != "" {
+		summary += fmt.Sprintf(" (thresholds %g/%g%% for %s)", warning, critical, window)
+	}
+	logical, err := cpu.Counts(true)
+	if err != nil {
+		return nil, fmt.Errorf("Error obtaining CPU count: %v", err)
+	}
+	capacity := float64(logical)
+	if plugin.useCgroup {
+		capacity = cgPrev.LimitCores
+	}
+	if plugin.CriticalCores > 0 || plugin.WarningCores > 0 {
+		busyCores := usedPct / 100 * capacity
+		if plugin.CriticalCores > 0 && busyCores > plugin.CriticalCores {
+			eval.breach("cores_critical", sensu.CheckStateCritical)
+		} else if plugin.WarningCores > 0 && busyCores > plugin.WarningCores {
+			eval.breach("cores_warning", sensu.CheckStateWarning)
+		}
+		summary += fmt.Sprintf(", %.2f of %.2f cores busy", busyCores, capacity)
+		metrics = append(metrics, Metric{"cpu_cores_busy", busyCores})
+	}
+
+	// Load averages are not available everywhere, they are only required
+	// when load thresholds are set
+	loadAvg, err := readLoadAvg(plugin.LoadPerCore, logical)
+	if err == nil {
+		metrics = append(metrics, loadMetrics(loadAvg, plugin.LoadPerCore)...)
+	} else if plugin.loadCritical != nil || plugin.loadWarning != nil {
+		return nil, fmt.Errorf("Error obtaining load averages: %v", err)
+	}
+	if plugin.loadCritical != nil && plugin.loadCritical.exceededBy(loadAvg) {
+		eval.breach("load_critical", sensu.CheckStateCritical)
+	} else if plugin.loadWarning != nil && plugin.loadWarning.exceededBy(loadAvg) {
+		eval.breach("load_warning", sensu.CheckStateWarning)
+	}
+	if plugin.loadCritical != nil || plugin.loadWarning != nil {
+		summary += fmt.Sprintf(", load %.2f %.2f %.2f", loadAvg[0], loadAvg[1], loadAvg[2])
+	}
+
+	burstName, burstCount := startBurst(processList, startTime)
+	metrics = append(metrics, Metric{"process_start_burst_max", float64(burstCount)})
+	if plugin.BurstCritical > 0 && burstCount > plugin.BurstCritical {
+		eval.breach("burst_critical", sensu.CheckStateCritical)
+	} else if plugin.BurstWarning > 0 && burstCount > plugin.BurstWarning {
+		eval.breach("burst_warning", sensu.CheckStateWarning)
+	}
+	if (plugin.BurstCritical > 0 || plugin.BurstWarning > 0) && burstCount > 0 {
+		summary += fmt.Sprintf(", %d new %s processes", burstCount, burstName)
+	}
+
+	if plugin.lockupDuration > 0 {
+		records, now, err := readKmsg()
+		if err != nil {
+			return nil, fmt.Errorf("Error reading kernel log: %v", err)
+		}
+		counts := countLockups(records, now-plugin.lockupDuration)
+		metrics = append(metrics, lockupMetrics(counts)...)
+		if counts.SoftLockups > 0 || counts.RCUStalls > 0 {
+			if plugin.LockupCrit {
+				eval.breach("kernel_lockup", sensu.CheckStateCritical)
+			}
+			summary += fmt.Sprintf(", %d soft lockups and %d RCU stalls in the last %s", counts.SoftLockups, counts.RCUStalls, plugin.lockupDuration)
+		}
+	}
+
+	for _, resource := range plugin.PSI {
+		psi, err := readPSI(resource)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading %s pressure: %v", resource, err)
+		}
+		metrics = append(metrics, psiMetrics(resource, psi)...)
+		if plugin.PSICritical > 0 && psi.Some.Avg10 > plugin.PSICritical {
+			eval.breach("psi_"+resource+"_critical", sensu.CheckStateCritical)
+		} else if plugin.PSIWarning > 0 && psi.Some.Avg10 > plugin.PSIWarning {
+			eval.breach("psi_"+resource+"_warning", sensu.CheckStateWarning)
+		}
+		if plugin.PSICritical > 0 || plugin.PSIWarning > 0 {
+			summary += fmt.Sprintf(", %s pressure %.2f%%", resource, psi.Some.Avg10)
+		}
+	}
+
+	if plugin.StealCritical > 0 && usage.Steal > plugin.StealCritical {
+		eval.breach("steal_critical", sensu.CheckStateCritical)
+	} else if plugin.StealWarning > 0 && usage.Steal > plugin.StealWarning {
+		eval.breach("steal_warning", sensu.CheckStateWarning)
+	}
+	if plugin.StealCritical > 0 || plugin.StealWarning > 0 {
+		summary += fmt.Sprintf(", %.2f%% steal", usage.Steal)
+	}
+
+	// A single pegged core only stands out as a bottleneck while the others
+	// have room to spare
+	if plugin.CoreCritical > 0 || plugin.CoreWarning > 0 {
+		busiest := busiestCore(cores)
+		metrics = append(metrics, Metric{"cpu_core_used_max", busiest.Used})
+		if plugin.CoreOverallMax == 0 || usedPct < plugin.CoreOverallMax {
+			if plugin.CoreCritical > 0 && busiest.Used > plugin.CoreCritical {
+				eval.breach("core_critical", sensu.CheckStateCritical)
+			} else if plugin.CoreWarning > 0 && busiest.Used > plugin.CoreWarning {
+				eval.breach("core_warning", sensu.CheckStateWarning)
+			}
+		}
+		summary += fmt.Sprintf(", busiest CPU %s at %.2f%%", busiest.Name, busiest.Used)
+	}
+	if plugin.usesTemperature() {
+		temps, err := readCPUTemperatures()
+		if err != nil {
+			return nil, fmt.Errorf("Error reading CPU temperatures: %v", err)
+		}
+		metrics = append(metrics, temperatureMetrics(temps)...)
+		hottest := hottestPackage(temps)
+		if plugin.TemperatureCritical > 0 && hottest.Celsius > plugin.TemperatureCritical {
+			eval.breach("temperature_critical", sensu.CheckStateCritical)
+		} else if plugin.TemperatureWarning > 0 && hottest.Celsius > plugin.TemperatureWarning {
+			eval.breach("temperature_warning", sensu.CheckStateWarning)
+		}
+		if plugin.TemperatureCritical > 0 || plugin.TemperatureWarning > 0 {
+			summary += fmt.Sprintf(", package %d at %.1f°C", hottest.Package, hottest.Celsius)
+		}
+	}
+	var dstateProcesses []DStateProcess
+	if plugin.usesDState() {
+		metrics = append(metrics, dstate.metrics()...)
+		dstateProcesses = dstate.top(10)
+		if plugin.DStateCritical > 0 && dstate.Max > plugin.DStateCritical {
+			eval.breach("dstate_critical", sensu.CheckStateCritical)
+		} else if plugin.DStateWarning > 0 && dstate.Max > plugin.DStateWarning {
+			eval.breach("dstate_warning", sensu.CheckStateWarning)
+		}
+		summary += fmt.Sprintf(", up to %d processes in D state", dstate.Max)
+	}
+	if plugin.ForkRateCritical > 0 || plugin.ForkRateWarning > 0 {
+		if forks, ok := forkRate(statReads, elapsed); ok {
+			if plugin.ForkRateCritical > 0 && forks > plugin.ForkRateCritical {
+				eval.breach("fork_rate_critical", sensu.CheckStateCritical)
+			} else if plugin.ForkRateWarning > 0 && forks > plugin.ForkRateWarning {
+				eval.breach("fork_rate_warning", sensu.CheckStateWarning)
+			}
+			summary += fmt.Sprintf(", %.1f forks/s", forks)
+		}
+	}
+	if plugin.OOMScore {
+		readProcessOOMScores(topProcesses)
+	}
+	if plugin.usesNice() {
+		readProcessPriorities(topProcesses)
+	}
+	if plugin.usesRTSched() {
+		readProcessSched(topProcesses)
+		if plugin.RTCritical > 0 {
+			if runaway := runawayRealtime(topProcesses, plugin.RTCritical); runaway != nil {
+				eval.breach("rt_critical", sensu.CheckStateCritical)
+				summary += fmt.Sprintf(", %s (PID %d) at %.2f%% under %s", runaway.Name, runaway.PID, runaway.CPU, runaway.Sched.Policy)
+			}
+		}
+	}
+	if plugin.usesFDs() {
+		countProcessFDs(topProcesses)
+		if status, offender := fdBreach(topProcesses, plugin.fdRules, float64(plugin.FDWarning), float64(plugin.FDCritical)); offender != nil {
+			if status == sensu.CheckStateCritical {
+				eval.breach("fd_critical", status)
+			} else {
+				eval.breach("fd_warning", status)
+			}
+			summary += fmt.Sprintf(", %d fds open by %s (PID %d)", *offender.FDs, offender.Name, offender.PID)
+		}
+	}
+	if len(plugin.WatchName) > 0 {
+		restarts := watchRestarts(processList, plugin.WatchName, &state)
+		metrics = append(metrics, restartMetrics(restarts)...)
+		for _, r := range restarts {
+			if r.Restarts == 0 {
+				continue
+			}
+			if plugin.RestartWarning {
+				eval.breach("restart_warning", sensu.CheckStateWarning)
+			}
+			summary += ", " + r.String()
+		}
+	}
+	// Watched processes are reported whether or not they made the top list,
+	// and one that is gone is the worst case for it. Pidfiles are read on
+	// every run, as the daemon rewrites them when it restarts.
+	var watched []ProcessInfo
+	if len(plugin.pids) > 0 || len(plugin.PIDFile) > 0 {
+		watches := append([]WatchedPID(nil), plugin.pids...)
+		readable := append([]WatchedPID(nil), plugin.pids...)
+		for _, file := range plugin.PIDFile {
+			w, err := readPIDFile(file)
+			watches = append(watches, w)
+			if err != nil {
+				eval.breach("pid_missing", sensu.CheckStateCritical)
+				summary += fmt.Sprintf(", cannot read pidfile: %v", err)
+				continue
+			}
+			readable = append(readable, w)
+		}
+		var missing []WatchedPID
+		watched, missing = watchedProcesses(processList, readable)
+		readProcessMemory(watched)
+		metrics = append(metrics, watchedMetrics(watches, watched)...)
+		for _, w := range missing {
+			eval.breach("pid_missing", sensu.CheckStateCritical)
+			summary += fmt.Sprintf(", %s not running", w)
+		}
+		if status, offender := watchedBreach(watched, plugin.PIDWarning, plugin.PIDCritical); offender != nil {
+			if status == sensu.CheckStateCritical {
+				eval.breach("pid_critical", status)
+			} else {
+				eval.breach("pid_warning", status)
+			}
+			summary += fmt.Sprintf(", PID %d (%s) at %.2f%%", offender.PID, offender.Name, offender.CPU)
+		}
+	}
+	if plugin.Cmdline {
+		readProcessCmdlines(topProcesses)
+		readProcessCmdlines(watched)
+	}
+	if plugin.showsColumn("rss") {
+		readProcessMemory(topProcesses)
+	}
+	if plugin.showsColumn("user") {
+		readProcessUsers(topProcesses)
+		readProcessUsers(watched)
+	}
+	// A service without processes is down, which is the worst case for it.
+	// The rollup emits every unit but only lists the busiest.
+	var units []UnitUsage
+	if plugin.UnitRollup {
+		units = unitRollup(processList, processUnits(processList))
+	}
+	addUnit := func(kind, unit string, pids []int32, err error) {
+		if err == nil && len(pids) == 0 {
+			err = fmt.Errorf("%s has no running processes", unit)
+		}
+		if err != nil {
+			eval.breach("unit_missing", sensu.CheckStateCritical)
+			summary += fmt.Sprintf(", %v", err)
+			metrics = append(metrics, Metric{UnitUsage{Kind: kind, Unit: unit}.metricName() + "_processes", 0})
+			return
+		}
+		units = append(units, unitUsage(processList, kind, unit, pids))
+	}
+	for _, unit := range plugin.Unit {
+		pids, err := unitPIDs(unit)
+		addUnit(unitKindSystemd, unit, pids, err)
+	}
+	for _, service := range plugin.Service {
+		pids, err := servicePIDs(service)
+		addUnit(unitKindWindows, service, pids, err)
+	}
+	for _, label := range plugin.LaunchdLabel {
+		pids, err := launchdPIDs(label)
+		addUnit(unitKindLaunchd, label, pids, err)
+	}
+	if len(units) > 0 {
+		metrics = append(metrics, unitMetrics(units)...)
+		if status, offender := unitBreach(units, plugin.UnitWarning, plugin.UnitCritical); offender != nil {
+			if status == sensu.CheckStateCritical {
+				eval.breach("unit_critical", status)
+			} else {
+				eval.breach("unit_warning", status)
+			}
+			summary += fmt.Sprintf(", %s at %.2f%%", offender.Unit, offender.CPU)
+		}
+	}
+	if plugin.UnitRollup && len(units) > 10 {
+		units = units[:10]
+	}
+	var zombieParents []ZombieParent
+	if plugin.usesZombies() {
+		parents, err := readZombieParents()
+		if err != nil {
+			return nil, fmt.Errorf("Error reading zombie processes: %v", err)
+		}
+		zombies := 0
+		for _, p := range parents {
+			zombies += p.Zombies
+		}
+		metrics = append(metrics,
+			Metric{"zombie_processes", float64(zombies)},
+			Metric{"zombie_parents", float64(len(parents))},
+		)
+		zombieParents = topZombieParents(parents, 10)
+		if plugin.ZombieWarning > 0 && zombies > plugin.ZombieWarning {
+			eval.breach("zombie_warning", sensu.CheckStateWarning)
+		}
+		summary += fmt.Sprintf(", %d zombie processes", zombies)
+	}
+	if plugin.usesThrottling() {
+		throttling := throttleEvents(throttleStart, throttleEnd)
+		metrics = append(metrics, throttling.metrics()...)
+		if throttling.throttled() {
+			summary += fmt.Sprintf(", throttled %g times on %d cores and %g times on %d packages", throttling.Core, throttling.Cores, throttling.Package, throttling.Packages)
+			if plugin.ThrottlingWarning {
+				eval.breach("throttling", sensu.CheckStateWarning)
+			}
+		}
+	}
+	if plugin.Governor {
+		policy, err := readCPUFreqPolicy()
+		if err != nil {
+			return nil, fmt.Errorf("Error reading cpufreq policy: %v", err)
+		}
+		metrics = append(metrics, policy.metrics()...)
+		summary += ", " + policy.String()
+	}
+	if plugin.PhysicalCores {
+		siblings, err := readCoreSiblings()
+		if err != nil {
+			return nil, fmt.Errorf("Error reading CPU topology: %v", err)
+		}
+		physical := physicalCoreUsage(cores, siblings)
+		physicalUsed := averageCoreUsage(physical)
+		metrics = append(metrics,
+			Metric{"cpu_physical_used", physicalUsed},
+			Metric{"cpu_physical_cores", float64(len(physical))},
+		)
+		summary += fmt.Sprintf(", %.2f%% of %d physical cores", physicalUsed, len(physical))
+	}
+	if plugin.CoreImbalance || plugin.CoreSpreadCritical > 0 || plugin.CoreSpreadWarning > 0 {
+		stddev, spread := coreImbalance(cores)
+		metrics = append(metrics,
+			Metric{"cpu_core_stddev", stddev},
+			Metric{"cpu_core_spread", spread},
+		)
+		if plugin.CoreSpreadCritical > 0 && spread > plugin.CoreSpreadCritical {
+			eval.breach("core_spread_critical", sensu.CheckStateCritical)
+		} else if plugin.CoreSpreadWarning > 0 && spread > plugin.CoreSpreadWarning {
+			eval.breach("core_spread_warning", sensu.CheckStateWarning)
+		}
+		if plugin.CoreSpreadCritical > 0 || plugin.CoreSpreadWarning > 0 {
+			summary += fmt.Sprintf(", %.2f points between the busiest and idlest CPU", spread)
+		}
+	}
+
+	// A suspend or clock step during the sample makes the measurements
+	// meaningless, so thresholds are not evaluated and the breach streak is
+	// left as it was
+	anomaly := clockAnomaly(startClocks, endClocks)
+	metrics = append(metrics, Metric{"clock_anomaly_seconds", anomaly.Seconds()})
+
+	// The baseline of the hour of day is evaluated before it learns this
+	// sample, and only alerts once it has learned enough of them
+	if plugin.BaselineCritical > 0 || plugin.BaselineWarning > 0 {
+		bucket := state.baselineBucket(now)
+		if bucket.Count >= plugin.BaselineSamples {
+			sigmas := bucket.sigmas(usedPct)
+			if plugin.BaselineCritical > 0 && sigmas > plugin.BaselineCritical {
+				eval.breach("baseline_critical", sensu.CheckStateCritical)
+			} else if plugin.BaselineWarning > 0 && sigmas > plugin.BaselineWarning {
+				eval.breach("baseline_warning", sensu.CheckStateWarning)
+			}
+			summary += fmt.Sprintf(", %.1f sigma from the %02d:00 baseline of %.2f%%", sigmas, now.Hour(), bucket.Mean)
+			metrics = append(metrics,
+				Metric{"baseline_mean", bucket.Mean},
+				Metric{"baseline_stddev", bucket.stddev()},
+				Metric{"baseline_sigmas", sigmas},
+			)
+		} else {
+			summary += fmt.Sprintf(", learning the %02d:00 baseline (%d of %d samples)", now.Hour(), bucket.Count, plugin.BaselineSamples)
+		}
+		if anomaly <= clockAnomalyTolerance {
+			bucket.add(usedPct)
+		}
+	}
+	if plugin.EWMAAlpha > 0 && anomaly <= clockAnomalyTolerance {
+		state.EWMA, state.EWMAAt = smoothed, now
+	}
+	if anomaly > clockAnomalyTolerance {
+		eval = Evaluation{}
+		summary += fmt.Sprintf(" (clocks diverged by %s during the sample, suspected suspend; thresholds not evaluated)", anomaly.Round(time.Second))
+	} else if inWindow {
+		eval = Evaluation{}
+		summary += fmt.Sprintf(" (suppress window %s, status forced OK)", suppressWindow.Spec)
+	} else if plugin.BreachCount > 1 && eval.dampen(&state, plugin.BreachCount) {
+		summary += fmt.Sprintf(" (threshold breached %d of %d consecutive runs)", state.ConsecutiveBreaches, plugin.BreachCount)
+	}
+
+	// The top offender is profiled while it is still hot, a JVM after its
+	// threads are dumped. A failed capture does not fail the check, which has
+	// already found what it came for.
+	threadDump := ""
+	if plugin.JVMDiagnostics && eval.Status == sensu.CheckStateCritical && topOffenderPID > 0 && isJVMProcess(topOffender) {
+		progress.setStage("capturing thread dump")
+		if file, err := captureThreadDump(topOffenderPID, plugin.CaptureProfile); err != nil {
+			summary += fmt.Sprintf(" (thread dump of PID %d failed: %v)", topOffenderPID, err)
+		} else {
+			threadDump = file
+			summary += fmt.Sprintf(" (thread dump of PID %d written to %s)", topOffenderPID, file)
+		}
+	}
+	profile := ""
+	if plugin.CaptureProfile != "" && eval.Status == sensu.CheckStateCritical && topOffenderPID > 0 {
+		progress.setStage("capturing profile")
+		from := time.Now()
+		if file, err := captureTopOffender(topOffenderPID, topOffender); err != nil {
+			summary += fmt.Sprintf(" (profile capture of PID %d failed: %v)", topOffenderPID, err)
+		} else {
+			profile = file
+			summary += fmt.Sprintf(" (profile of PID %d written to %s)", topOffenderPID, file)
+			if plugin.PyroscopeURL != "" || plugin.ParcaURL != "" {
+				progress.setStage("pushing profile")
+				captured := CapturedProfile{File: file, PID: topOffenderPID, Name: topOffender, From: from, Until: time.Now()}
+				if err := pushProfile(captured); err != nil {
+					summary += fmt.Sprintf(" (pushing profile failed: %v)", err)
+				}
+			}
+		}
+	}
+
+	if plugin.usesState() {
+		if err := saveState(plugin.StateFile, state); err != nil {
+			return nil, fmt.Errorf("Error writing state file: %v", err)
+		}
+	}
+
+	result := &Result{
+		Timestamp:  now,
+		Status:     eval.Status,
+		Summary:    summary,
+		Usage:      usage,
+		Metrics:    metrics,
+		Processes:  topProcesses,
+		Breached:   eval.Breached,
+		Disabled:   plugin.disabled,
+		Groups:     groups,
+		OffCPU:     offCPU,
+		Profile:    profile,
+		ThreadDump: threadDump,
+		DState:     dstateProcesses,
+		Zombies:    zombieParents,
+		Watched:    watched,
+		Units:      units,
+	}
+	if eval.Status != sensu.CheckStateOK {
+		result.Fingerprint = alertFingerprint(eval.Breached, topOffender)
+	}
+	return result, nil
+}
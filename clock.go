@@ -0,0 +1,40 @@
+package main
+
+import "time"
+
+// Divergence between clocks across a sample beyond which the host is taken
+// to have been suspended or to have had its clock stepped
+const clockAnomalyTolerance = time.Second
+
+// Struct to hold a read of the clocks at one point of the sample. Wall
+// carries Go's monotonic reading, which stops while the host is suspended.
+// Boot is the time since boot including suspend, where available.
+type ClockReading struct {
+	Wall    time.Time
+	Boot    time.Duration
+	HasBoot bool
+}
+
+// Function to read the clocks
+func readClocks() ClockReading {
+	boot, err := readBootClock()
+	return ClockReading{Wall: time.Now(), Boot: boot, HasBoot: err == nil}
+}
+
+// Function to measure how far the clocks diverged between two reads. On a
+// healthy host they all advance together; a suspend and resume advances the
+// boot clock but not the monotonic one, and a frozen VM or a stepped clock
+// moves the wall clock apart from the monotonic one.
+func clockAnomaly(start, end ClockReading) time.Duration {
+	monotonic := end.Wall.Sub(start.Wall)
+	anomaly := end.Wall.Round(0).Sub(start.Wall.Round(0)) - monotonic
+	if anomaly < 0 {
+		anomaly = -anomaly
+	}
+	if start.HasBoot && end.HasBoot {
+		if suspended := end.Boot - start.Boot - monotonic; suspended > anomaly {
+			anomaly = suspended
+		}
+	}
+	return anomaly
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "time"
+
+// Function to read the time since boot including suspend
+func readBootClock() (time.Duration, error) {
+	return 0, errUnsupported
+}
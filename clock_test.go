@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClockAnomaly(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	start := ClockReading{Wall: now, Boot: 100 * time.Second, HasBoot: true}
+
+	healthy := ClockReading{Wall: now.Add(2 * time.Second), Boot: 102 * time.Second, HasBoot: true}
+	assert.Equal(time.Duration(0), clockAnomaly(start, healthy))
+
+	// The boot clock kept counting through a 30s suspend
+	resumed := ClockReading{Wall: now.Add(2 * time.Second), Boot: 132 * time.Second, HasBoot: true}
+	assert.Equal(30*time.Second, clockAnomaly(start, resumed))
+
+	// Without a boot clock only the wall clock is compared
+	noBoot := ClockReading{Wall: now.Add(2 * time.Second)}
+	assert.Equal(time.Duration(0), clockAnomaly(start, noBoot))
+}
+
+func TestReadClocks(t *testing.T) {
+	assert := assert.New(t)
+	start := readClocks()
+	time.Sleep(10 * time.Millisecond)
+	assert.True(clockAnomaly(start, readClocks()) < clockAnomalyTolerance)
+}
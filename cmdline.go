@@ -0,0 +1,11 @@
+package main
+
+// Function to truncate a command line to a number of characters, marking
+// where it was cut. A length of 0 leaves it whole.
+func truncateCmdline(cmdline string, length int) string {
+	runes := []rune(cmdline)
+	if length <= 0 || len(runes) <= length {
+		return cmdline
+	}
+	return string(runes[:length]) + "..."
+}
@@ -0,0 +1,34 @@
+//go:build !openbsd
+
+package main
+
+import "github.com/shirou/gopsutil/v3/process"
+
+// Function to read the full command lines of the processes, with the secrets
+// of --redact-args and --redact-pattern masked, leaving them unset for those
+// that cannot be read, such as kernel threads
+func readProcessCmdlines(processes []ProcessInfo) {
+	for i := range processes {
+		p, err := process.NewProcess(processes[i].PID)
+		if err != nil {
+			continue
+		}
+		if cmdline, err := p.Cmdline(); err == nil {
+			processes[i].Cmdline = redact(cmdline, plugin.redactPatterns)
+		}
+	}
+}
+
+// Function to read the user each of the processes runs as, leaving it unset
+// for those that cannot be read
+func readProcessUsers(processes []ProcessInfo) {
+	for i := range processes {
+		p, err := process.NewProcess(processes[i].PID)
+		if err != nil {
+			continue
+		}
+		if user, err := p.Username(); err == nil {
+			processes[i].User = user
+		}
+	}
+}
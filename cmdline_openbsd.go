@@ -0,0 +1,40 @@
+package main
+
+// Function to read the full command lines of the processes, with the secrets
+// of --redact-args and --redact-pattern masked, leaving them unset for those
+// that cannot be read. gopsutil's process support needs cgo on OpenBSD, so
+// they come from a single ps call.
+func readProcessCmdlines(processes []ProcessInfo) {
+	listed, err := listPSArgs()
+	if err != nil {
+		return
+	}
+	for i := range processes {
+		if p, ok := listed[processes[i].PID]; ok && p.Args != "" {
+			processes[i].Cmdline = redact(p.Args, plugin.redactPatterns)
+		}
+	}
+}
+
+// Function to read the user each of the processes runs as, leaving it unset
+// for those that cannot be read
+func readProcessUsers(processes []ProcessInfo) {
+	listed, err := listPSArgs()
+	if err != nil {
+		return
+	}
+	for i := range processes {
+		if p, ok := listed[processes[i].PID]; ok {
+			processes[i].User = p.User
+		}
+	}
+}
+
+// Function to list the user and command line of every process
+func listPSArgs() (map[int32]psArgs, error) {
+	out, err := runPS(psEveryProcess, "-o", "pid=,user=,args=")
+	if err != nil {
+		return nil, err
+	}
+	return parsePSArgs(out)
+}
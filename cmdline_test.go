@@ -0,0 +1,15 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateCmdline(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("/usr/bin/java -jar app.jar", truncateCmdline("/usr/bin/java -jar app.jar", 0))
+	assert.Equal("/usr/bin/java -jar app.jar", truncateCmdline("/usr/bin/java -jar app.jar", 26))
+	assert.Equal("/usr/bin/java...", truncateCmdline("/usr/bin/java -jar app.jar", 13))
+	assert.Equal("python3 héllo...", truncateCmdline("python3 héllo_wörld.py", 13))
+}
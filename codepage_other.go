@@ -0,0 +1,9 @@
+//go:build !windows
+
+package main
+
+// Function to decode the output of a command into UTF-8. Other platforms run
+// commands in the C locale, so it is left as it is.
+func decodeCommandOutput(out []byte) []byte {
+	return out
+}
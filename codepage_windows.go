@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/sys/windows"
+)
+
+// Code page identifier standing for the OEM code page of the system, which
+// console programs such as tasklist write their output in
+const cpOEMCP = 1
+
+// Function to decode the output of a command from the OEM code page into
+// UTF-8, so non-ASCII process names are not mangled. Output that is already
+// valid UTF-8, including plain ASCII, is left as it is.
+func decodeCommandOutput(out []byte) []byte {
+	if len(out) == 0 || utf8.Valid(out) {
+		return out
+	}
+	n, err := windows.MultiByteToWideChar(cpOEMCP, 0, &out[0], int32(len(out)), nil, 0)
+	if err != nil || n == 0 {
+		return []byte(strings.ToValidUTF8(string(out), "\uFFFD"))
+	}
+	wide := make([]uint16, n)
+	if _, err := windows.MultiByteToWideChar(cpOEMCP, 0, &out[0], int32(len(out)), &wide[0], n); err != nil {
+		return []byte(strings.ToValidUTF8(string(out), "\uFFFD"))
+	}
+	return []byte(windows.UTF16ToString(wide))
+}
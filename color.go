@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+)
+
+// Function to tell whether a file is a terminal rather than a pipe or a
+// regular file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Function to get the color highlighting a value over its critical or
+// warning threshold, none when it is under both or they are 0
+func thresholdColor(v, warning, critical float64) string {
+	switch {
+	case critical > 0 && v > critical:
+		return ansiRed
+	case warning > 0 && v > warning:
+		return ansiYellow
+	}
+	return ""
+}
+
+// Function to get the color highlighting a status, none when it is OK
+func statusColor(status int) string {
+	switch status {
+	case sensu.CheckStateWarning:
+		return ansiYellow
+	case sensu.CheckStateCritical:
+		return ansiRed
+	}
+	return ""
+}
+
+// Function to color the rows of a table, given the color of every row and
+// leaving the header line as it is
+func colorTable(table string, colors []string) string {
+	lines := strings.SplitAfter(table, "\n")
+	for i, color := range colors {
+		if color != "" && i+1 < len(lines) {
+			lines[i+1] = color + strings.TrimSuffix(lines[i+1], "\n") + ansiReset + "\n"
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThresholdColor(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(ansiRed, thresholdColor(95, 50, 90))
+	assert.Equal(ansiYellow, thresholdColor(60, 50, 90))
+	assert.Equal("", thresholdColor(40, 50, 90))
+	assert.Equal("", thresholdColor(95, 0, 0))
+	assert.Equal(ansiRed, statusColor(sensu.CheckStateCritical))
+	assert.Equal("", statusColor(sensu.CheckStateOK))
+}
+
+func TestColorTable(t *testing.T) {
+	assert := assert.New(t)
+	table := "PID  CPU\n42   95.00%\n7    60.00%\n9    1.00%\n"
+	assert.Equal("PID  CPU\n"+
+		ansiRed+"42   95.00%"+ansiReset+"\n"+
+		ansiYellow+"7    60.00%"+ansiReset+"\n"+
+		"9    1.00%\n", colorTable(table, []string{ansiRed, ansiYellow, ""}))
+}
+
+func TestFormatProcessTableColor(t *testing.T) {
+	assert := assert.New(t)
+	result := testResult()
+	result.Processes = []ProcessInfo{{PID: 42, CPU: 90, Name: "java"}, {PID: 7, CPU: 5, Name: "backup"}}
+	plugin.ProcessWarning, plugin.ProcessCritical = 50, 80
+	plugin.colorOutput = true
+	defer func() { plugin.ProcessWarning, plugin.ProcessCritical, plugin.colorOutput = 0, 0, false }()
+	assert.Equal("Top CPU processes:\n"+
+		"PID  CPU     NAME\n"+
+		ansiRed+"42   90.00%  java"+ansiReset+"\n"+
+		"7    5.00%   backup\n", formatProcessTable(result))
+}
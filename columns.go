@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats the process table can be written in
+const (
+	processFormatText  = "text"
+	processFormatCSV   = "csv"
+	processFormatJSONL = "jsonl"
+)
+
+// Struct to hold a column of the process table, with how to get its value
+// for a process at the time of the run, and a human-readable form of it for
+// the text table where it differs. Values that were not collected are empty.
+type ProcessColumn struct {
+	Name  string
+	Value func(p ProcessInfo, now time.Time) string
+	Text  func(p ProcessInfo, now time.Time) string
+}
+
+// Function to get the human-readable value of the column for a process
+func (c ProcessColumn) text(p ProcessInfo, now time.Time) string {
+	if c.Text != nil {
+		return c.Text(p, now)
+	}
+	return c.Value(p, now)
+}
+
+// Columns of the process table, in the order they are written
+var processColumns = []ProcessColumn{
+	{Name: "pid", Value: func(p ProcessInfo, now time.Time) string { return strconv.Itoa(int(p.PID)) }},
+	{Name: "name", Value: func(p ProcessInfo, now time.Time) string { return p.Name }},
+	{Name: "user", Value: func(p ProcessInfo, now time.Time) string { return p.User }},
+	{
+		Name:  "cmdline",
+		Value: func(p ProcessInfo, now time.Time) string { return p.Cmdline },
+		Text: func(p ProcessInfo, now time.Time) string {
+			if p.Cmdline == "" {
+				return p.Name
+			}
+			return truncateCmdline(p.Cmdline, plugin.CmdlineLength)
+		},
+	},
+	{
+		Name:  "cpu",
+		Value: func(p ProcessInfo, now time.Time) string { return fmt.Sprintf("%.2f", p.CPU) },
+		Text:  func(p ProcessInfo, now time.Time) string { return fmt.Sprintf("%.2f%%", p.CPU) },
+	},
+	{Name: "started", Value: func(p ProcessInfo, now time.Time) string {
+		if p.CreatedAt.IsZero() {
+			return ""
+		}
+		return p.CreatedAt.UTC().Format(time.RFC3339)
+	}},
+	{
+		Name: "age",
+		Value: func(p ProcessInfo, now time.Time) string {
+			if p.CreatedAt.IsZero() {
+				return ""
+			}
+			return strconv.Itoa(int(now.Sub(p.CreatedAt) / time.Second))
+		},
+		Text: func(p ProcessInfo, now time.Time) string {
+			if p.CreatedAt.IsZero() {
+				return ""
+			}
+			return formatAge(now.Sub(p.CreatedAt))
+		},
+	},
+	{
+		Name: "growth",
+		Value: func(p ProcessInfo, now time.Time) string {
+			if p.Growth == nil {
+				return ""
+			}
+			return fmt.Sprintf("%.2f", *p.Growth)
+		},
+		Text: func(p ProcessInfo, now time.Time) string {
+			if p.Growth == nil {
+				return ""
+			}
+			return fmt.Sprintf("%+.2f%%", *p.Growth)
+		},
+	},
+	{
+		Name: "rss",
+		Value: func(p ProcessInfo, now time.Time) string {
+			if p.RSS == nil {
+				return ""
+			}
+			return strconv.FormatUint(*p.RSS, 10)
+		},
+		Text: func(p ProcessInfo, now time.Time) string {
+			if p.RSS == nil {
+				return ""
+			}
+			return fmt.Sprintf("%.1f MiB", float64(*p.RSS)/(1<<20))
+		},
+	},
+	{Name: "fds", Value: func(p ProcessInfo, now time.Time) string {
+		if p.FDs == nil {
+			return ""
+		}
+		return strconv.Itoa(*p.FDs)
+	}},
+	{Name: "oom_score", Value: func(p ProcessInfo, now time.Time) string {
+		if p.OOM == nil {
+			return ""
+		}
+		return strconv.Itoa(p.OOM.Score)
+	}},
+	{Name: "oom_adj", Value: func(p ProcessInfo, now time.Time) string {
+		if p.OOM == nil {
+			return ""
+		}
+		return strconv.Itoa(p.OOM.Adj)
+	}},
+	{Name: "nice", Value: func(p ProcessInfo, now time.Time) string {
+		if p.Priority == nil {
+			return ""
+		}
+		return strconv.Itoa(p.Priority.Nice)
+	}},
+	{Name: "priority", Value: func(p ProcessInfo, now time.Time) string {
+		if p.Priority == nil {
+			return ""
+		}
+		return strconv.Itoa(p.Priority.Priority)
+	}},
+	{Name: "sched_policy", Value: func(p ProcessInfo, now time.Time) string {
+		if p.Sched == nil {
+			return ""
+		}
+		return p.Sched.Policy
+	}},
+	{Name: "sched_priority", Value: func(p ProcessInfo, now time.Time) string {
+		if p.Sched == nil {
+			return ""
+		}
+		return strconv.Itoa(p.Sched.Priority)
+	}},
+	{Name: "namespace", Value: func(p ProcessInfo, now time.Time) string {
+		if p.Pod == nil {
+			return ""
+		}
+		return p.Pod.Namespace
+	}},
+	{Name: "pod", Value: func(p ProcessInfo, now time.Time) string {
+		if p.Pod == nil {
+			return ""
+		}
+		return p.Pod.Pod
+	}},
+	{Name: "container", Value: func(p ProcessInfo, now time.Time) string {
+		switch {
+		case p.Pod != nil:
+			return p.Pod.Container
+		case p.Container != nil:
+			return p.Container.Name
+		}
+		return ""
+	}},
+	{Name: "image", Value: func(p ProcessInfo, now time.Time) string {
+		if p.Container == nil {
+			return ""
+		}
+		return p.Container.Image
+	}},
+	{Name: "suppressed_until", Value: func(p ProcessInfo, now time.Time) string {
+		if p.SuppressedUntil == nil {
+			return ""
+		}
+		return p.SuppressedUntil.UTC().Format(time.RFC3339)
+	}},
+}
+
+// Function to find the process columns of the given names, in that order
+func parseProcessColumns(names []string) ([]ProcessColumn, error) {
+	var columns []ProcessColumn
+	for _, name := range names {
+		found := false
+		for _, c := range processColumns {
+			if c.Name == name {
+				columns = append(columns, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+	}
+	return columns, nil
+}
+
+// Function to get the option collecting the values of a column when it is
+// not enabled, as the column would otherwise be left empty, or "" when the
+// values are collected or do not depend on an option
+func columnOption(name string) string {
+	switch {
+	case name == "cmdline" && !plugin.Cmdline:
+		return "--cmdline"
+	case name == "growth" && plugin.RankBy != rankByGrowth:
+		return "--rank-by growth"
+	case name == "fds" && !plugin.usesFDs():
+		return "--fds"
+	case (name == "oom_score" || name == "oom_adj") && !plugin.OOMScore:
+		return "--oom-score"
+	case (name == "nice" || name == "priority") && !plugin.usesNice():
+		return "--nice"
+	case (name == "sched_policy" || name == "sched_priority") && !plugin.usesRTSched():
+		return "--rt-sched"
+	}
+	return ""
+}
+
+// Columns the text table shows by default, besides the PID, CPU usage and
+// name, when at least one of the listed processes has a value for them
+var optionalProcessColumns = []string{
+	"growth", "rss", "fds", "oom_score", "oom_adj", "nice", "priority",
+	"sched_policy", "sched_priority", "namespace", "pod", "container", "image",
+	"suppressed_until",
+}
+
+// Function to get the columns the text table shows for processes without
+// --process-columns: the PID and CPU usage, the start time and age with
+// --process-age, the fields collected for any of the processes, and the
+// name, or the command line with --cmdline
+func defaultProcessColumns(processes []ProcessInfo, now time.Time) []ProcessColumn {
+	names := []string{"pid", "cpu"}
+	if plugin.ProcessAge {
+		names = append(names, "started", "age")
+	}
+	for _, name := range optionalProcessColumns {
+		column, _ := parseProcessColumns([]string{name})
+		for _, p := range processes {
+			if column[0].Value(p, now) != "" {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	if plugin.Cmdline {
+		names = append(names, "cmdline")
+	} else {
+		names = append(names, "name")
+	}
+	columns, _ := parseProcessColumns(names)
+	return columns
+}
+
+// Function to format processes as a table of the columns
+func formatProcessColumns(processes []ProcessInfo, columns []ProcessColumn, now time.Time) string {
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = strings.ToUpper(c.Name)
+	}
+	rows := make([][]string, len(processes))
+	for i, p := range processes {
+		rows[i] = make([]string, len(columns))
+		for j, c := range columns {
+			rows[i][j] = c.text(p, now)
+		}
+	}
+	return formatTable(header, rows)
+}
+
+// Function to format processes as CSV, with a header line naming the columns
+func formatProcessCSV(processes []ProcessInfo, columns []ProcessColumn, now time.Time) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	record := make([]string, len(columns))
+	for i, c := range columns {
+		record[i] = c.Name
+	}
+	w.Write(record)
+	for _, p := range processes {
+		for i, c := range columns {
+			record[i] = c.Value(p, now)
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// Function to format processes as JSON Lines, one object per process with
+// the fields of the JSON output. Processes that cannot be encoded, which
+// only happens with a non-finite CPU usage, are left out.
+func formatProcessJSONL(processes []ProcessInfo) string {
+	var b strings.Builder
+	for _, p := range processes {
+		line, err := json.Marshal(p)
+		if err != nil {
+			continue
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
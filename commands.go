@@ -0,0 +1,152 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/sensu/sensu-go/types"
+)
+
+// Struct to describe a subcommand of the plugin. Every subcommand shares the
+// plugin options and may register extra ones of its own. Subcommands taking
+// arguments besides flags find them in commandArgs.
+type Command struct {
+	Name      string
+	Short     string
+	Options   []*sensu.PluginConfigOption
+	Validate  func(*types.Event) (int, error)
+	Execute   func(*types.Event) (int, error)
+	TakesArgs bool
+}
+
+// The first command is the default, used when no subcommand is given so bare
+// invocations keep working as before
+var commands = []*Command{
+	{
+		Name:     "check",
+		Short:    "Check CPU usage and provide metrics",
+		Validate: checkArgs,
+		Execute:  executeCheck,
+	},
+	{
+		Name:     "metrics",
+		Short:    "Emit CPU usage metrics only, without alerting or the process list",
+		Validate: checkArgs,
+		Execute:  executeMetrics,
+	},
+	{
+		Name:     "top",
+		Short:    "Emit the table of top CPU processes only, without alerting",
+		Validate: checkArgs,
+		Execute:  executeTop,
+	},
+	{
+		Name:     "watch",
+		Short:    "Refresh the CPU breakdown and top processes in the terminal every sample interval, like top",
+		Validate: checkArgs,
+		Execute:  executeWatch,
+	},
+	{
+		Name:     "daemon",
+		Short:    "Sample continuously, printing aggregated results periodically and serving them over HTTP",
+		Options:  daemonOptions,
+		Validate: daemonArgs,
+		Execute:  executeDaemon,
+	},
+	{
+		Name:     "snapshot",
+		Short:    "Capture the CPU usage and the usage of every process as JSON, for a later diff",
+		Options:  snapshotOptions,
+		Validate: checkArgs,
+		Execute:  executeSnapshot,
+	},
+	{
+		Name:      "diff",
+		Short:     "Compare two snapshots given as arguments, listing the processes that changed the most",
+		Validate:  diffArgs,
+		Execute:   executeDiff,
+		TakesArgs: true,
+	},
+	{
+		Name:     "history",
+		Short:    "List the results recorded in --history-file or --history-db over a time range",
+		Options:  historyRangeOptions,
+		Validate: historyArgs,
+		Execute:  executeHistory,
+	},
+	{
+		Name:     "replay",
+		Short:    "Evaluate the thresholds against the results recorded in --history-file or --history-db",
+		Options:  historyRangeOptions,
+		Validate: replayArgs,
+		Execute:  executeReplay,
+	},
+	{
+		Name:     "calibrate",
+		Short:    "Suggest thresholds from the results recorded in --history-file or --history-db",
+		Options:  calibrateOptions,
+		Validate: calibrateArgs,
+		Execute:  executeCalibrate,
+	},
+	{
+		Name:     "recommend",
+		Short:    "Recommend CPU requests and limits from --history-file or --history-db",
+		Options:  recommendOptions,
+		Validate: recommendArgs,
+		Execute:  executeRecommend,
+	},
+}
+
+// Arguments left on the command line once the flags are taken out, which the
+// SDK does not pass on to the subcommands
+var commandArgs []string
+
+// Function to pick the subcommand named by the first argument, returning it
+// along with the remaining arguments
+func selectCommand(args []string) (*Command, []string) {
+	if len(args) > 0 {
+		for _, c := range commands {
+			if c.Name == args[0] {
+				return c, args[1:]
+			}
+		}
+	}
+	return commands[0], args
+}
+
+// Function to split the arguments into flags, along with their values, and
+// the other arguments, telling flags that take a value from those that do not
+// by the type of their option. The SDK would take the other arguments for an
+// unknown subcommand.
+func splitArgs(args []string, opts []*sensu.PluginConfigOption) ([]string, []string) {
+	takesValue := make(map[string]bool)
+	for _, opt := range opts {
+		if opt.Argument == "" {
+			continue
+		}
+		isBool := reflect.Indirect(reflect.ValueOf(opt.Value)).Kind() == reflect.Bool
+		takesValue["--"+opt.Argument] = !isBool
+		if opt.Shorthand != "" {
+			takesValue["-"+opt.Shorthand] = !isBool
+		}
+	}
+
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return flags, append(positional, args[i+1:]...)
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			flags = append(flags, arg)
+			if takesValue[arg] && i+1 < len(args) {
+				i++
+				flags = append(flags, args[i])
+			}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	return flags, positional
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectCommand(t *testing.T) {
+	assert := assert.New(t)
+	c, args := selectCommand([]string{"check", "-c", "90"})
+	assert.Equal("check", c.Name)
+	assert.Equal([]string{"-c", "90"}, args)
+
+	c, args = selectCommand([]string{"-c", "90"})
+	assert.Equal("check", c.Name)
+	assert.Equal([]string{"-c", "90"}, args)
+
+	c, args = selectCommand([]string{"top", "--sample-interval", "1s"})
+	assert.Equal("top", c.Name)
+	assert.Equal([]string{"--sample-interval", "1s"}, args)
+
+	c, args = selectCommand([]string{"metrics"})
+	assert.Equal("metrics", c.Name)
+	assert.Empty(args)
+
+	c, args = selectCommand(nil)
+	assert.Equal("check", c.Name)
+	assert.Empty(args)
+
+	c, args = selectCommand([]string{"replay", "--since", "24h"})
+	assert.Equal("replay", c.Name)
+	assert.Equal([]string{"--since", "24h"}, args)
+
+	c, args = selectCommand([]string{"version"})
+	assert.Equal("check", c.Name)
+	assert.Equal([]string{"version"}, args)
+}
+
+func TestOptionPaths(t *testing.T) {
+	assert := assert.New(t)
+	// Every option can be overridden through an annotation under its own name,
+	// except the config file which is read before annotations are
+	for _, c := range commands {
+		for _, opt := range append(options, c.Options...) {
+			if opt.Argument == "config" {
+				assert.Empty(opt.Path)
+				continue
+			}
+			assert.Equal(opt.Argument, opt.Path, "option --%s of %s", opt.Argument, c.Name)
+		}
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	assert := assert.New(t)
+	flags, args := splitArgs([]string{"a.json", "-s", "1s", "--output-json", "b.json", "--critical=95"}, options)
+	assert.Equal([]string{"-s", "1s", "--output-json", "--critical=95"}, flags)
+	assert.Equal([]string{"a.json", "b.json"}, args)
+
+	flags, args = splitArgs([]string{"-w", "80", "--", "-c.json"}, options)
+	assert.Equal([]string{"-w", "80"}, flags)
+	assert.Equal([]string{"-c.json"}, args)
+}
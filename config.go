@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/spf13/viper"
+)
+
+// Error loading the --config file, reported when the options are validated
+var configFileErr error
+
+// Function to find the --config file in the command line arguments. It has
+// to be loaded before the SDK sets up the flags, so the arguments are scanned
+// ahead of the SDK parsing them.
+func configFileArg(args []string) string {
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return ""
+		case arg == "--config" && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(arg, "--config="):
+			return strings.TrimPrefix(arg, "--config=")
+		}
+	}
+	return ""
+}
+
+// Function to load a YAML, TOML or JSON config file, told apart by its
+// extension, holding option values under their flag names. The SDK takes the
+// defaults of the flags from viper, so the file values replace the built-in
+// defaults and flags given on the command line still override them. Keys
+// that are not the name of an option are rejected to catch typos.
+func loadConfigFile(file string, opts []*sensu.PluginConfigOption) error {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml", ".toml", ".json":
+	default:
+		return fmt.Errorf("%s is not a .yaml, .yml, .toml or .json file", file)
+	}
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(opts))
+	for _, opt := range opts {
+		if opt.Argument != "" {
+			known[opt.Argument] = true
+		}
+	}
+	var unknown []string
+	for _, key := range v.AllKeys() {
+		if !known[key] || key == "config" {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown options in %s: %s", file, strings.Join(unknown, ", "))
+	}
+	for _, key := range v.AllKeys() {
+		viper.Set(key, v.Get(key))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFileArg(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("cpu.yaml", configFileArg([]string{"-w", "80", "--config", "cpu.yaml"}))
+	assert.Equal("cpu.toml", configFileArg([]string{"--config=cpu.toml", "-w", "80"}))
+	assert.Equal("", configFileArg([]string{"-w", "80"}))
+	assert.Equal("", configFileArg([]string{"--config"}))
+	assert.Equal("", configFileArg([]string{"--", "--config", "cpu.yaml"}))
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	assert := assert.New(t)
+	defer viper.Reset()
+	dir := t.TempDir()
+
+	yamlFile := filepath.Join(dir, "cpu.yaml")
+	assert.NoError(os.WriteFile(yamlFile, []byte(`critical: 95
+warning: 85
+suppress:
+  - backup=2h
+process-rule:
+  - "java*=200:400"
+  - "nginx=:150"
+`), 0644))
+	assert.NoError(loadConfigFile(yamlFile, options))
+	assert.Equal(95.0, viper.GetFloat64("critical"))
+	assert.Equal([]string{"backup=2h"}, viper.GetStringSlice("suppress"))
+	assert.Equal([]string{"java*=200:400", "nginx=:150"}, viper.GetStringSlice("process-rule"))
+
+	tomlFile := filepath.Join(dir, "cpu.toml")
+	assert.NoError(os.WriteFile(tomlFile, []byte("sample-interval = \"5s\"\noutput-json = true\n"), 0644))
+	assert.NoError(loadConfigFile(tomlFile, options))
+	assert.Equal("5s", viper.GetString("sample-interval"))
+	assert.True(viper.GetBool("output-json"))
+
+	typo := filepath.Join(dir, "typo.yaml")
+	assert.NoError(os.WriteFile(typo, []byte("critcal: 95\n"), 0644))
+	assert.EqualError(loadConfigFile(typo, options), "unknown options in "+typo+": critcal")
+
+	assert.Error(loadConfigFile(filepath.Join(dir, "cpu.ini"), options))
+	assert.Error(loadConfigFile(filepath.Join(dir, "missing.yaml"), options))
+}
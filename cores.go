@@ -0,0 +1,72 @@
+package main
+
+import (
+	"math"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// Struct to hold the CPU usage of one logical CPU over the sample interval
+type CoreUsage struct {
+	Name string
+	Used float64
+}
+
+// Function to get the CPU usage of every logical CPU between two per-CPU
+// timings. CPUs that went offline during the interval are left out.
+func perCPUUsage(start, end []cpu.TimesStat) []CPUUsage {
+	var usage []CPUUsage
+	for i := range start {
+		if i < len(end) {
+			usage = append(usage, cpuUsage(start[i], end[i]))
+		}
+	}
+	return usage
+}
+
+// Function to get the used CPU percentage of every logical CPU between two
+// per-CPU timings, named after the CPU
+func coreUsage(start, end []cpu.TimesStat) []CoreUsage {
+	var cores []CoreUsage
+	for i, u := range perCPUUsage(start, end) {
+		cores = append(cores, CoreUsage{Name: start[i].CPU, Used: u.Used})
+	}
+	return cores
+}
+
+// Function to get the busiest logical CPU
+func busiestCore(cores []CoreUsage) CoreUsage {
+	var busiest CoreUsage
+	for i, c := range cores {
+		if i == 0 || c.Used > busiest.Used {
+			busiest = c
+		}
+	}
+	return busiest
+}
+
+// Function to get how unevenly the logical CPUs were used, as the standard
+// deviation of their usage and the spread between the busiest and idlest
+func coreImbalance(cores []CoreUsage) (float64, float64) {
+	if len(cores) == 0 {
+		return 0, 0
+	}
+	min, max, sum := cores[0].Used, cores[0].Used, 0.0
+	for _, c := range cores {
+		min = math.Min(min, c.Used)
+		max = math.Max(max, c.Used)
+		sum += c.Used
+	}
+	mean := sum / float64(len(cores))
+	var squares float64
+	for _, c := range cores {
+		squares += (c.Used - mean) * (c.Used - mean)
+	}
+	return math.Sqrt(squares / float64(len(cores))), max - min
+}
+
+// Function to probe per-CPU timings, which need cgo on macOS
+func probePerCPU() error {
+	_, err := cpu.Times(true)
+	return err
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoreUsage(t *testing.T) {
+	assert := assert.New(t)
+	start := []cpu.TimesStat{
+		{CPU: "cpu0", User: 10, Idle: 90},
+		{CPU: "cpu1", User: 10, Idle: 90},
+		{CPU: "cpu2", User: 10, Idle: 90},
+	}
+	end := []cpu.TimesStat{
+		{CPU: "cpu0", User: 19, Idle: 91},
+		{CPU: "cpu1", User: 11, Idle: 99},
+	}
+
+	cores := coreUsage(start, end)
+	assert.Len(cores, 2)
+	assert.Equal("cpu0", cores[0].Name)
+	assert.InDelta(90, cores[0].Used, 0.001)
+	assert.Equal("cpu1", cores[1].Name)
+	assert.InDelta(10, cores[1].Used, 0.001)
+	assert.Len(perCPUUsage(start, end), 2)
+}
+
+func TestBusiestCore(t *testing.T) {
+	assert := assert.New(t)
+	cores := []CoreUsage{{"cpu0", 12}, {"cpu1", 99.5}, {"cpu2", 40}}
+	assert.Equal(CoreUsage{"cpu1", 99.5}, busiestCore(cores))
+	assert.Equal(CoreUsage{}, busiestCore(nil))
+}
+
+func TestCoreImbalance(t *testing.T) {
+	assert := assert.New(t)
+	stddev, spread := coreImbalance([]CoreUsage{{"cpu0", 90}, {"cpu1", 10}, {"cpu2", 10}, {"cpu3", 10}})
+	assert.InDelta(34.641, stddev, 0.001)
+	assert.Equal(float64(80), spread)
+
+	stddev, spread = coreImbalance([]CoreUsage{{"cpu0", 50}, {"cpu1", 50}})
+	assert.Equal(float64(0), stddev)
+	assert.Equal(float64(0), spread)
+
+	stddev, spread = coreImbalance(nil)
+	assert.Equal(float64(0), stddev)
+	assert.Equal(float64(0), spread)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// Labels the kubelet sets on the containers it creates through the CRI
+const (
+	criLabelPodName       = "io.kubernetes.pod.name"
+	criLabelPodNamespace  = "io.kubernetes.pod.namespace"
+	criLabelContainerName = "io.kubernetes.container.name"
+)
+
+// Function to describe a container listed by the CRI, along with the pod it
+// belongs to when it was created by the kubelet
+func criContainerInfo(c *runtimeapi.Container) ContainerInfo {
+	info := ContainerInfo{ID: c.Id}
+	if c.Metadata != nil {
+		info.Name = c.Metadata.Name
+	}
+	if c.Image != nil {
+		info.Image = c.Image.Image
+	}
+	if pod, ns := c.Labels[criLabelPodName], c.Labels[criLabelPodNamespace]; pod != "" && ns != "" {
+		info.pod = &PodInfo{Namespace: ns, Pod: pod, Container: c.Labels[criLabelContainerName]}
+	}
+	return info
+}
+
+// Function to list the running containers through the CRI runtime service on
+// a unix socket, given as a path or a unix:// URL
+func criContainers(endpoint string) (map[string]ContainerInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, "unix://"+strings.TrimPrefix(endpoint, "unix://"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	resp, err := runtimeapi.NewRuntimeServiceClient(conn).ListContainers(ctx, &runtimeapi.ListContainersRequest{
+		Filter: &runtimeapi.ContainerFilter{
+			State: &runtimeapi.ContainerStateValue{State: runtimeapi.ContainerState_CONTAINER_RUNNING},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	containers := make(map[string]ContainerInfo, len(resp.Containers))
+	for _, c := range resp.Containers {
+		containers[c.Id] = criContainerInfo(c)
+	}
+	return containers, nil
+}
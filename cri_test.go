@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// Fake CRI runtime service serving a fixed container list
+type fakeRuntimeService struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+	containers []*runtimeapi.Container
+}
+
+func (f *fakeRuntimeService) ListContainers(ctx context.Context, req *runtimeapi.ListContainersRequest) (*runtimeapi.ListContainersResponse, error) {
+	return &runtimeapi.ListContainersResponse{Containers: f.containers}, nil
+}
+
+func testCRIContainer() *runtimeapi.Container {
+	return &runtimeapi.Container{
+		Id:       testContainerID,
+		Metadata: &runtimeapi.ContainerMetadata{Name: "app"},
+		Image:    &runtimeapi.ImageSpec{Image: "registry.example.com/shop/web:1.4"},
+		Labels: map[string]string{
+			criLabelPodName:       "web-1",
+			criLabelPodNamespace:  "shop",
+			criLabelContainerName: "app",
+		},
+	}
+}
+
+func TestCRIContainerInfo(t *testing.T) {
+	assert := assert.New(t)
+	info := criContainerInfo(testCRIContainer())
+	assert.Equal("app", info.Name)
+	assert.Equal("registry.example.com/shop/web:1.4", info.Image)
+	assert.Equal(&PodInfo{Namespace: "shop", Pod: "web-1", Container: "app"}, info.pod)
+
+	// Containers not created by the kubelet have no pod
+	info = criContainerInfo(&runtimeapi.Container{Id: testContainerID, Metadata: &runtimeapi.ContainerMetadata{Name: "buildkitd"}})
+	assert.Equal("buildkitd", info.Name)
+	assert.Nil(info.pod)
+}
+
+func TestCRIContainers(t *testing.T) {
+	assert := assert.New(t)
+	socket := filepath.Join(t.TempDir(), "containerd.sock")
+	listener, err := net.Listen("unix", socket)
+	if !assert.NoError(err) {
+		return
+	}
+	server := grpc.NewServer()
+	runtimeapi.RegisterRuntimeServiceServer(server, &fakeRuntimeService{containers: []*runtimeapi.Container{testCRIContainer()}})
+	go server.Serve(listener)
+	defer server.Stop()
+
+	containers, err := criContainers("unix://" + socket)
+	assert.NoError(err)
+	assert.Equal("app", containers[testContainerID].Name)
+	containers, err = criContainers(socket)
+	assert.NoError(err)
+	assert.Len(containers, 1)
+}
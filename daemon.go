@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/makijapan/cpu-process-profiler/profilerpb"
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/sensu/sensu-go/types"
+	"google.golang.org/grpc"
+)
+
+// Struct to hold the options of the daemon subcommand
+type DaemonConfig struct {
+	Period      string
+	Buffer      int
+	ReportEvery string
+	Listen      string
+	GRPCListen  string
+
+	// Parsed forms of options, set by daemonArgs
+	periodDuration time.Duration
+	reportDuration time.Duration
+}
+
+var (
+	daemon = DaemonConfig{}
+
+	daemonOptions = []*sensu.PluginConfigOption{
+		{
+			Path:     "period",
+			Argument: "period",
+			Default:  "10s",
+			Usage:    "How often to start a sample, at least --sample-interval",
+			Value:    &daemon.Period,
+		},
+		{
+			Path:     "buffer",
+			Argument: "buffer",
+			Default:  60,
+			Usage:    "Number of most recent samples to keep for aggregated output and the HTTP API",
+			Value:    &daemon.Buffer,
+		},
+		{
+			Path:     "report-every",
+			Argument: "report-every",
+			Default:  "1m",
+			Usage:    "Print the aggregate of the buffered samples this often, 0 to disable",
+			Value:    &daemon.ReportEvery,
+		},
+		{
+			Path:     "listen",
+			Argument: "listen",
+			Default:  "",
+			Usage:    "Serve the buffered samples over HTTP on this address (e.g. 127.0.0.1:8080)",
+			Value:    &daemon.Listen,
+		},
+		{
+			Path:     "grpc-listen",
+			Argument: "grpc-listen",
+			Default:  "",
+			Usage:    "Stream samples to subscribers of the gRPC Profiler service on this address (e.g. 127.0.0.1:9090)",
+			Value:    &daemon.GRPCListen,
+		},
+	}
+)
+
+// Struct to hold the most recent results of a daemon, oldest first
+type resultBuffer struct {
+	mu      sync.Mutex
+	size    int
+	results []*Result
+}
+
+// Function to add a result, dropping the oldest one when the buffer is full
+func (b *resultBuffer) add(r *Result) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.results = append(b.results, r)
+	if len(b.results) > b.size {
+		b.results = append([]*Result(nil), b.results[len(b.results)-b.size:]...)
+	}
+}
+
+// Function to get the buffered results, oldest first
+func (b *resultBuffer) snapshot() []*Result {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*Result(nil), b.results...)
+}
+
+// Function to aggregate buffered results into one. Metrics and the CPU
+// breakdown are averaged, the status is the worst seen and processes are
+// ranked by their CPU usage averaged over every result, counting zero where
+// they were not listed.
+func aggregateResults(results []*Result) *Result {
+	agg := &Result{Disabled: plugin.disabled}
+	if len(results) == 0 {
+		agg.Status = sensu.CheckStateUnknown
+		agg.Summary = "no samples collected yet"
+		return agg
+	}
+	n := float64(len(results))
+
+	var maxUsed float64
+	var names []string
+	sums := make(map[string]float64)
+	breached := make(map[string]bool)
+	processes := make(map[string]*ProcessInfo)
+	for _, r := range results {
+		if r.Status > agg.Status {
+			agg.Status = r.Status
+		}
+		if r.Usage.Used > maxUsed {
+			maxUsed = r.Usage.Used
+		}
+		u := r.Usage
+		agg.Usage = CPUUsage{
+			Idle:      agg.Usage.Idle + u.Idle/n,
+			Used:      agg.Usage.Used + u.Used/n,
+			User:      agg.Usage.User + u.User/n,
+			System:    agg.Usage.System + u.System/n,
+			Nice:      agg.Usage.Nice + u.Nice/n,
+			Iowait:    agg.Usage.Iowait + u.Iowait/n,
+			Irq:       agg.Usage.Irq + u.Irq/n,
+			Softirq:   agg.Usage.Softirq + u.Softirq/n,
+			Steal:     agg.Usage.Steal + u.Steal/n,
+			Guest:     agg.Usage.Guest + u.Guest/n,
+			GuestNice: agg.Usage.GuestNice + u.GuestNice/n,
+		}
+		for _, m := range r.Metrics {
+			if _, ok := sums[m.Name]; !ok {
+				names = append(names, m.Name)
+			}
+			sums[m.Name] += m.Value
+		}
+		for _, b := range r.Breached {
+			if !breached[b] {
+				breached[b] = true
+				agg.Breached = append(agg.Breached, b)
+			}
+		}
+		for _, p := range r.Processes {
+			key := processKey(p)
+			if _, ok := processes[key]; !ok {
+				processes[key] = &ProcessInfo{PID: p.PID, Name: p.Name, CreatedAt: p.CreatedAt}
+			}
+			processes[key].CPU += p.CPU / n
+		}
+	}
+
+	for _, name := range names {
+		agg.Metrics = append(agg.Metrics, Metric{name, sums[name] / n})
+	}
+	var processList []ProcessInfo
+	for _, p := range processes {
+		processList = append(processList, *p)
+	}
+	sort.Slice(processList, func(i, j int) bool {
+		return processList[i].PID < processList[j].PID
+	})
+	agg.Processes = topCPUProcesses(processList, 10)
+
+	first, last := results[0].Timestamp, results[len(results)-1].Timestamp
+	agg.Timestamp = last
+	agg.Summary = fmt.Sprintf("%.2f%% average CPU usage (max %.2f%%) over %d samples since %s", agg.Usage.Used, maxUsed, len(results), first.Format(time.RFC3339))
+	return agg
+}
+
+// Function to validate the plugin options along with those of the daemon
+func daemonArgs(event *types.Event) (int, error) {
+	if status, err := checkArgs(event); err != nil {
+		return status, err
+	}
+	period, err := time.ParseDuration(daemon.Period)
+	if err != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--period: %v", err)
+	}
+	if period < plugin.intervalDuration {
+		return sensu.CheckStateWarning, fmt.Errorf("--period cannot be shorter than --sample-interval")
+	}
+	daemon.periodDuration = period
+	if daemon.Buffer < 1 {
+		return sensu.CheckStateWarning, fmt.Errorf("--buffer must be at least 1")
+	}
+	report, err := time.ParseDuration(daemon.ReportEvery)
+	if err != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--report-every: %v", err)
+	}
+	if report < 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--report-every cannot be negative")
+	}
+	daemon.reportDuration = report
+	if report == 0 && daemon.Listen == "" && daemon.GRPCListen == "" {
+		return sensu.CheckStateWarning, fmt.Errorf("--report-every 0 requires --listen or --grpc-listen")
+	}
+	return sensu.CheckStateOK, nil
+}
+
+// Function to sample every period until stopped, keeping the most recent
+// results to print aggregated at every report and to serve over HTTP, and
+// streaming every sample over gRPC. Start
+// jitter is only applied before the first sample. A failed sample is logged
+// and the daemon carries on.
+func executeDaemon(event *types.Event) (int, error) {
+	buffer := &resultBuffer{size: daemon.Buffer}
+	hub := newSampleHub(buffer)
+	errc := make(chan error, 2)
+	if daemon.Listen != "" {
+		go func() {
+			errc <- fmt.Errorf("Error serving HTTP API: %v", http.ListenAndServe(daemon.Listen, daemonHandler(buffer)))
+		}()
+	}
+	if daemon.GRPCListen != "" {
+		lis, err := net.Listen("tcp", daemon.GRPCListen)
+		if err != nil {
+			return sensu.CheckStateCritical, fmt.Errorf("Error serving gRPC API: %v", err)
+		}
+		server := grpc.NewServer()
+		profilerpb.RegisterProfilerServer(server, &profilerServer{hub: hub})
+		go func() {
+			errc <- fmt.Errorf("Error serving gRPC API: %v", server.Serve(lis))
+		}()
+	}
+
+	sleepStartJitter(plugin.jitterDuration)
+	plugin.jitterDuration = 0
+	ticker := time.NewTicker(daemon.periodDuration)
+	defer ticker.Stop()
+	lastReport := time.Now()
+	for {
+		result, err := runCheckWithin(plugin.timeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", plugin.PluginConfig.Name, err)
+		} else {
+			hub.record(result)
+			if err := saveHistory(result); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", plugin.PluginConfig.Name, err)
+			}
+		}
+		if daemon.reportDuration > 0 && time.Since(lastReport) >= daemon.reportDuration {
+			fmt.Print(formatResult(aggregateResults(buffer.snapshot())))
+			lastReport = time.Now()
+		}
+
+		select {
+		case err := <-errc:
+			return sensu.CheckStateCritical, err
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Struct to hold the most recent sample without its process list, as
+// served at /summary
+type DaemonSummary struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Status      int       `json:"status"`
+	Summary     string    `json:"summary"`
+	Usage       CPUUsage  `json:"usage"`
+	Metrics     []Metric  `json:"metrics"`
+	Breached    []string  `json:"breached,omitempty"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+}
+
+// Struct to hold the top processes of the most recent sample, as served at
+// /processes
+type DaemonProcesses struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Processes []ProcessInfo `json:"processes"`
+	Threads   []ThreadInfo  `json:"threads,omitempty"`
+}
+
+// Struct to hold the health of the daemon, as served at /health
+type DaemonHealth struct {
+	Status     string     `json:"status"`
+	Samples    int        `json:"samples"`
+	LastSample *time.Time `json:"last_sample,omitempty"`
+}
+
+// Function to tell whether the daemon is healthy: it is starting until the
+// first sample is collected, and stale when the last one is older than
+// maxAge, which means sampling has stopped or keeps failing
+func daemonHealth(results []*Result, now time.Time, maxAge time.Duration) (DaemonHealth, bool) {
+	health := DaemonHealth{Status: "starting", Samples: len(results)}
+	if len(results) == 0 {
+		return health, false
+	}
+	last := results[len(results)-1].Timestamp
+	health.LastSample = &last
+	if now.Sub(last) > maxAge {
+		health.Status = "stale"
+		return health, false
+	}
+	health.Status = "ok"
+	return health, true
+}
+
+// Function to build the HTTP API of the daemon. /results serves every
+// buffered result, /summary and /processes the most recent one and /health
+// whether samples are still coming in, as three sample periods without one
+// make the daemon stale.
+func daemonHandler(buffer *resultBuffer) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/results", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, buffer.snapshot())
+	})
+	mux.HandleFunc("/summary", func(w http.ResponseWriter, r *http.Request) {
+		latest, ok := latestResult(w, buffer)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, DaemonSummary{
+			Timestamp:   latest.Timestamp,
+			Status:      latest.Status,
+			Summary:     latest.Summary,
+			Usage:       latest.Usage,
+			Metrics:     latest.Metrics,
+			Breached:    latest.Breached,
+			Fingerprint: latest.Fingerprint,
+		})
+	})
+	mux.HandleFunc("/processes", func(w http.ResponseWriter, r *http.Request) {
+		latest, ok := latestResult(w, buffer)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, DaemonProcesses{
+			Timestamp: latest.Timestamp,
+			Processes: latest.Processes,
+			Threads:   latest.Threads,
+		})
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		health, ok := daemonHealth(buffer.snapshot(), time.Now(), 3*daemon.periodDuration)
+		status := http.StatusOK
+		if !ok {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, health)
+	})
+	return mux
+}
+
+// Function to get the most recent result, answering 503 when there is none
+// yet
+func latestResult(w http.ResponseWriter, buffer *resultBuffer) (*Result, bool) {
+	results := buffer.snapshot()
+	if len(results) == 0 {
+		http.Error(w, "no samples collected yet", http.StatusServiceUnavailable)
+		return nil, false
+	}
+	return results[len(results)-1], true
+}
+
+// Function to write a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaemonHandler(t *testing.T) {
+	assert := assert.New(t)
+	buffer := &resultBuffer{size: 5}
+	buffer.add(&Result{Summary: "10.00% CPU usage"})
+
+	rec := httptest.NewRecorder()
+	daemonHandler(buffer).ServeHTTP(rec, httptest.NewRequest("GET", "/results", nil))
+	assert.Equal(200, rec.Code)
+	assert.Equal("application/json", rec.Header().Get("Content-Type"))
+	var results []Result
+	assert.NoError(json.Unmarshal(rec.Body.Bytes(), &results))
+	assert.Len(results, 1)
+	assert.Equal("10.00% CPU usage", results[0].Summary)
+}
+
+func TestDaemonHealth(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2024, 9, 2, 12, 0, 0, 0, time.UTC)
+	health, ok := daemonHealth(nil, now, 30*time.Second)
+	assert.False(ok)
+	assert.Equal("starting", health.Status)
+
+	results := []*Result{{Timestamp: now.Add(-10 * time.Second)}}
+	health, ok = daemonHealth(results, now, 30*time.Second)
+	assert.True(ok)
+	assert.Equal(DaemonHealth{Status: "ok", Samples: 1, LastSample: &results[0].Timestamp}, health)
+
+	health, ok = daemonHealth(results, now.Add(time.Minute), 30*time.Second)
+	assert.False(ok)
+	assert.Equal("stale", health.Status)
+}
+
+func TestDaemonHandlerLatest(t *testing.T) {
+	assert := assert.New(t)
+	buffer := &resultBuffer{size: 5}
+	handler := daemonHandler(buffer)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/summary", nil))
+	assert.Equal(503, rec.Code)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(503, rec.Code)
+
+	buffer.add(&Result{Summary: "10.00% CPU usage"})
+	buffer.add(testResult())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/summary", nil))
+	assert.Equal(200, rec.Code)
+	var summary map[string]interface{}
+	assert.NoError(json.Unmarshal(rec.Body.Bytes(), &summary))
+	assert.Equal("95.00% CPU usage", summary["summary"])
+	assert.NotContains(summary, "processes")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/processes", nil))
+	assert.Equal(200, rec.Code)
+	var processes DaemonProcesses
+	assert.NoError(json.Unmarshal(rec.Body.Bytes(), &processes))
+	assert.Len(processes.Processes, 2)
+	assert.Equal("java", processes.Processes[0].Name)
+}
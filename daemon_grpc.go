@@ -0,0 +1,125 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/makijapan/cpu-process-profiler/profilerpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Number of samples queued for a gRPC subscriber before new ones are dropped
+const subscriberQueue = 16
+
+// Struct to record the samples of a daemon in its buffer and pass them on to
+// gRPC subscribers. Both happen under one lock, so a subscriber replaying
+// the buffer neither misses nor repeats a sample.
+type sampleHub struct {
+	mu          sync.Mutex
+	buffer      *resultBuffer
+	subscribers map[chan *profilerpb.Sample]bool
+}
+
+// Function to create a hub recording samples in a buffer
+func newSampleHub(buffer *resultBuffer) *sampleHub {
+	return &sampleHub{buffer: buffer, subscribers: make(map[chan *profilerpb.Sample]bool)}
+}
+
+// Function to record a result and send it to every subscriber. A subscriber
+// too slow to keep up misses samples rather than holding up the daemon.
+func (h *sampleHub) record(r *Result) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buffer.add(r)
+	if len(h.subscribers) == 0 {
+		return
+	}
+	sample := resultSample(r)
+	for ch := range h.subscribers {
+		select {
+		case ch <- sample:
+		default:
+		}
+	}
+}
+
+// Function to subscribe to new samples, returning the buffered ones when
+// replay is set, the channel new ones arrive on and a function to
+// unsubscribe
+func (h *sampleHub) subscribe(replay bool) ([]*Result, chan *profilerpb.Sample, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var buffered []*Result
+	if replay {
+		buffered = h.buffer.snapshot()
+	}
+	ch := make(chan *profilerpb.Sample, subscriberQueue)
+	h.subscribers[ch] = true
+	return buffered, ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers, ch)
+	}
+}
+
+// Struct to implement the gRPC Profiler service
+type profilerServer struct {
+	profilerpb.UnimplementedProfilerServer
+	hub *sampleHub
+}
+
+// Function to stream samples to a subscriber until it goes away
+func (s *profilerServer) Subscribe(req *profilerpb.SubscribeRequest, stream profilerpb.Profiler_SubscribeServer) error {
+	buffered, ch, unsubscribe := s.hub.subscribe(req.Replay)
+	defer unsubscribe()
+	for _, r := range buffered {
+		if err := stream.Send(resultSample(r)); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case sample := <-ch:
+			if err := stream.Send(sample); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Function to convert a result to the sample streamed over gRPC
+func resultSample(r *Result) *profilerpb.Sample {
+	u := r.Usage
+	cpu := &profilerpb.CPUSample{
+		Timestamp:   timestamppb.New(r.Timestamp),
+		Status:      int32(r.Status),
+		Summary:     r.Summary,
+		Idle:        u.Idle,
+		Used:        u.Used,
+		User:        u.User,
+		System:      u.System,
+		Nice:        u.Nice,
+		Iowait:      u.Iowait,
+		Irq:         u.Irq,
+		Softirq:     u.Softirq,
+		Steal:       u.Steal,
+		Guest:       u.Guest,
+		GuestNice:   u.GuestNice,
+		Breached:    r.Breached,
+		Fingerprint: r.Fingerprint,
+	}
+	for _, m := range r.Metrics {
+		cpu.Metrics = append(cpu.Metrics, &profilerpb.Metric{Name: m.Name, Value: m.Value})
+	}
+	sample := &profilerpb.Sample{Cpu: cpu}
+	for _, p := range r.Processes {
+		sample.Processes = append(sample.Processes, &profilerpb.ProcessSample{
+			Pid:       p.PID,
+			Name:      p.Name,
+			Cpu:       p.CPU,
+			CreatedAt: timestamppb.New(p.CreatedAt),
+		})
+	}
+	return sample
+}
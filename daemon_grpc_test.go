@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/makijapan/cpu-process-profiler/profilerpb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestResultSample(t *testing.T) {
+	assert := assert.New(t)
+	sample := resultSample(testResult())
+	assert.Equal(int32(2), sample.Cpu.Status)
+	assert.Equal("95.00% CPU usage", sample.Cpu.Summary)
+	assert.Equal(95.0, sample.Cpu.Used)
+	assert.Len(sample.Cpu.Metrics, 2)
+	assert.Equal("cpu_idle", sample.Cpu.Metrics[0].Name)
+	assert.Equal([]string{"cpu_critical"}, sample.Cpu.Breached)
+	assert.Len(sample.Processes, 2)
+	assert.Equal(int32(42), sample.Processes[0].Pid)
+	assert.Equal(90.0, sample.Processes[0].Cpu)
+	assert.Equal(time.Date(2024, 9, 2, 12, 0, 0, 0, time.UTC), sample.Cpu.Timestamp.AsTime())
+}
+
+func TestProfilerSubscribe(t *testing.T) {
+	assert := assert.New(t)
+	hub := newSampleHub(&resultBuffer{size: 5})
+	hub.record(&Result{Summary: "buffered"})
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	profilerpb.RegisterProfilerServer(server, &profilerServer{hub: hub})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(err)
+	defer conn.Close()
+
+	stream, err := profilerpb.NewProfilerClient(conn).Subscribe(ctx, &profilerpb.SubscribeRequest{Replay: true})
+	assert.NoError(err)
+	sample, err := stream.Recv()
+	assert.NoError(err)
+	assert.Equal("buffered", sample.Cpu.Summary)
+
+	// The replayed sample is only sent once the subscription is registered
+	hub.record(&Result{Summary: "live"})
+	sample, err = stream.Recv()
+	assert.NoError(err)
+	assert.Equal("live", sample.Cpu.Summary)
+}
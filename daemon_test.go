@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultBuffer(t *testing.T) {
+	assert := assert.New(t)
+	buffer := &resultBuffer{size: 2}
+	for _, s := range []string{"a", "b", "c"} {
+		buffer.add(&Result{Summary: s})
+	}
+	snapshot := buffer.snapshot()
+	assert.Len(snapshot, 2)
+	assert.Equal("b", snapshot[0].Summary)
+	assert.Equal("c", snapshot[1].Summary)
+}
+
+func TestAggregateResults(t *testing.T) {
+	assert := assert.New(t)
+	start := time.Date(2024, 9, 2, 12, 0, 0, 0, time.UTC)
+	results := []*Result{
+		{
+			Timestamp: start,
+			Status:    sensu.CheckStateOK,
+			Usage:     CPUUsage{Used: 20, Idle: 80},
+			Metrics:   []Metric{{"cpu_idle", 80}},
+			Processes: []ProcessInfo{{PID: 1, Name: "java", CPU: 20}},
+		},
+		{
+			Timestamp: start.Add(10 * time.Second),
+			Status:    sensu.CheckStateCritical,
+			Usage:     CPUUsage{Used: 60, Idle: 40},
+			Metrics:   []Metric{{"cpu_idle", 40}},
+			Processes: []ProcessInfo{{PID: 1, Name: "java", CPU: 40}, {PID: 2, Name: "nginx", CPU: 50}},
+			Breached:  []string{"cpu_critical"},
+		},
+	}
+	agg := aggregateResults(results)
+	assert.Equal(sensu.CheckStateCritical, agg.Status)
+	assert.Equal("40.00% average CPU usage (max 60.00%) over 2 samples since 2024-09-02T12:00:00Z", agg.Summary)
+	assert.Equal(start.Add(10*time.Second), agg.Timestamp)
+	assert.InDelta(60, agg.Usage.Idle, 0.001)
+	assert.Equal([]Metric{{"cpu_idle", 60}}, agg.Metrics)
+	assert.Equal([]string{"cpu_critical"}, agg.Breached)
+	assert.Len(agg.Processes, 2)
+	assert.Equal("java", agg.Processes[0].Name)
+	assert.InDelta(30, agg.Processes[0].CPU, 0.001)
+	assert.InDelta(25, agg.Processes[1].CPU, 0.001)
+
+	assert.Equal(sensu.CheckStateUnknown, aggregateResults(nil).Status)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/sensu/sensu-go/types"
+)
+
+// Function to build the handler serving the Go runtime profiles of the
+// plugin itself under /debug/pprof/, as net/http/pprof does, answering 401 to
+// requests without the bearer token when there is one
+func debugPprofHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return requireToken(token, mux)
+}
+
+// Function to wrap a handler so it answers 401 to requests without the
+// bearer token, when there is one
+func requireToken(token string, h http.Handler) http.Handler {
+	if token == "" {
+		return h
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Function to tell whether a listen address only accepts local connections
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Function to start serving the Go runtime profiles on an address, returning
+// once it listens
+func startDebugPprof(addr, token string) (*http.Server, net.Addr, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv := &http.Server{Handler: debugPprofHandler(token)}
+	go func() { _ = srv.Serve(ln) }()
+	return srv, ln.Addr(), nil
+}
+
+// Function to wrap the validation of a subcommand with that of the
+// --debug-pprof-* options, which the profiles are not served without: a
+// listen address, on loopback unless there is a token
+func debugPprofArgs(validate func(*types.Event) (int, error)) func(*types.Event) (int, error) {
+	return func(event *types.Event) (int, error) {
+		if plugin.DebugPprofListen == "" && plugin.DebugPprofToken != "" {
+			return sensu.CheckStateWarning, fmt.Errorf("--debug-pprof-token requires --debug-pprof-listen")
+		}
+		if plugin.DebugPprofListen != "" && plugin.DebugPprofToken == "" && !isLoopbackAddr(plugin.DebugPprofListen) {
+			return sensu.CheckStateWarning, fmt.Errorf("--debug-pprof-listen requires a loopback address or --debug-pprof-token")
+		}
+		return validate(event)
+	}
+}
+
+// Function to wrap the execution of a subcommand so the Go runtime profiles
+// are served on --debug-pprof-listen for as long as it runs
+func withDebugPprof(execute func(*types.Event) (int, error)) func(*types.Event) (int, error) {
+	return func(event *types.Event) (int, error) {
+		if plugin.DebugPprofListen == "" {
+			return execute(event)
+		}
+		srv, _, err := startDebugPprof(plugin.DebugPprofListen, plugin.DebugPprofToken)
+		if err != nil {
+			return sensu.CheckStateCritical, fmt.Errorf("Error serving pprof endpoints: %v", err)
+		}
+		defer srv.Close()
+		return execute(event)
+	}
+}
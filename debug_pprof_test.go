@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugPprofHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	rec := httptest.NewRecorder()
+	debugPprofHandler("").ServeHTTP(rec, httptest.NewRequest("GET", "/debug/pprof/goroutine?debug=1", nil))
+	assert.Equal(200, rec.Code)
+	assert.Contains(rec.Body.String(), "goroutine profile")
+
+	handler := debugPprofHandler("s3cret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/pprof/heap", nil))
+	assert.Equal(401, rec.Code)
+	assert.Equal("Bearer", rec.Header().Get("WWW-Authenticate"))
+	req := httptest.NewRequest("GET", "/debug/pprof/heap", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(401, rec.Code)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(200, rec.Code)
+	assert.NotEmpty(rec.Body.Bytes())
+}
+
+func TestStartDebugPprof(t *testing.T) {
+	assert := assert.New(t)
+	srv, addr, err := startDebugPprof("127.0.0.1:0", "")
+	if !assert.NoError(err) {
+		return
+	}
+	defer srv.Close()
+	resp, err := http.Get("http://" + addr.String() + "/debug/pprof/cmdline")
+	if assert.NoError(err) {
+		resp.Body.Close()
+		assert.Equal(200, resp.StatusCode)
+	}
+}
+
+func TestDebugPprofArgs(t *testing.T) {
+	assert := assert.New(t)
+	defer func() { plugin.DebugPprofListen, plugin.DebugPprofToken = "", "" }()
+	validate := debugPprofArgs(func(*types.Event) (int, error) { return sensu.CheckStateOK, nil })
+
+	status, err := validate(nil)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+
+	plugin.DebugPprofListen = "127.0.0.1:6060"
+	_, err = validate(nil)
+	assert.NoError(err)
+
+	plugin.DebugPprofListen = ":6060"
+	status, err = validate(nil)
+	assert.Error(err)
+	assert.Equal(sensu.CheckStateWarning, status)
+	plugin.DebugPprofToken = "s3cret"
+	_, err = validate(nil)
+	assert.NoError(err)
+
+	plugin.DebugPprofListen = ""
+	_, err = validate(nil)
+	assert.Error(err)
+}
+
+func TestIsLoopbackAddr(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(isLoopbackAddr("127.0.0.1:8080"))
+	assert.True(isLoopbackAddr("[::1]:8080"))
+	assert.True(isLoopbackAddr("localhost:8080"))
+	assert.False(isLoopbackAddr(":8080"))
+	assert.False(isLoopbackAddr("0.0.0.0:8080"))
+	assert.False(isLoopbackAddr("10.0.0.5:8080"))
+	assert.False(isLoopbackAddr("127.0.0.1"))
+}
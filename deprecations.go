@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/sensu/sensu-go/types"
+)
+
+// Struct to describe a renamed option. Old and New are both the flag name and
+// the annotation keyspace path, as every option uses the same name for both.
+type Deprecation struct {
+	Old string
+	New string
+}
+
+// Options that have been renamed, checked against the command line and the
+// event annotations so existing check configs keep working
+var deprecations = []Deprecation{
+	{Old: "interval", New: "sample-interval"},
+}
+
+// Function to print a deprecation warning, replaceable in tests
+var deprecationWarning = func(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", a...)
+}
+
+// Function to rewrite deprecated flags in the command line arguments to their
+// new names
+func migrateArgs(args []string) []string {
+	migrated := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(migrated, args[i:]...)
+		}
+		for _, d := range deprecations {
+			old := "--" + d.Old
+			if arg == old || strings.HasPrefix(arg, old+"=") {
+				deprecationWarning("--%s is deprecated, use --%s instead", d.Old, d.New)
+				arg = "--" + d.New + strings.TrimPrefix(arg, old)
+				break
+			}
+		}
+		migrated = append(migrated, arg)
+	}
+	return migrated
+}
+
+// Function to build options for the deprecated annotation keyspace paths.
+// Each one shares the value of the option it was renamed to and has no flag,
+// so an annotation under the old path still overrides the setting. They are
+// meant to be placed before the regular options so the new path wins when
+// both are set.
+func deprecatedOptions(opts []*sensu.PluginConfigOption) []*sensu.PluginConfigOption {
+	var aliases []*sensu.PluginConfigOption
+	for _, d := range deprecations {
+		for _, opt := range opts {
+			if opt.Path == d.New {
+				aliases = append(aliases, &sensu.PluginConfigOption{
+					Path:  d.Old,
+					Value: opt.Value,
+					Usage: fmt.Sprintf("Deprecated, use %s", d.New),
+				})
+				break
+			}
+		}
+	}
+	return aliases
+}
+
+// Function to warn about deprecated annotation keyspace paths used in an event
+func warnDeprecatedAnnotations(event *types.Event) {
+	if event == nil {
+		return
+	}
+	for _, d := range deprecations {
+		key := path.Join(plugin.Keyspace, d.Old)
+		if (event.Check != nil && event.Check.Annotations[key] != "") ||
+			(event.Entity != nil && event.Entity.Annotations[key] != "") {
+			deprecationWarning("annotation %s is deprecated, use %s instead", key, path.Join(plugin.Keyspace, d.New))
+		}
+	}
+}
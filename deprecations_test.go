@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	corev2 "github.com/sensu/sensu-go/api/core/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func captureDeprecationWarnings(t *testing.T) *[]string {
+	var warnings []string
+	saved := deprecationWarning
+	t.Cleanup(func() { deprecationWarning = saved })
+	deprecationWarning = func(format string, a ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, a...))
+	}
+	return &warnings
+}
+
+func TestMigrateArgs(t *testing.T) {
+	assert := assert.New(t)
+	warnings := captureDeprecationWarnings(t)
+
+	args := migrateArgs([]string{"--interval", "2", "-c", "90"})
+	assert.Equal([]string{"--sample-interval", "2", "-c", "90"}, args)
+	args = migrateArgs([]string{"--interval=500ms"})
+	assert.Equal([]string{"--sample-interval=500ms"}, args)
+	args = migrateArgs([]string{"--sample-interval", "2s", "--", "--interval"})
+	assert.Equal([]string{"--sample-interval", "2s", "--", "--interval"}, args)
+	assert.Len(*warnings, 2)
+}
+
+func TestDeprecatedOptions(t *testing.T) {
+	assert := assert.New(t)
+	var interval string
+	opts := []*sensu.PluginConfigOption{{Path: "sample-interval", Argument: "sample-interval", Value: &interval}}
+	aliases := deprecatedOptions(opts)
+	assert.Len(aliases, 1)
+	assert.Equal("interval", aliases[0].Path)
+	assert.Empty(aliases[0].Argument)
+	assert.Equal(&interval, aliases[0].Value)
+}
+
+func TestWarnDeprecatedAnnotations(t *testing.T) {
+	assert := assert.New(t)
+	warnings := captureDeprecationWarnings(t)
+	event := corev2.FixtureEvent("entity1", "check1")
+	warnDeprecatedAnnotations(event)
+	assert.Empty(*warnings)
+	event.Check.Annotations = map[string]string{plugin.Keyspace + "/interval": "5s"}
+	warnDeprecatedAnnotations(event)
+	assert.Len(*warnings, 1)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Struct to hold the container a process belongs to. A container listed by
+// the CRI also carries the pod it belongs to.
+type ContainerInfo struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Image string `json:"image"`
+	pod   *PodInfo
+}
+
+// Function to parse the response of the Docker API's container list into the
+// containers by ID
+func parseDockerContainers(r io.Reader) (map[string]ContainerInfo, error) {
+	var list []struct {
+		ID    string   `json:"Id"`
+		Names []string `json:"Names"`
+		Image string   `json:"Image"`
+	}
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, err
+	}
+	containers := make(map[string]ContainerInfo, len(list))
+	for _, c := range list {
+		info := ContainerInfo{ID: c.ID, Image: c.Image}
+		if len(c.Names) > 0 {
+			info.Name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		containers[c.ID] = info
+	}
+	return containers, nil
+}
+
+// Function to list the running containers through the Docker API on a unix
+// socket
+func dockerContainers(socket string) (map[string]ContainerInfo, error) {
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	resp, err := client.Get("http://docker/containers/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response from the Docker API: %s", resp.Status)
+	}
+	return parseDockerContainers(resp.Body)
+}
+
+// Function to roll up the CPU usage of processes by the container they
+// belong to, as container_cpu_<name> metrics
+func containerMetrics(processes []ProcessInfo) []Metric {
+	var metrics []Metric
+	index := make(map[string]int)
+	for _, p := range processes {
+		if p.Container == nil {
+			continue
+		}
+		i, ok := index[p.Container.ID]
+		if !ok {
+			i = len(metrics)
+			index[p.Container.ID] = i
+			metrics = append(metrics, Metric{"container_cpu_" + p.Container.Name, 0})
+		}
+		metrics[i].Value += p.CPU
+	}
+	return metrics
+}
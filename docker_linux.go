@@ -0,0 +1,29 @@
+package main
+
+// Function to annotate processes with the container they run in, from the
+// containers listed by the Docker API or the CRI. The pod of a CRI container
+// is used when the process was not attributed to one already.
+func attributeContainers(processes []ProcessInfo, containers map[string]ContainerInfo) {
+	for i, p := range processes {
+		if id, ok := processContainerID(p.PID, ""); ok {
+			if c, ok := containers[id]; ok {
+				processes[i].Container = &c
+				if processes[i].Pod == nil {
+					processes[i].Pod = c.pod
+				}
+			}
+		}
+	}
+}
+
+// Function to check that processes can be mapped to Docker containers
+func probeDockerAttribution() error {
+	_, err := dockerContainers(plugin.DockerSocket)
+	return err
+}
+
+// Function to check that processes can be mapped to CRI containers
+func probeCRIAttribution() error {
+	_, err := criContainers(plugin.CRISocket)
+	return err
+}
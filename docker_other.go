@@ -0,0 +1,17 @@
+//go:build !linux
+
+package main
+
+// Function to annotate processes with the container they run in
+func attributeContainers(processes []ProcessInfo, containers map[string]ContainerInfo) {}
+
+// Function to check that processes can be mapped to Docker containers, which
+// needs the cgroups of Linux
+func probeDockerAttribution() error {
+	return errUnsupported
+}
+
+// Function to check that processes can be mapped to CRI containers
+func probeCRIAttribution() error {
+	return errUnsupported
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDockerContainers = `[{"Id":"` + testContainerID + `","Names":["/web"],"Image":"nginx:1.25","State":"running"}]`
+
+func TestParseDockerContainers(t *testing.T) {
+	assert := assert.New(t)
+	containers, err := parseDockerContainers(strings.NewReader(testDockerContainers))
+	assert.NoError(err)
+	assert.Equal(map[string]ContainerInfo{testContainerID: {ID: testContainerID, Name: "web", Image: "nginx:1.25"}}, containers)
+
+	_, err = parseDockerContainers(strings.NewReader("page not found"))
+	assert.Error(err)
+}
+
+func TestDockerContainers(t *testing.T) {
+	assert := assert.New(t)
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if !assert.NoError(err) {
+		return
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testDockerContainers))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	containers, err := dockerContainers(socket)
+	assert.NoError(err)
+	assert.Equal("web", containers[testContainerID].Name)
+
+	_, err = dockerContainers(filepath.Join(t.TempDir(), "missing.sock"))
+	assert.Error(err)
+}
+
+func TestContainerMetrics(t *testing.T) {
+	assert := assert.New(t)
+	web := &ContainerInfo{ID: testContainerID, Name: "web", Image: "nginx:1.25"}
+	processes := []ProcessInfo{
+		{PID: 1, CPU: 30, Name: "nginx", Container: web},
+		{PID: 2, CPU: 20, Name: "sshd"},
+		{PID: 3, CPU: 10, Name: "nginx", Container: web},
+	}
+	assert.Equal([]Metric{{"container_cpu_web", 40}}, containerMetrics(processes))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// Struct to hold a process seen in uninterruptible sleep (D state) during
+// the interval, and in how many of the samples
+type DStateProcess struct {
+	PID     int32  `json:"pid"`
+	Name    string `json:"name"`
+	Samples int    `json:"samples"`
+}
+
+// Struct to hold the processes seen in D state over the samples of an
+// interval
+type DStateSamples struct {
+	Samples   int
+	Max       int
+	Processes map[int32]*DStateProcess
+}
+
+// Function to add a sample of the processes in D state, by PID with their
+// names
+func (s *DStateSamples) add(sample map[int32]string) {
+	if s.Processes == nil {
+		s.Processes = make(map[int32]*DStateProcess)
+	}
+	s.Samples++
+	if len(sample) > s.Max {
+		s.Max = len(sample)
+	}
+	for pid, name := range sample {
+		p, ok := s.Processes[pid]
+		if !ok {
+			p = &DStateProcess{PID: pid, Name: name}
+			s.Processes[pid] = p
+		}
+		p.Samples++
+	}
+}
+
+// Function to get the n processes seen in D state in the most samples
+func (s *DStateSamples) top(n int) []DStateProcess {
+	processes := make([]DStateProcess, 0, len(s.Processes))
+	for _, p := range s.Processes {
+		processes = append(processes, *p)
+	}
+	sort.Slice(processes, func(i, j int) bool {
+		if processes[i].Samples != processes[j].Samples {
+			return processes[i].Samples > processes[j].Samples
+		}
+		return processes[i].PID < processes[j].PID
+	})
+	if len(processes) > n {
+		processes = processes[:n]
+	}
+	return processes
+}
+
+// Function to get the metrics of the samples: the most processes in D state
+// at once, and how many were seen in it at all
+func (s *DStateSamples) metrics() []Metric {
+	return []Metric{
+		{"dstate_processes", float64(s.Max)},
+		{"dstate_processes_seen", float64(len(s.Processes))},
+	}
+}
+
+// Function to parse the command and state of a task from its stat file in
+// /proc. The command is in parentheses and may contain any character, so the
+// state is found after the last closing one.
+func parseStatState(data []byte) (string, byte, error) {
+	start := bytes.IndexByte(data, '(')
+	end := bytes.LastIndexByte(data, ')')
+	if start < 0 || end < start || end+2 >= len(data) {
+		return "", 0, fmt.Errorf("invalid stat line %q", data)
+	}
+	return string(data[start+1 : end]), data[end+2], nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Function to read the processes with a thread in uninterruptible sleep (D
+// state) from /proc, by PID with their names. A process counts when any of
+// its threads is, as it is usually a worker thread blocked on I/O.
+func readDStateProcesses() (map[int32]string, error) {
+	dirs, err := filepath.Glob(hostProc("[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	processes := make(map[int32]string)
+	for _, dir := range dirs {
+		pid, err := strconv.ParseInt(filepath.Base(dir), 10, 32)
+		if err != nil {
+			continue
+		}
+		tasks, err := filepath.Glob(filepath.Join(dir, "task", "[0-9]*", "stat"))
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			data, err := os.ReadFile(task)
+			if err != nil {
+				// The thread exited since
+				continue
+			}
+			if _, state, err := parseStatState(data); err != nil || state != 'D' {
+				continue
+			}
+			name := ""
+			if data, err := os.ReadFile(filepath.Join(dir, "stat")); err == nil {
+				name, _, _ = parseStatState(data)
+			}
+			processes[int32(pid)] = name
+			break
+		}
+	}
+	return processes, nil
+}
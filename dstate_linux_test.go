@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadDStateProcesses(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	t.Setenv("HOST_PROC", root)
+	write := func(file, content string) {
+		path := filepath.Join(root, file)
+		assert.NoError(os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(os.WriteFile(path, []byte(content), 0644))
+	}
+	// A worker thread of postgres is blocked, sshd is sleeping
+	write("100/stat", "100 (postgres) S 1 100 100 0 -1")
+	write("100/task/100/stat", "100 (postgres) S 1 100 100 0 -1")
+	write("100/task/101/stat", "101 (bgwriter) D 1 100 100 0 -1")
+	write("200/stat", "200 (sshd) S 1 200 200 0 -1")
+	write("200/task/200/stat", "200 (sshd) S 1 200 200 0 -1")
+	write("self/stat", "300 (cpp) R 1 300 300 0 -1")
+
+	processes, err := readDStateProcesses()
+	assert.NoError(err)
+	assert.Equal(map[int32]string{100: "postgres"}, processes)
+}
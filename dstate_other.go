@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// Function to read the processes with a thread in uninterruptible sleep (D
+// state), by PID with their names
+func readDStateProcesses() (map[int32]string, error) {
+	return nil, errUnsupported
+}
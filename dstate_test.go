@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDStateSamples(t *testing.T) {
+	assert := assert.New(t)
+	var s DStateSamples
+	s.add(map[int32]string{10: "nfsd", 20: "postgres"})
+	s.add(map[int32]string{10: "nfsd"})
+	s.add(map[int32]string{10: "nfsd", 20: "postgres", 30: "rsync"})
+	s.add(nil)
+	assert.Equal(4, s.Samples)
+	assert.Equal(3, s.Max)
+	assert.Equal([]DStateProcess{
+		{PID: 10, Name: "nfsd", Samples: 3},
+		{PID: 20, Name: "postgres", Samples: 2},
+	}, s.top(2))
+	assert.Equal([]Metric{
+		{"dstate_processes", 3},
+		{"dstate_processes_seen", 3},
+	}, s.metrics())
+}
+
+func TestParseStatState(t *testing.T) {
+	assert := assert.New(t)
+	comm, state, err := parseStatState([]byte("1234 (my (odd) app) D 1 1234 1234 0 -1"))
+	assert.NoError(err)
+	assert.Equal("my (odd) app", comm)
+	assert.Equal(byte('D'), state)
+	_, _, err = parseStatState([]byte("1234 broken"))
+	assert.Error(err)
+}
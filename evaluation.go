@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+)
+
+// Struct to hold the outcome of threshold evaluation
+type Evaluation struct {
+	Status   int
+	Breached []string
+}
+
+// Record a breached threshold, raising the status if needed
+func (e *Evaluation) breach(name string, state int) {
+	e.Breached = append(e.Breached, name)
+	if state > e.Status {
+		e.Status = state
+	}
+}
+
+// Hold the status at OK until a breach has been seen on count consecutive
+// runs, tracking the streak in state. Returns true if a breach was held back.
+func (e *Evaluation) dampen(state *State, count int) bool {
+	if e.Status == sensu.CheckStateOK {
+		state.ConsecutiveBreaches = 0
+		return false
+	}
+	state.ConsecutiveBreaches++
+	if state.ConsecutiveBreaches >= count {
+		return false
+	}
+	e.Status = sensu.CheckStateOK
+	return true
+}
+
+// Function to get the label used in the check output for a state
+func stateLabel(state int) string {
+	switch state {
+	case sensu.CheckStateOK:
+		return "OK"
+	case sensu.CheckStateWarning:
+		return "Warning"
+	case sensu.CheckStateCritical:
+		return "Critical"
+	default:
+		return "Unknown"
+	}
+}
+
+// Function to compute a stable fingerprint for an alert condition. Only the
+// breached thresholds and the name of the top offender are used, so repeated
+// alerts with the same root cause share a fingerprint even as PIDs and
+// measured values change between runs.
+func alertFingerprint(breached []string, topOffender string) string {
+	names := append([]string(nil), breached...)
+	sort.Strings(names)
+
+	h := sha256.New()
+	h.Write([]byte(strings.Join(names, ",")))
+	h.Write([]byte{0})
+	h.Write([]byte(topOffender))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluationBreach(t *testing.T) {
+	assert := assert.New(t)
+	var eval Evaluation
+	assert.Equal(sensu.CheckStateOK, eval.Status)
+	eval.breach("cpu_critical", sensu.CheckStateCritical)
+	eval.breach("steal_warning", sensu.CheckStateWarning)
+	assert.Equal(sensu.CheckStateCritical, eval.Status)
+	assert.Equal([]string{"cpu_critical", "steal_warning"}, eval.Breached)
+}
+
+func TestEvaluationDampen(t *testing.T) {
+	assert := assert.New(t)
+	var state State
+	for i := 1; i < 3; i++ {
+		eval := Evaluation{}
+		eval.breach("cpu_critical", sensu.CheckStateCritical)
+		assert.True(eval.dampen(&state, 3))
+		assert.Equal(sensu.CheckStateOK, eval.Status)
+		assert.Equal(i, state.ConsecutiveBreaches)
+	}
+	eval := Evaluation{}
+	eval.breach("cpu_critical", sensu.CheckStateCritical)
+	assert.False(eval.dampen(&state, 3))
+	assert.Equal(sensu.CheckStateCritical, eval.Status)
+
+	eval = Evaluation{}
+	assert.False(eval.dampen(&state, 3))
+	assert.Equal(0, state.ConsecutiveBreaches)
+}
+
+func TestAlertFingerprint(t *testing.T) {
+	assert := assert.New(t)
+	a := alertFingerprint([]string{"cpu_critical", "steal_warning"}, "nginx")
+	b := alertFingerprint([]string{"steal_warning", "cpu_critical"}, "nginx")
+	assert.Equal(a, b)
+	assert.Len(a, 16)
+	assert.NotEqual(a, alertFingerprint([]string{"cpu_critical"}, "nginx"))
+	assert.NotEqual(a, alertFingerprint([]string{"cpu_critical", "steal_warning"}, "java"))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Prefix of the annotations holding the structured result
+const resultAnnotationPrefix = "sensu.io/plugins/cpu-process-profiler/"
+
+// Structs to hold the parts of a Sensu event the plugin submits. The agent
+// fills in the entity and namespace. The sensu-go types are not used as
+// their JSON encoding goes through an outdated json-iterator.
+type SensuEvent struct {
+	Timestamp int64         `json:"timestamp"`
+	Check     SensuCheck    `json:"check"`
+	Metrics   *SensuMetrics `json:"metrics,omitempty"`
+}
+
+type SensuCheck struct {
+	Metadata        SensuMetadata `json:"metadata"`
+	Status          int           `json:"status"`
+	Output          string        `json:"output"`
+	Executed        int64         `json:"executed"`
+	Handlers        []string      `json:"handlers,omitempty"`
+	ProxyEntityName string        `json:"proxy_entity_name,omitempty"`
+}
+
+type SensuMetadata struct {
+	Name        string            `json:"name"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type SensuMetrics struct {
+	Handlers []string           `json:"handlers,omitempty"`
+	Points   []SensuMetricPoint `json:"points"`
+}
+
+type SensuMetricPoint struct {
+	Name      string      `json:"name"`
+	Value     float64     `json:"value"`
+	Timestamp int64       `json:"timestamp"`
+	Tags      []MetricTag `json:"tags,omitempty"`
+}
+
+// Function to build the Sensu event of a check run, with the output as
+// printed and the metrics as metric points named with --metric-prefix,
+// carrying the given tags and stamped in --metric-precision
+func sensuEvent(result *Result, output string, name string, handlers []string, tags []MetricTag) *SensuEvent {
+	event := &SensuEvent{
+		Timestamp: result.Timestamp.Unix(),
+		Check: SensuCheck{
+			Metadata: SensuMetadata{Name: name},
+			Status:   result.Status,
+			Output:   output,
+			Executed: result.Timestamp.Unix(),
+			Handlers: handlers,
+		},
+		Metrics: &SensuMetrics{Handlers: handlers},
+	}
+	for _, m := range prefixMetrics(result.Metrics, plugin.MetricPrefix) {
+		event.Metrics.Points = append(event.Metrics.Points, SensuMetricPoint{
+			Name:      m.Name,
+			Value:     m.Value,
+			Timestamp: metricTimestamp(result.Timestamp, plugin.MetricPrecision),
+			Tags:      tags,
+		})
+	}
+	return event
+}
+
+// Function to get the top processes and CPU breakdown of a result, and the
+// paths of a captured profile and thread dump, as JSON annotations, so
+// handlers can render them without parsing the output, along with its alert
+// fingerprint for handlers to deduplicate on
+func resultAnnotations(result *Result) (map[string]string, error) {
+	processes, err := json.Marshal(result.Processes)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := json.Marshal(result.Usage)
+	if err != nil {
+		return nil, err
+	}
+	annotations := map[string]string{
+		resultAnnotationPrefix + "processes": string(processes),
+		resultAnnotationPrefix + "usage":     string(usage),
+	}
+	if result.Fingerprint != "" {
+		annotations[resultAnnotationPrefix+"fingerprint"] = result.Fingerprint
+	}
+	if result.Profile != "" {
+		annotations[resultAnnotationPrefix+"profile"] = result.Profile
+	}
+	if result.ThreadDump != "" {
+		annotations[resultAnnotationPrefix+"thread_dump"] = result.ThreadDump
+	}
+	return annotations, nil
+}
+
+// Function to submit an event to the events API of a Sensu agent
+func postEvent(url string, event *SensuEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSensuEvent(t *testing.T) {
+	assert := assert.New(t)
+	result := testResult()
+	event := sensuEvent(result, "output", "cpu-process-profiler", []string{"slack"}, []MetricTag{{"team", "web"}})
+	assert.Equal("cpu-process-profiler", event.Check.Metadata.Name)
+	assert.Equal(2, event.Check.Status)
+	assert.Equal("output", event.Check.Output)
+	assert.Equal(result.Timestamp.Unix(), event.Check.Executed)
+	assert.Equal([]string{"slack"}, event.Check.Handlers)
+	assert.Len(event.Metrics.Points, 2)
+	assert.Equal("cpu_idle", event.Metrics.Points[0].Name)
+	assert.Equal(5.0, event.Metrics.Points[0].Value)
+	assert.Equal(result.Timestamp.Unix(), event.Metrics.Points[0].Timestamp)
+	assert.Equal([]MetricTag{{"team", "web"}}, event.Metrics.Points[1].Tags)
+}
+
+func TestPostEvent(t *testing.T) {
+	assert := assert.New(t)
+	var received SensuEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events" {
+			http.NotFound(w, r)
+			return
+		}
+		assert.Equal("POST", r.Method)
+		assert.NoError(json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	event := sensuEvent(testResult(), "output", "cpu", nil, nil)
+	assert.NoError(postEvent(server.URL+"/events", event))
+	assert.Equal("cpu", received.Check.Metadata.Name)
+	assert.Len(received.Metrics.Points, 2)
+
+	assert.Error(postEvent(server.URL+"/missing", event))
+}
+
+func TestResultAnnotations(t *testing.T) {
+	assert := assert.New(t)
+	result := testResult()
+	annotations, err := resultAnnotations(result)
+	assert.NoError(err)
+	assert.Len(annotations, 3)
+	assert.Equal(result.Fingerprint, annotations["sensu.io/plugins/cpu-process-profiler/fingerprint"])
+
+	var processes []ProcessInfo
+	assert.NoError(json.Unmarshal([]byte(annotations["sensu.io/plugins/cpu-process-profiler/processes"]), &processes))
+	assert.Len(processes, 2)
+	assert.Equal("java", processes[0].Name)
+	var usage CPUUsage
+	assert.NoError(json.Unmarshal([]byte(annotations["sensu.io/plugins/cpu-process-profiler/usage"]), &usage))
+	assert.Equal(result.Usage, usage)
+
+	// OK results have no fingerprint
+	result.Fingerprint = ""
+	annotations, err = resultAnnotations(result)
+	assert.NoError(err)
+	assert.Len(annotations, 2)
+
+	result.Profile = "/var/tmp/cpu-profile-42-20260901T120000.pprof"
+	annotations, err = resultAnnotations(result)
+	assert.NoError(err)
+	assert.Equal(result.Profile, annotations["sensu.io/plugins/cpu-process-profiler/profile"])
+	assert.NotContains(annotations, "sensu.io/plugins/cpu-process-profiler/thread_dump")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+)
+
+// Function to get the open file descriptor thresholds of a process, from the
+// first rule matching its name or else the given defaults
+func fdThresholds(rules []ProcessRule, name string, warning, critical float64) (float64, float64) {
+	for _, r := range rules {
+		if ok, _ := path.Match(r.Pattern, name); ok {
+			return r.Warning, r.Critical
+		}
+	}
+	return warning, critical
+}
+
+// Function to evaluate the open file descriptors of the processes against
+// their thresholds, returning the worst state along with the process with
+// the most descriptors over a threshold. Processes whose descriptors could
+// not be counted are skipped.
+func fdBreach(processes []ProcessInfo, rules []ProcessRule, warning, critical float64) (int, *ProcessInfo) {
+	status := sensu.CheckStateOK
+	var offender *ProcessInfo
+	for i, p := range processes {
+		if p.FDs == nil {
+			continue
+		}
+		w, c := fdThresholds(rules, p.Name, warning, critical)
+		fds := float64(*p.FDs)
+		s := sensu.CheckStateOK
+		if c > 0 && fds > c {
+			s = sensu.CheckStateCritical
+		} else if w > 0 && fds > w {
+			s = sensu.CheckStateWarning
+		}
+		if s == sensu.CheckStateOK {
+			continue
+		}
+		if s > status || (s == status && *p.FDs > *offender.FDs) {
+			status, offender = s, &processes[i]
+		}
+	}
+	return status, offender
+}
+
+// Function to count the open file descriptors of the processes, leaving the
+// count unset for those that cannot be read, such as the processes of other
+// users when not running as root
+func countProcessFDs(processes []ProcessInfo) {
+	for i := range processes {
+		if n, err := countFDs(processes[i].PID); err == nil {
+			processes[i].FDs = &n
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+// Function to count the open file descriptors of a process from
+// /proc/<pid>/fd
+func countFDs(pid int32) (int, error) {
+	dir, err := os.Open(hostProc(strconv.Itoa(int(pid)), "fd"))
+	if err != nil {
+		return 0, err
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return 0, err
+	}
+	return len(names), nil
+}
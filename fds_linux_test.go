@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountFDs(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	t.Setenv("HOST_PROC", root)
+	dir := filepath.Join(root, "42", "fd")
+	assert.NoError(os.MkdirAll(dir, 0755))
+	for _, fd := range []string{"0", "1", "2", "3"} {
+		assert.NoError(os.Symlink("/dev/null", filepath.Join(dir, fd)))
+	}
+
+	n, err := countFDs(42)
+	assert.NoError(err)
+	assert.Equal(4, n)
+	_, err = countFDs(43)
+	assert.Error(err)
+}
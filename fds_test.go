@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFDThresholds(t *testing.T) {
+	assert := assert.New(t)
+	rules := []ProcessRule{{Pattern: "java*", Warning: 8000, Critical: 16000}}
+	warning, critical := fdThresholds(rules, "java-agent", 1000, 2000)
+	assert.Equal(float64(8000), warning)
+	assert.Equal(float64(16000), critical)
+	warning, critical = fdThresholds(rules, "nginx", 1000, 2000)
+	assert.Equal(float64(1000), warning)
+	assert.Equal(float64(2000), critical)
+}
+
+func TestFDBreach(t *testing.T) {
+	assert := assert.New(t)
+	fds := func(n int) *int { return &n }
+	processes := []ProcessInfo{
+		{PID: 10, Name: "java", FDs: fds(9000)},
+		{PID: 20, Name: "nginx", FDs: fds(1500)},
+		{PID: 30, Name: "worker", FDs: fds(1200)},
+		{PID: 40, Name: "sshd"},
+	}
+	rules := []ProcessRule{{Pattern: "java", Warning: 8000, Critical: 16000}}
+
+	status, offender := fdBreach(processes, rules, 1000, 2000)
+	assert.Equal(sensu.CheckStateWarning, status)
+	assert.Equal(int32(10), offender.PID)
+
+	status, offender = fdBreach(processes, nil, 1000, 1400)
+	assert.Equal(sensu.CheckStateCritical, status)
+	assert.Equal(int32(10), offender.PID)
+
+	status, offender = fdBreach(processes, rules, 0, 0)
+	assert.Equal(sensu.CheckStateWarning, status)
+	assert.Equal(int32(10), offender.PID)
+
+	status, offender = fdBreach(processes[1:], nil, 0, 0)
+	assert.Equal(sensu.CheckStateOK, status)
+	assert.Nil(offender)
+}
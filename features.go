@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// Policies for options enabled on a platform that does not support them
+const (
+	unsupportedFail    = "fail"
+	unsupportedDisable = "disable"
+)
+
+// Struct to describe an option that depends on platform support. Probe
+// returns nil when the option can be collected on this host.
+type Feature struct {
+	Option  string
+	Enabled func() bool
+	Disable func()
+	Probe   func() error
+}
+
+// Options that are probed in checkArgs, so an unsupported one is rejected up
+// front instead of failing mid-collection
+var features = []Feature{
+	{
+		Option:  "--lockup-window",
+		Enabled: func() bool { return plugin.lockupDuration > 0 },
+		Disable: func() { plugin.lockupDuration, plugin.LockupCrit = 0, false },
+		Probe:   probeKmsg,
+	},
+	{
+		Option:  "--load-warning/--load-critical",
+		Enabled: func() bool { return plugin.loadWarning != nil || plugin.loadCritical != nil },
+		Disable: func() { plugin.loadWarning, plugin.loadCritical = nil, nil },
+		Probe:   probeLoadAvg,
+	},
+	{
+		Option:  "--cgroup-mode cgroup",
+		Enabled: func() bool { return plugin.useCgroup },
+		Disable: func() { plugin.useCgroup = false },
+		Probe:   func() error { _, err := readCgroupCPU(); return err },
+	},
+	{
+		Option:  "--docker-socket",
+		Enabled: func() bool { return plugin.DockerSocket != "" },
+		Disable: func() { plugin.DockerSocket, plugin.DockerRollup = "", false },
+		Probe:   probeDockerAttribution,
+	},
+	{
+		Option:  "--cri-socket",
+		Enabled: func() bool { return plugin.CRISocket != "" },
+		Disable: func() { plugin.CRISocket, plugin.DockerRollup = "", false },
+		Probe:   probeCRIAttribution,
+	},
+	{
+		Option:  "--windows-backend wmi",
+		Enabled: func() bool { return plugin.WindowsBackend == windowsBackendWMI },
+		Disable: func() { plugin.WindowsBackend = windowsBackendNative },
+		Probe:   probeWMI,
+	},
+	{
+		Option:  "--target-unit",
+		Enabled: func() bool { return plugin.TargetUnit != "" },
+		Disable: func() { plugin.TargetUnit = "" },
+		Probe:   probeUnitCgroups,
+	},
+	{
+		Option:  "--unit",
+		Enabled: func() bool { return len(plugin.Unit) > 0 },
+		Disable: func() { plugin.Unit = nil },
+		Probe:   probeUnitCgroups,
+	},
+	{
+		Option:  "--unit-rollup",
+		Enabled: func() bool { return plugin.UnitRollup },
+		Disable: func() { plugin.UnitRollup = false },
+		Probe:   func() error { _, err := readCgroupUnit(int32(os.Getpid())); return err },
+	},
+	{
+		Option:  "--service",
+		Enabled: func() bool { return len(plugin.Service) > 0 },
+		Disable: func() { plugin.Service = nil },
+		Probe:   probeServices,
+	},
+	{
+		Option:  "--launchd-label",
+		Enabled: func() bool { return len(plugin.LaunchdLabel) > 0 },
+		Disable: func() { plugin.LaunchdLabel = nil },
+		Probe:   probeLaunchd,
+	},
+	{
+		Option:  "--psi",
+		Enabled: func() bool { return len(plugin.PSI) > 0 },
+		Disable: func() { plugin.PSI, plugin.PSIWarning, plugin.PSICritical = nil, 0, 0 },
+		Probe:   probePSI,
+	},
+	{
+		Option:  "--core-warning/--core-critical/--core-imbalance/--core-spread-warning/--core-spread-critical",
+		Enabled: plugin.usesPerCPU,
+		Disable: func() {
+			plugin.CoreWarning, plugin.CoreCritical, plugin.CoreImbalance = 0, 0, false
+			plugin.CoreSpreadWarning, plugin.CoreSpreadCritical = 0, 0
+		},
+		Probe: probePerCPU,
+	},
+	{
+		Option:  "--physical-cores",
+		Enabled: func() bool { return plugin.PhysicalCores },
+		Disable: func() { plugin.PhysicalCores = false },
+		Probe:   probePhysicalCores,
+	},
+	{
+		Option:  "--frequency",
+		Enabled: func() bool { return plugin.Frequency },
+		Disable: func() { plugin.Frequency = false },
+		Probe:   func() error { _, err := readCPUFrequencies(); return err },
+	},
+	{
+		Option:  "--temperature/--temperature-warning/--temperature-critical",
+		Enabled: plugin.usesTemperature,
+		Disable: func() { plugin.Temperature, plugin.TemperatureWarning, plugin.TemperatureCritical = false, 0, 0 },
+		Probe:   func() error { _, err := readCPUTemperatures(); return err },
+	},
+	{
+		Option:  "--rapl",
+		Enabled: func() bool { return plugin.RAPL },
+		Disable: func() { plugin.RAPL = false },
+		Probe:   func() error { _, err := readRAPL(); return err },
+	},
+	{
+		Option:  "--throttling/--throttling-warning",
+		Enabled: plugin.usesThrottling,
+		Disable: func() { plugin.Throttling, plugin.ThrottlingWarning = false, false },
+		Probe:   func() error { _, err := readThrottleCounts(); return err },
+	},
+	{
+		Option:  "--governor",
+		Enabled: func() bool { return plugin.Governor },
+		Disable: func() { plugin.Governor = false },
+		Probe:   func() error { _, err := readCPUFreqPolicy(); return err },
+	},
+	{
+		Option:  "--perf",
+		Enabled: func() bool { return plugin.Perf },
+		Disable: func() { plugin.Perf = false },
+		Probe:   probePerf,
+	},
+	{
+		Option:  "--off-cpu",
+		Enabled: func() bool { return plugin.OffCPU },
+		Disable: func() { plugin.OffCPU = false },
+		Probe:   probeOffCPU,
+	},
+	{
+		Option:  "--capture-profile",
+		Enabled: func() bool { return plugin.CaptureProfile != "" },
+		Disable: func() { plugin.CaptureProfile = "" },
+		// Go processes with a pprof endpoint are profiled without perf
+		Probe: func() error {
+			if len(plugin.pprofPorts) > 0 {
+				return nil
+			}
+			return probePerf()
+		},
+	},
+	{
+		Option:  "--dstate/--dstate-warning/--dstate-critical",
+		Enabled: plugin.usesDState,
+		Disable: func() { plugin.DState, plugin.DStateWarning, plugin.DStateCritical = false, 0, 0 },
+		Probe:   func() error { _, err := readDStateProcesses(); return err },
+	},
+	{
+		Option:  "--zombies/--zombie-warning",
+		Enabled: plugin.usesZombies,
+		Disable: func() { plugin.Zombies, plugin.ZombieWarning = false, 0 },
+		Probe:   func() error { _, err := readZombieParents(); return err },
+	},
+	{
+		Option:  "--fds/--fd-warning/--fd-critical/--fd-rule",
+		Enabled: plugin.usesFDs,
+		Disable: func() { plugin.FDs, plugin.FDWarning, plugin.FDCritical, plugin.FDRules = false, 0, 0, nil },
+		Probe:   func() error { _, err := countFDs(int32(os.Getpid())); return err },
+	},
+	{
+		Option:  "--oom-score",
+		Enabled: func() bool { return plugin.OOMScore },
+		Disable: func() { plugin.OOMScore = false },
+		Probe:   func() error { _, err := readOOMScore(int32(os.Getpid())); return err },
+	},
+	{
+		Option:  "--rt-sched/--rt-critical",
+		Enabled: plugin.usesRTSched,
+		Disable: func() { plugin.RTSched, plugin.RTCritical = false, 0 },
+		Probe:   func() error { _, err := readSched(int32(os.Getpid())); return err },
+	},
+	{
+		Option:  "--nice/--nice-buckets",
+		Enabled: plugin.usesNice,
+		Disable: func() { plugin.Nice, plugin.NiceBuckets = false, false },
+		Probe:   func() error { _, err := readPriority(int32(os.Getpid())); return err },
+	},
+	{
+		Option:  "--fork-rate-warning/--fork-rate-critical",
+		Enabled: func() bool { return plugin.ForkRateWarning > 0 || plugin.ForkRateCritical > 0 },
+		Disable: func() { plugin.ForkRateWarning, plugin.ForkRateCritical = 0, 0 },
+		Probe:   func() error { _, err := readProcStat(); return err },
+	},
+	{
+		Option:  "--jvm-diagnostics",
+		Enabled: func() bool { return plugin.JVMDiagnostics },
+		Disable: func() { plugin.JVMDiagnostics = false },
+		Probe:   probeThreadDump,
+	},
+	{
+		Option:  "--history-db",
+		Enabled: func() bool { return plugin.HistoryDB != "" },
+		Disable: func() { plugin.HistoryDB = "" },
+		Probe:   probeHistory,
+	},
+}
+
+// Function to probe every enabled option against this platform, failing or
+// disabling unsupported ones according to policy. Returns the disabled
+// options along with the reason.
+func probeFeatures(list []Feature, policy string) ([]string, error) {
+	var disabled []string
+	for _, f := range list {
+		if !f.Enabled() {
+			continue
+		}
+		err := f.Probe()
+		if err == nil {
+			continue
+		}
+		if policy != unsupportedDisable {
+			return nil, fmt.Errorf("%s is not supported on this host (%s/%s): %v", f.Option, runtime.GOOS, runtime.GOARCH, err)
+		}
+		f.Disable()
+		disabled = append(disabled, fmt.Sprintf("%s (%v)", f.Option, err))
+	}
+	return disabled, nil
+}
+
+// Function to probe the pressure files of the resources given to --psi
+func probePSI() error {
+	for _, r := range plugin.PSI {
+		if _, err := readPSI(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Function to probe the per-CPU timings and CPU topology
+func probePhysicalCores() error {
+	if err := probePerCPU(); err != nil {
+		return err
+	}
+	_, err := readCoreSiblings()
+	return err
+}
+
+// Function to probe the load averages
+func probeLoadAvg() error {
+	_, err := readLoadAvg(false, 0)
+	return err
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeFeatures(t *testing.T) {
+	assert := assert.New(t)
+	var enabled, disabledCalled bool
+	list := []Feature{
+		{
+			Option:  "--supported",
+			Enabled: func() bool { return true },
+			Disable: func() { t.Fatal("supported option disabled") },
+			Probe:   func() error { return nil },
+		},
+		{
+			Option:  "--unsupported",
+			Enabled: func() bool { return enabled },
+			Disable: func() { disabledCalled = true },
+			Probe:   func() error { return errors.New("no such file") },
+		},
+	}
+
+	disabled, err := probeFeatures(list, unsupportedFail)
+	assert.NoError(err)
+	assert.Empty(disabled)
+
+	enabled = true
+	_, err = probeFeatures(list, unsupportedFail)
+	assert.Error(err)
+	assert.Contains(err.Error(), "--unsupported is not supported")
+	assert.False(disabledCalled)
+
+	disabled, err = probeFeatures(list, unsupportedDisable)
+	assert.NoError(err)
+	assert.Equal([]string{"--unsupported (no such file)"}, disabled)
+	assert.True(disabledCalled)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Struct to hold one reading of the current frequency of every logical CPU,
+// along with the highest frequency they are rated for where known, in MHz
+type CPUFrequencies struct {
+	Current []float64
+	MaxMHz  float64
+}
+
+// Function to parse the "cpu MHz" lines of /proc/cpuinfo, one per logical CPU
+func parseCPUInfoMHz(r io.Reader) ([]float64, error) {
+	var mhz []float64
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(key) != "cpu MHz" {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, err
+		}
+		mhz = append(mhz, v)
+	}
+	return mhz, scanner.Err()
+}
+
+// Function to compute the frequency metrics over every reading taken during
+// the interval: the average, minimum and maximum of all CPUs, and the average
+// as a percentage of the rated maximum where known
+func frequencyMetrics(readings []CPUFrequencies) []Metric {
+	var sum, min, max, rated float64
+	var n int
+	for _, r := range readings {
+		for _, f := range r.Current {
+			if n == 0 {
+				min, max = f, f
+			}
+			min, max = math.Min(min, f), math.Max(max, f)
+			sum += f
+			n++
+		}
+		rated = math.Max(rated, r.MaxMHz)
+	}
+	if n == 0 {
+		return nil
+	}
+	avg := sum / float64(n)
+	metrics := []Metric{
+		{"cpu_frequency_avg_mhz", avg},
+		{"cpu_frequency_min_mhz", min},
+		{"cpu_frequency_max_mhz", max},
+	}
+	if rated > 0 {
+		metrics = append(metrics, Metric{"cpu_frequency_pct_of_max", avg / rated * 100})
+	}
+	return metrics
+}
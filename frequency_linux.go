@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Function to read the current frequency of every logical CPU from cpufreq
+// in sysfs, along with the rated maximum, falling back to /proc/cpuinfo
+// where cpufreq is not available, such as on many VMs
+func readCPUFrequencies() (CPUFrequencies, error) {
+	var freqs CPUFrequencies
+	files, err := filepath.Glob(hostSys("devices", "system", "cpu", "cpu[0-9]*", "cpufreq", "scaling_cur_freq"))
+	if err != nil {
+		return freqs, err
+	}
+	for _, file := range files {
+		khz, err := readKHz(file)
+		if err != nil {
+			return freqs, err
+		}
+		freqs.Current = append(freqs.Current, khz/1000)
+		if max, err := readKHz(filepath.Join(filepath.Dir(file), "cpuinfo_max_freq")); err == nil && max/1000 > freqs.MaxMHz {
+			freqs.MaxMHz = max / 1000
+		}
+	}
+	if len(freqs.Current) > 0 {
+		return freqs, nil
+	}
+
+	f, err := os.Open(hostProc("cpuinfo"))
+	if err != nil {
+		return freqs, err
+	}
+	defer f.Close()
+	if freqs.Current, err = parseCPUInfoMHz(f); err != nil {
+		return freqs, err
+	}
+	if len(freqs.Current) == 0 {
+		return freqs, fmt.Errorf("no CPU frequencies in cpufreq or %s", hostProc("cpuinfo"))
+	}
+	return freqs, nil
+}
+
+// Function to read a frequency in kHz from a cpufreq file
+func readKHz(file string) (float64, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+}
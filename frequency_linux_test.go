@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadCPUFrequencies(t *testing.T) {
+	assert := assert.New(t)
+	sys, proc := t.TempDir(), t.TempDir()
+	t.Setenv("HOST_SYS", sys)
+	t.Setenv("HOST_PROC", proc)
+
+	// Without cpufreq, /proc/cpuinfo is read instead
+	_, err := readCPUFrequencies()
+	assert.Error(err)
+	assert.NoError(os.WriteFile(filepath.Join(proc, "cpuinfo"), []byte("processor\t: 0\ncpu MHz\t\t: 2000.000\n"), 0644))
+	freqs, err := readCPUFrequencies()
+	assert.NoError(err)
+	assert.Equal(CPUFrequencies{Current: []float64{2000}}, freqs)
+
+	for cpu, cur := range map[string]string{"cpu0": "3400000", "cpu1": "800000"} {
+		dir := filepath.Join(sys, "devices", "system", "cpu", cpu, "cpufreq")
+		assert.NoError(os.MkdirAll(dir, 0755))
+		assert.NoError(os.WriteFile(filepath.Join(dir, "scaling_cur_freq"), []byte(cur+"\n"), 0644))
+		assert.NoError(os.WriteFile(filepath.Join(dir, "cpuinfo_max_freq"), []byte("3600000\n"), 0644))
+	}
+	freqs, err = readCPUFrequencies()
+	assert.NoError(err)
+	assert.Equal(CPUFrequencies{Current: []float64{3400, 800}, MaxMHz: 3600}, freqs)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// Function to read the current frequency of every logical CPU
+func readCPUFrequencies() (CPUFrequencies, error) {
+	return CPUFrequencies{}, errUnsupported
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCPUInfoMHz(t *testing.T) {
+	assert := assert.New(t)
+	cpuinfo := "processor\t: 0\ncpu MHz\t\t: 2000.000\ncache size\t: 512 KB\n\nprocessor\t: 1\ncpu MHz\t\t: 1197.512\n"
+	mhz, err := parseCPUInfoMHz(strings.NewReader(cpuinfo))
+	assert.NoError(err)
+	assert.Equal([]float64{2000, 1197.512}, mhz)
+
+	_, err = parseCPUInfoMHz(strings.NewReader("cpu MHz\t\t: fast\n"))
+	assert.Error(err)
+}
+
+func TestFrequencyMetrics(t *testing.T) {
+	assert := assert.New(t)
+	assert.Nil(frequencyMetrics(nil))
+
+	metrics := frequencyMetrics([]CPUFrequencies{
+		{Current: []float64{3000, 1000}, MaxMHz: 4000},
+		{Current: []float64{2000, 2000}, MaxMHz: 4000},
+	})
+	assert.Equal([]Metric{
+		{"cpu_frequency_avg_mhz", 2000},
+		{"cpu_frequency_min_mhz", 1000},
+		{"cpu_frequency_max_mhz", 3000},
+		{"cpu_frequency_pct_of_max", 50},
+	}, metrics)
+
+	// The rated maximum is not known from /proc/cpuinfo
+	assert.Len(frequencyMetrics([]CPUFrequencies{{Current: []float64{2000}}}), 3)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Struct to hold the cpufreq policy of the CPUs: how many CPUs use each
+// governor, and whether turbo boost is enabled where the driver tells
+type CPUFreqPolicy struct {
+	Governors  map[string]int
+	Turbo      bool
+	TurboKnown bool
+}
+
+// Function to get the names of the governors in use, sorted
+func (p CPUFreqPolicy) governorNames() []string {
+	names := make([]string, 0, len(p.Governors))
+	for name := range p.Governors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Function to get the metrics of the policy: the number of CPUs using each
+// governor, and 1 or 0 for turbo boost where known
+func (p CPUFreqPolicy) metrics() []Metric {
+	var metrics []Metric
+	for _, name := range p.governorNames() {
+		metrics = append(metrics, Metric{fmt.Sprintf("cpu_governor_%s_cpus", name), float64(p.Governors[name])})
+	}
+	if p.TurboKnown {
+		turbo := 0.0
+		if p.Turbo {
+			turbo = 1
+		}
+		metrics = append(metrics, Metric{"cpu_turbo_enabled", turbo})
+	}
+	return metrics
+}
+
+// Function to describe the policy for the summary
+func (p CPUFreqPolicy) String() string {
+	s := fmt.Sprintf("%s governor", strings.Join(p.governorNames(), "/"))
+	if p.TurboKnown {
+		if p.Turbo {
+			s += ", turbo on"
+		} else {
+			s += ", turbo off"
+		}
+	}
+	return s
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Function to read the cpufreq governor of every CPU and whether turbo boost
+// is enabled, from intel_pstate/no_turbo for the Intel P-state driver and
+// cpufreq/boost for the others that support it
+func readCPUFreqPolicy() (CPUFreqPolicy, error) {
+	policy := CPUFreqPolicy{Governors: map[string]int{}}
+	files, err := filepath.Glob(hostSys("devices", "system", "cpu", "cpu[0-9]*", "cpufreq", "scaling_governor"))
+	if err != nil {
+		return policy, err
+	}
+	if len(files) == 0 {
+		return policy, fmt.Errorf("no cpufreq governors in %s", hostSys("devices", "system", "cpu"))
+	}
+	for _, file := range files {
+		governor, err := readSysfsString(file)
+		if err != nil {
+			return policy, err
+		}
+		policy.Governors[governor]++
+	}
+
+	if noTurbo, err := readSysfsString(hostSys("devices", "system", "cpu", "intel_pstate", "no_turbo")); err == nil {
+		policy.Turbo, policy.TurboKnown = noTurbo == "0", true
+	} else if boost, err := readSysfsString(hostSys("devices", "system", "cpu", "cpufreq", "boost")); err == nil {
+		policy.Turbo, policy.TurboKnown = boost == "1", true
+	} else if !os.IsNotExist(err) {
+		return policy, err
+	}
+	return policy, nil
+}
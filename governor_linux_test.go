@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadCPUFreqPolicy(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	t.Setenv("HOST_SYS", root)
+	write := func(file, content string) {
+		path := filepath.Join(root, "devices", "system", "cpu", file)
+		assert.NoError(os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(os.WriteFile(path, []byte(content+"\n"), 0644))
+	}
+
+	_, err := readCPUFreqPolicy()
+	assert.Error(err)
+
+	write("cpu0/cpufreq/scaling_governor", "powersave")
+	write("cpu1/cpufreq/scaling_governor", "powersave")
+	policy, err := readCPUFreqPolicy()
+	assert.NoError(err)
+	assert.Equal(CPUFreqPolicy{Governors: map[string]int{"powersave": 2}}, policy)
+
+	write("cpufreq/boost", "1")
+	policy, err = readCPUFreqPolicy()
+	assert.NoError(err)
+	assert.True(policy.TurboKnown)
+	assert.True(policy.Turbo)
+
+	write("intel_pstate/no_turbo", "1")
+	policy, err = readCPUFreqPolicy()
+	assert.NoError(err)
+	assert.True(policy.TurboKnown)
+	assert.False(policy.Turbo)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// Function to read the cpufreq governor of every CPU and whether turbo boost
+// is enabled
+func readCPUFreqPolicy() (CPUFreqPolicy, error) {
+	return CPUFreqPolicy{}, errUnsupported
+}
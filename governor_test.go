@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCPUFreqPolicy(t *testing.T) {
+	assert := assert.New(t)
+	policy := CPUFreqPolicy{Governors: map[string]int{"powersave": 3, "performance": 1}, Turbo: true, TurboKnown: true}
+	assert.Equal([]Metric{
+		{"cpu_governor_performance_cpus", 1},
+		{"cpu_governor_powersave_cpus", 3},
+		{"cpu_turbo_enabled", 1},
+	}, policy.metrics())
+	assert.Equal("performance/powersave governor, turbo on", policy.String())
+
+	policy = CPUFreqPolicy{Governors: map[string]int{"schedutil": 2}}
+	assert.Equal([]Metric{{"cpu_governor_schedutil_cpus", 2}}, policy.metrics())
+	assert.Equal("schedutil governor", policy.String())
+}
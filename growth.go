@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Ways to rank the process list
+const (
+	rankByCPU    = "cpu"
+	rankByGrowth = "growth"
+)
+
+// Struct to hold what is remembered about a process between runs
+type ProcessSample struct {
+	CPUTime float64 `json:"cpu_time"`
+	Share   float64 `json:"share"`
+}
+
+// Function to build the key a process is remembered under. The start time is
+// part of it so a reused PID is not mistaken for the process before it.
+func processKey(p ProcessInfo) string {
+	return fmt.Sprintf("%d-%d", p.PID, p.CreatedAt.UnixMilli())
+}
+
+// Function to get the top n processes by how much their CPU share grew since
+// the previous run. A process's share for this run is the CPU time it used
+// since the previous run, so a process whose usage is escalating is caught
+// long before its lifetime average tops the absolute ranking. Processes not
+// seen in the previous run have no growth and are ranked after the others by
+// CPU usage. The samples for the next run are stored in the state.
+func topGrowthProcesses(processList []ProcessInfo, state *State, now time.Time, n int) []ProcessInfo {
+	elapsed := now.Sub(state.ProcessesAt).Seconds()
+	samples := make(map[string]ProcessSample, len(processList))
+	ranked := make([]ProcessInfo, 0, len(processList))
+	for _, p := range processList {
+		key := processKey(p)
+		share := p.CPU
+		if prev, ok := state.Processes[key]; ok && elapsed > 0 {
+			share = (p.CPUTime - prev.CPUTime) / elapsed * 100
+			growth := share - prev.Share
+			p.Growth = &growth
+		}
+		samples[key] = ProcessSample{CPUTime: p.CPUTime, Share: share}
+		ranked = append(ranked, p)
+	}
+	state.Processes = samples
+	state.ProcessesAt = now
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		gi, gj := ranked[i].Growth, ranked[j].Growth
+		if gi != nil && gj != nil {
+			return *gi > *gj
+		}
+		if gi != nil || gj != nil {
+			return gi != nil
+		}
+		return ranked[i].CPU > ranked[j].CPU
+	})
+
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
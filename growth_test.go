@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopGrowthProcesses(t *testing.T) {
+	assert := assert.New(t)
+	created := time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 9, 2, 12, 0, 0, 0, time.UTC)
+
+	// First run, nothing to compare against so the ranking is by CPU
+	var state State
+	list := []ProcessInfo{
+		{PID: 1, CPU: 40, Name: "java", CreatedAt: created, CPUTime: 100},
+		{PID: 2, CPU: 5, Name: "leaky", CreatedAt: created, CPUTime: 10},
+	}
+	top := topGrowthProcesses(list, &state, now, 10)
+	assert.Equal([]int32{1, 2}, []int32{top[0].PID, top[1].PID})
+	assert.Nil(top[0].Growth)
+	assert.Len(state.Processes, 2)
+	assert.Equal(now, state.ProcessesAt)
+
+	// Over the next minute java used 24s (40%) and leaky 30s (50%)
+	list = []ProcessInfo{
+		{PID: 1, CPU: 40, Name: "java", CreatedAt: created, CPUTime: 124},
+		{PID: 2, CPU: 6, Name: "leaky", CreatedAt: created, CPUTime: 40},
+		{PID: 3, CPU: 90, Name: "new", CreatedAt: now, CPUTime: 1},
+	}
+	top = topGrowthProcesses(list, &state, now.Add(time.Minute), 2)
+	assert.Len(top, 2)
+	assert.Equal("leaky", top[0].Name)
+	assert.InDelta(45, *top[0].Growth, 0.001)
+	assert.Equal("java", top[1].Name)
+	assert.InDelta(0, *top[1].Growth, 0.001)
+	assert.Len(state.Processes, 3)
+
+	// A reused PID is a different process
+	list = []ProcessInfo{{PID: 2, CPU: 1, Name: "other", CreatedAt: now.Add(time.Minute), CPUTime: 41}}
+	top = topGrowthProcesses(list, &state, now.Add(2*time.Minute), 10)
+	assert.Nil(top[0].Growth)
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/sensu/sensu-go/types"
+)
+
+// Struct to hold the time range of the history and replay subcommands
+type HistoryRangeConfig struct {
+	Since string
+	Until string
+
+	// Parsed forms of options, set by historyRangeArgs
+	from time.Time
+	to   time.Time
+}
+
+// Layout of the local times accepted by --since and --until, besides RFC 3339
+const historyTimeLayout = "2006-01-02 15:04"
+
+var (
+	historyRange = HistoryRangeConfig{}
+
+	historyRangeOptions = []*sensu.PluginConfigOption{
+		{
+			Path:     "since",
+			Argument: "since",
+			Default:  "1h",
+			Usage:    "Start of the results of --history-file or --history-db to read, as a duration ago, an RFC 3339 time or a local YYYY-MM-DD HH:MM time",
+			Value:    &historyRange.Since,
+		},
+		{
+			Path:     "until",
+			Argument: "until",
+			Default:  "",
+			Usage:    "End of the results of --history-file or --history-db to read, in the same forms as --since, now when empty",
+			Value:    &historyRange.Until,
+		},
+	}
+)
+
+// Struct to hold a check result recorded in the history file, written as one
+// JSON object per line
+type HistoryRecord struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Status    int              `json:"status"`
+	Summary   string           `json:"summary"`
+	Usage     CPUUsage         `json:"usage"`
+	Processes []HistoryProcess `json:"processes"`
+}
+
+// Struct to hold a top process of a recorded check result
+type HistoryProcess struct {
+	PID  int32   `json:"pid"`
+	Name string  `json:"name"`
+	CPU  float64 `json:"cpu"`
+}
+
+// Function to get the busiest process of a record, if any
+func (r HistoryRecord) top() (HistoryProcess, bool) {
+	if len(r.Processes) == 0 {
+		return HistoryProcess{}, false
+	}
+	top := r.Processes[0]
+	for _, p := range r.Processes[1:] {
+		if p.CPU > top.CPU {
+			top = p
+		}
+	}
+	return top, true
+}
+
+// Function to get the history form of a list of processes
+func historyProcesses(processes []ProcessInfo) []HistoryProcess {
+	list := make([]HistoryProcess, len(processes))
+	for i, p := range processes {
+		list[i] = HistoryProcess{p.PID, p.Name, p.CPU}
+	}
+	return list
+}
+
+// Function to append a record to the history file, creating it if needed.
+// The line is written in a single call so records of overlapping runs do not
+// interleave.
+func appendHistoryFile(path string, record HistoryRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Function to read the records of the history file taken from one time up to
+// another, in the order they were written. Lines that cannot be parsed, such
+// as one cut short by a full disk, are skipped.
+func readHistoryFile(path string, from, to time.Time) ([]HistoryRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []HistoryRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var r HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		if r.Timestamp.Before(from) || r.Timestamp.After(to) {
+			continue
+		}
+		records = append(records, r)
+	}
+	return records, scanner.Err()
+}
+
+// Statements creating the history database tables, run on every open so a
+// new file is set up on first use
+var historySchema = []string{
+	`CREATE TABLE IF NOT EXISTS samples (
+		id INTEGER PRIMARY KEY,
+		timestamp INTEGER NOT NULL,
+		status INTEGER NOT NULL,
+		summary TEXT NOT NULL,
+		used REAL NOT NULL,
+		user REAL NOT NULL,
+		system REAL NOT NULL,
+		iowait REAL NOT NULL,
+		steal REAL NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS samples_timestamp ON samples (timestamp)`,
+	`CREATE TABLE IF NOT EXISTS processes (
+		sample_id INTEGER NOT NULL REFERENCES samples (id),
+		pid INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		cpu REAL NOT NULL,
+		started INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS processes_sample_id ON processes (sample_id)`,
+}
+
+// Function to append a result and its top processes to the history database,
+// pruning the samples older than the retention, 0 to keep everything.
+// Timestamps are stored as Unix seconds.
+func recordHistory(path string, result *Result, retention time.Duration) error {
+	db, err := openHistory(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	u := result.Usage
+	res, err := tx.Exec(`INSERT INTO samples (timestamp, status, summary, used, user, system, iowait, steal) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		result.Timestamp.Unix(), result.Status, result.Summary, u.Used, u.User, u.System, u.Iowait, u.Steal)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, p := range result.Processes {
+		if _, err := tx.Exec(`INSERT INTO processes (sample_id, pid, name, cpu, started) VALUES (?, ?, ?, ?, ?)`,
+			id, p.PID, p.Name, p.CPU, p.CreatedAt.Unix()); err != nil {
+			return err
+		}
+	}
+
+	if retention > 0 {
+		cutoff := result.Timestamp.Add(-retention).Unix()
+		if _, err := tx.Exec(`DELETE FROM processes WHERE sample_id IN (SELECT id FROM samples WHERE timestamp < ?)`, cutoff); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM samples WHERE timestamp < ?`, cutoff); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Function to read the samples of the history database taken from one time
+// up to another, oldest first, in the same form as the records of the history
+// file
+func readHistoryDB(path string, from, to time.Time) ([]HistoryRecord, error) {
+	db, err := openHistory(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT id, timestamp, status, summary, used, user, system, iowait, steal FROM samples
+		WHERE timestamp >= ? AND timestamp <= ? ORDER BY timestamp, id`, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []HistoryRecord
+	index := make(map[int64]int)
+	for rows.Next() {
+		var r HistoryRecord
+		var id, ts int64
+		u := &r.Usage
+		if err := rows.Scan(&id, &ts, &r.Status, &r.Summary, &u.Used, &u.User, &u.System, &u.Iowait, &u.Steal); err != nil {
+			return nil, err
+		}
+		r.Timestamp = time.Unix(ts, 0)
+		index[id] = len(records)
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	procs, err := db.Query(`SELECT p.sample_id, p.pid, p.name, p.cpu FROM processes p JOIN samples s ON p.sample_id = s.id
+		WHERE s.timestamp >= ? AND s.timestamp <= ? ORDER BY p.rowid`, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer procs.Close()
+	for procs.Next() {
+		var id int64
+		var p HistoryProcess
+		if err := procs.Scan(&id, &p.PID, &p.Name, &p.CPU); err != nil {
+			return nil, err
+		}
+		if i, ok := index[id]; ok {
+			records[i].Processes = append(records[i].Processes, p)
+		}
+	}
+	return records, procs.Err()
+}
+
+// Function to record a check result in --history-file and --history-db, when
+// set
+func saveHistory(result *Result) error {
+	if plugin.HistoryFile != "" {
+		record := HistoryRecord{
+			Timestamp: result.Timestamp,
+			Status:    result.Status,
+			Summary:   result.Summary,
+			Usage:     result.Usage,
+			Processes: historyProcesses(result.Processes),
+		}
+		if err := appendHistoryFile(plugin.HistoryFile, record); err != nil {
+			return fmt.Errorf("Error writing history file: %v", err)
+		}
+	}
+	if plugin.HistoryDB != "" {
+		if err := recordHistory(plugin.HistoryDB, result, plugin.historyRetention); err != nil {
+			return fmt.Errorf("Error writing history database: %v", err)
+		}
+	}
+	return nil
+}
+
+// Function to read the recorded results taken from one time up to another,
+// from --history-db when set and --history-file otherwise
+func readHistory(from, to time.Time) ([]HistoryRecord, error) {
+	if plugin.HistoryDB != "" {
+		records, err := readHistoryDB(plugin.HistoryDB, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading history database: %v", err)
+		}
+		return records, nil
+	}
+	records, err := readHistoryFile(plugin.HistoryFile, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading history file: %v", err)
+	}
+	return records, nil
+}
+
+// Function to validate the source of the subcommands reading recorded
+// results, --history-db or --history-file
+func historySourceArgs() (int, error) {
+	if plugin.HistoryDB != "" {
+		if err := probeHistory(); err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--history-db: %v", err)
+		}
+		return sensu.CheckStateOK, nil
+	}
+	if plugin.HistoryFile == "" {
+		return sensu.CheckStateWarning, fmt.Errorf("--history-file or --history-db is required")
+	}
+	return sensu.CheckStateOK, nil
+}
+
+// Function to parse a time given to --since or --until: a duration before
+// now, an RFC 3339 time or a local time
+func parseHistoryTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(historyTimeLayout, s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a duration, an RFC 3339 time or a YYYY-MM-DD HH:MM time", s)
+}
+
+// Function to validate the source and the time range shared by the history,
+// replay and calibrate subcommands
+func historyRangeArgs(now time.Time) (int, error) {
+	if status, err := historySourceArgs(); err != nil {
+		return status, err
+	}
+	from, err := parseHistoryTime(historyRange.Since, now)
+	if err != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--since: %v", err)
+	}
+	to := now
+	if historyRange.Until != "" {
+		if to, err = parseHistoryTime(historyRange.Until, now); err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--until: %v", err)
+		}
+	}
+	if to.Before(from) {
+		return sensu.CheckStateWarning, fmt.Errorf("--until cannot be before --since")
+	}
+	historyRange.from, historyRange.to = from, to
+	return sensu.CheckStateOK, nil
+}
+
+// Function to validate the arguments of the history subcommand, which only
+// reads the recorded results and takes no thresholds
+func historyArgs(event *types.Event) (int, error) {
+	warnDeprecatedAnnotations(event)
+	return historyRangeArgs(time.Now())
+}
+
+// Function to format the top process of a record with its CPU usage, empty
+// when none was recorded
+func formatHistoryTop(r HistoryRecord) string {
+	top, ok := r.top()
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s (%.2f%%)", top.Name, top.CPU)
+}
+
+// Function to format recorded results as a table
+func formatHistoryRecords(records []HistoryRecord) string {
+	rows := make([][]string, len(records))
+	for i, r := range records {
+		rows[i] = []string{
+			r.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			stateLabel(r.Status),
+			fmt.Sprintf("%.2f%%", r.Usage.Used),
+			fmt.Sprintf("%.2f%%", r.Usage.User),
+			fmt.Sprintf("%.2f%%", r.Usage.System),
+			fmt.Sprintf("%.2f%%", r.Usage.Iowait),
+			fmt.Sprintf("%.2f%%", r.Usage.Steal),
+			formatHistoryTop(r),
+		}
+	}
+	return formatTable([]string{"TIME", "STATUS", "USED", "USER", "SYSTEM", "IOWAIT", "STEAL", "TOP"}, rows)
+}
+
+// Function to print the recorded results in the time range
+func executeHistory(event *types.Event) (int, error) {
+	records, err := readHistory(historyRange.from, historyRange.to)
+	if err != nil {
+		return sensu.CheckStateCritical, err
+	}
+	fmt.Printf("%s OK: %d results from %s to %s\n", plugin.PluginConfig.Name, len(records),
+		historyRange.from.Format(time.RFC3339), historyRange.to.Format(time.RFC3339))
+	if len(records) > 0 {
+		fmt.Printf("\n%s", formatHistoryRecords(records))
+	}
+	return sensu.CheckStateOK, nil
+}
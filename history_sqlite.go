@@ -0,0 +1,31 @@
+//go:build !solaris && !(windows && 386)
+
+package main
+
+import (
+	"database/sql"
+	"net/url"
+
+	_ "modernc.org/sqlite"
+)
+
+// Function to open the history database, creating it if needed. Runs that
+// overlap wait for each other's writes rather than failing.
+func openHistory(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+(&url.URL{Path: path}).EscapedPath()+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range historySchema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// Function to probe the SQLite driver, available on every other platform
+func probeHistory() error {
+	return nil
+}
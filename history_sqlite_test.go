@@ -0,0 +1,124 @@
+//go:build !solaris && !(windows && 386)
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordHistory(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "history db.sqlite")
+	now := time.Unix(1700000000, 0)
+
+	old := &Result{
+		Timestamp: now.Add(-48 * time.Hour),
+		Summary:   "12.00% CPU usage",
+		Usage:     CPUUsage{Used: 12},
+		Processes: []ProcessInfo{{PID: 7, Name: "cron", CPU: 1}},
+	}
+	assert.NoError(recordHistory(path, old, 0))
+	cur := &Result{
+		Timestamp: now,
+		Status:    1,
+		Summary:   "85.00% CPU usage",
+		Usage:     CPUUsage{Used: 85, User: 80, System: 5},
+		Processes: []ProcessInfo{
+			{PID: 42, Name: "java", CPU: 150, CreatedAt: now.Add(-time.Hour)},
+			{PID: 43, Name: "nginx", CPU: 12.5},
+		},
+	}
+	assert.NoError(recordHistory(path, cur, 24*time.Hour))
+
+	db, err := openHistory(path)
+	assert.NoError(err)
+	defer db.Close()
+
+	var count int
+	assert.NoError(db.QueryRow(`SELECT COUNT(*) FROM samples`).Scan(&count))
+	assert.Equal(1, count)
+	assert.NoError(db.QueryRow(`SELECT COUNT(*) FROM processes`).Scan(&count))
+	assert.Equal(2, count)
+
+	var ts int64
+	var status int
+	var summary string
+	var used, user float64
+	assert.NoError(db.QueryRow(`SELECT timestamp, status, summary, used, user FROM samples`).Scan(&ts, &status, &summary, &used, &user))
+	assert.Equal(now.Unix(), ts)
+	assert.Equal(1, status)
+	assert.Equal("85.00% CPU usage", summary)
+	assert.Equal(float64(85), used)
+	assert.Equal(float64(80), user)
+
+	var name string
+	var cpu float64
+	var started int64
+	assert.NoError(db.QueryRow(`SELECT name, cpu, started FROM processes ORDER BY cpu DESC LIMIT 1`).Scan(&name, &cpu, &started))
+	assert.Equal("java", name)
+	assert.Equal(float64(150), cpu)
+	assert.Equal(now.Add(-time.Hour).Unix(), started)
+
+	assert.Error(recordHistory(filepath.Join(t.TempDir(), "missing", "history.db"), cur, 0))
+}
+
+func TestReadHistoryDB(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "history.db")
+	now := time.Unix(1700000000, 0)
+
+	for i, used := range []float64{10, 85, 20} {
+		assert.NoError(recordHistory(path, &Result{
+			Timestamp: now.Add(time.Duration(i-2) * time.Hour),
+			Status:    i % 2,
+			Summary:   "CPU usage",
+			Usage:     CPUUsage{Used: used, User: used - 5},
+			Processes: []ProcessInfo{{PID: 42, Name: "java", CPU: used * 4}, {PID: 7, Name: "cron", CPU: 1}},
+		}, 0))
+	}
+
+	records, err := readHistoryDB(path, now.Add(-90*time.Minute), now)
+	assert.NoError(err)
+	if assert.Len(records, 2) {
+		assert.Equal(now.Add(-time.Hour), records[0].Timestamp)
+		assert.Equal(1, records[0].Status)
+		assert.Equal(CPUUsage{Used: 85, User: 80}, records[0].Usage)
+		assert.Equal([]HistoryProcess{{42, "java", 340}, {7, "cron", 1}}, records[0].Processes)
+		assert.Equal(float64(20), records[1].Usage.Used)
+	}
+
+	records, err = readHistoryDB(path, now.Add(time.Hour), now.Add(2*time.Hour))
+	assert.NoError(err)
+	assert.Empty(records)
+}
+
+func TestSaveHistory(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	defer func() { plugin.HistoryFile, plugin.HistoryDB = "", "" }()
+	plugin.HistoryFile = filepath.Join(dir, "history.jsonl")
+	plugin.HistoryDB = filepath.Join(dir, "history.db")
+	now := time.Unix(1700000000, 0)
+	assert.NoError(saveHistory(&Result{Timestamp: now, Usage: CPUUsage{Used: 42}}))
+
+	// Both stores are written, and the database is read when given
+	records, err := readHistoryFile(plugin.HistoryFile, now, now)
+	assert.NoError(err)
+	assert.Len(records, 1)
+	records, err = readHistory(now, now)
+	assert.NoError(err)
+	if assert.Len(records, 1) {
+		assert.Equal(float64(42), records[0].Usage.Used)
+	}
+
+	status, err := historySourceArgs()
+	assert.NoError(err)
+	assert.Equal(0, status)
+	plugin.HistoryFile, plugin.HistoryDB = "", ""
+	_, err = historySourceArgs()
+	assert.Error(err)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryFile(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Unix(1700000000, 0)
+
+	for i, used := range []float64{10, 85, 20} {
+		record := HistoryRecord{
+			Timestamp: now.Add(time.Duration(i-2) * time.Hour),
+			Status:    i % 2,
+			Summary:   "summary",
+			Usage:     CPUUsage{Used: used},
+			Processes: []HistoryProcess{{PID: 42, Name: "java", CPU: used * 2}},
+		}
+		assert.NoError(appendHistoryFile(path, record))
+	}
+
+	records, err := readHistoryFile(path, now.Add(-90*time.Minute), now.Add(-30*time.Minute))
+	assert.NoError(err)
+	assert.Len(records, 1)
+	assert.True(now.Add(-time.Hour).Equal(records[0].Timestamp))
+	assert.Equal(1, records[0].Status)
+	assert.Equal(CPUUsage{Used: 85}, records[0].Usage)
+	assert.Equal([]HistoryProcess{{PID: 42, Name: "java", CPU: 170}}, records[0].Processes)
+
+	// A line cut short is skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	assert.NoError(err)
+	_, err = f.WriteString(`{"timestamp":"2023-11-14T22:13:20Z","sta` + "\n")
+	assert.NoError(err)
+	assert.NoError(f.Close())
+	records, err = readHistoryFile(path, now.Add(-3*time.Hour), now)
+	assert.NoError(err)
+	assert.Len(records, 3)
+
+	_, err = readHistoryFile(filepath.Join(t.TempDir(), "missing.jsonl"), now, now)
+	assert.Error(err)
+	assert.Error(appendHistoryFile(filepath.Join(t.TempDir(), "missing", "history.jsonl"), HistoryRecord{}))
+}
+
+func TestHistoryRecordTop(t *testing.T) {
+	assert := assert.New(t)
+	r := HistoryRecord{Processes: []HistoryProcess{{PID: 43, Name: "nginx", CPU: 12.5}, {PID: 42, Name: "java", CPU: 150}}}
+	top, ok := r.top()
+	assert.True(ok)
+	assert.Equal("java", top.Name)
+	assert.Equal("java (150.00%)", formatHistoryTop(r))
+
+	_, ok = HistoryRecord{}.top()
+	assert.False(ok)
+	assert.Equal("", formatHistoryTop(HistoryRecord{}))
+}
+
+func TestFormatHistoryRecords(t *testing.T) {
+	records := []HistoryRecord{{
+		Timestamp: time.Date(2024, 5, 2, 3, 11, 0, 0, time.Local),
+		Status:    1,
+		Usage:     CPUUsage{Used: 81.2, User: 74.02, System: 6.85, Iowait: 0.21},
+		Processes: []HistoryProcess{{PID: 42, Name: "java", CPU: 612.4}},
+	}}
+	assert.Equal(t, "TIME                 STATUS   USED    USER    SYSTEM  IOWAIT  STEAL  TOP\n"+
+		"2024-05-02 03:11:00  Warning  81.20%  74.02%  6.85%   0.21%   0.00%  java (612.40%)\n", formatHistoryRecords(records))
+	assert.Equal(t, "", formatHistoryRecords(nil))
+}
+
+func TestParseHistoryTime(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2024, 5, 2, 3, 30, 0, 0, time.Local)
+
+	at, err := parseHistoryTime("90m", now)
+	assert.NoError(err)
+	assert.Equal(now.Add(-90*time.Minute), at)
+
+	at, err = parseHistoryTime("2024-05-02T01:00:00Z", now)
+	assert.NoError(err)
+	assert.True(at.Equal(time.Date(2024, 5, 2, 1, 0, 0, 0, time.UTC)))
+
+	at, err = parseHistoryTime("2024-05-02 03:10", now)
+	assert.NoError(err)
+	assert.Equal(time.Date(2024, 5, 2, 3, 10, 0, 0, time.Local), at)
+
+	_, err = parseHistoryTime("yesterday", now)
+	assert.Error(err)
+}
@@ -0,0 +1,14 @@
+package main
+
+import "database/sql"
+
+// Function to open the history database. The SQLite driver does not support
+// 32-bit Windows.
+func openHistory(path string) (*sql.DB, error) {
+	return nil, errUnsupported
+}
+
+// Function to probe the SQLite driver
+func probeHistory() error {
+	return errUnsupported
+}
@@ -0,0 +1,140 @@
+//go:build integration
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+// Image built from testdata/integration for the load scenarios
+const integrationImage = "cpu-process-profiler-integration"
+
+// Struct to describe a load scenario: a container with a CPU limit running a
+// known stress-ng load, and what the plugin is expected to measure in it
+type scenario struct {
+	Name     string
+	CPUs     string
+	Load     string
+	Args     []string
+	Status   int
+	Metric   string
+	Min, Max float64
+	Top      string
+}
+
+var scenarios = []scenario{
+	{
+		Name:   "idle",
+		CPUs:   "1",
+		Args:   []string{"--cgroup-mode", "cgroup"},
+		Status: sensu.CheckStateOK,
+		Metric: "cgroup_cpu_used",
+		Min:    0, Max: 20,
+	},
+	{
+		Name:   "saturated quota",
+		CPUs:   "1",
+		Load:   "stress-ng --cpu 2 --timeout 60s --quiet",
+		Args:   []string{"--cgroup-mode", "cgroup", "--warning", "50", "--critical", "80"},
+		Status: sensu.CheckStateCritical,
+		Metric: "cgroup_cpu_used",
+		Min:    85, Max: 105,
+		Top: "stress-ng",
+	},
+	{
+		Name:   "half of quota",
+		CPUs:   "2",
+		Load:   "stress-ng --cpu 1 --timeout 60s --quiet",
+		Args:   []string{"--cgroup-mode", "cgroup", "--warning", "35", "--critical", "70"},
+		Status: sensu.CheckStateWarning,
+		Metric: "cgroup_cpu_used",
+		Min:    38, Max: 62,
+		Top: "stress-ng",
+	},
+	{
+		Name:   "partial load",
+		CPUs:   "1",
+		Load:   "stress-ng --cpu 1 --cpu-load 30 --timeout 60s --quiet",
+		Args:   []string{"--cgroup-mode", "cgroup", "--warning", "60", "--critical", "90"},
+		Status: sensu.CheckStateOK,
+		Metric: "cgroup_cpu_used",
+		Min:    15, Max: 45,
+	},
+}
+
+// Function to run the plugin in a container running the scenario's load,
+// returning the result decoded from its JSON block
+func runScenario(t *testing.T, bin string, s scenario) *Result {
+	script := "/opt/profiler/cpu-process-profiler " + strings.Join(s.Args, " ") + " --sample-interval 3s --output-json"
+	if s.Load != "" {
+		script = s.Load + " & sleep 2; " + script
+	}
+	out, err := exec.Command("docker", "run", "--rm", "--cpus", s.CPUs,
+		"-v", filepath.Dir(bin)+":/opt/profiler:ro",
+		integrationImage, "sh", "-c", script).Output()
+	// The plugin exits non-zero for Warning and Critical
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		t.Fatalf("running scenario: %v", err)
+	}
+
+	text := string(out)
+	begin := strings.Index(text, jsonBlockBegin)
+	end := strings.Index(text, jsonBlockEnd)
+	if begin < 0 || end < begin {
+		t.Fatalf("no JSON block in output:\n%s", text)
+	}
+	var result Result
+	if err := json.Unmarshal([]byte(text[begin+len(jsonBlockBegin):end]), &result); err != nil {
+		t.Fatalf("decoding JSON block: %v\n%s", err, text)
+	}
+	return &result
+}
+
+func TestIntegrationScenarios(t *testing.T) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker is not available")
+	}
+
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "cpu-process-profiler")
+	build := exec.Command("go", "build", "-o", bin, ".")
+	build.Env = append(os.Environ(), "GOOS=linux", "GOARCH="+runtime.GOARCH, "CGO_ENABLED=0")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("building plugin: %v\n%s", err, out)
+	}
+	if out, err := exec.Command("docker", "build", "-t", integrationImage, "testdata/integration").CombinedOutput(); err != nil {
+		t.Fatalf("building image: %v\n%s", err, out)
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.Name, func(t *testing.T) {
+			assert := assert.New(t)
+			result := runScenario(t, bin, s)
+			assert.Equal(s.Status, result.Status, result.Summary)
+
+			found := false
+			for _, m := range result.Metrics {
+				if m.Name == s.Metric {
+					found = true
+					assert.GreaterOrEqual(m.Value, s.Min, m.Name)
+					assert.LessOrEqual(m.Value, s.Max, m.Name)
+				}
+			}
+			assert.True(found, "metric %s not emitted", s.Metric)
+
+			if s.Top != "" && assert.NotEmpty(result.Processes) {
+				assert.True(strings.HasPrefix(result.Processes[0].Name, s.Top), "top process %s", result.Processes[0].Name)
+			}
+		})
+	}
+}
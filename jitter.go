@@ -0,0 +1,24 @@
+package main
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// Function to compute the start delay for a host. The delay is derived from
+// a hash of the host name rather than drawn at random, so each host keeps a
+// stable offset within the window and a fleet started by the same clock tick
+// spreads out evenly instead of sampling in the same second.
+func startJitter(window time.Duration, host string) time.Duration {
+	if window <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(host))
+	return time.Duration(h.Sum64() % uint64(window))
+}
+
+// Function to sleep for this host's start jitter
+func sleepStartJitter(window time.Duration) {
+	time.Sleep(startJitter(window, plugin.hostname))
+}
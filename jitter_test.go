@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartJitter(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(time.Duration(0), startJitter(0, "host1"))
+
+	window := 30 * time.Second
+	d := startJitter(window, "host1")
+	assert.Equal(d, startJitter(window, "host1"))
+	assert.True(d >= 0 && d < window)
+	assert.NotEqual(d, startJitter(window, "host2"))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// Names of the processes taken for JVMs
+var jvmProcessNames = map[string]bool{"java": true, "javaw": true}
+
+// Function to tell whether a process is a JVM from its name
+func isJVMProcess(name string) bool {
+	return jvmProcessNames[name]
+}
+
+// Function to get the command printing the thread dump of a JVM, preferring
+// jcmd and falling back to jstack on older JDKs
+func threadDumpCommand(pid int32) (string, []string, error) {
+	if _, err := exec.LookPath("jcmd"); err == nil {
+		return "jcmd", []string{strconv.Itoa(int(pid)), "Thread.print", "-l"}, nil
+	}
+	if _, err := exec.LookPath("jstack"); err == nil {
+		return "jstack", []string{"-l", strconv.Itoa(int(pid))}, nil
+	}
+	return "", nil, fmt.Errorf("neither jcmd nor jstack found in PATH")
+}
+
+// Function to capture a thread dump of a JVM into dir. Returns the path of
+// the file written.
+func captureThreadDump(pid int32, dir string) (string, error) {
+	name, args, err := threadDumpCommand(pid)
+	if err != nil {
+		return "", err
+	}
+	out, err := runCommand(name, args...)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", name, err)
+	}
+
+	file := captureFileName(dir, "jvm-threads", pid, "txt")
+	f, err := os.Create(file)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, out); err != nil {
+		f.Close()
+		os.Remove(file)
+		return "", err
+	}
+	return file, f.Close()
+}
+
+// Function to probe for a tool to take thread dumps with
+func probeThreadDump() error {
+	_, _, err := threadDumpCommand(0)
+	return err
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsJVMProcess(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(isJVMProcess("java"))
+	assert.False(isJVMProcess("javac"))
+}
+
+func TestCaptureThreadDump(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script standing in for jstack")
+	}
+	assert := assert.New(t)
+	bin := t.TempDir()
+	t.Setenv("PATH", bin)
+	assert.Error(probeThreadDump())
+
+	script := "#!/bin/sh\necho \"Full thread dump of $2\"\n"
+	assert.NoError(os.WriteFile(filepath.Join(bin, "jstack"), []byte(script), 0755))
+	assert.NoError(probeThreadDump())
+	dir := t.TempDir()
+	file, err := captureThreadDump(42, dir)
+	assert.NoError(err)
+	assert.Regexp(`/jvm-threads-42-\d{8}T\d{6}\.txt$`, file)
+	data, err := os.ReadFile(file)
+	assert.NoError(err)
+	assert.Equal("Full thread dump of 42\n", string(data))
+}
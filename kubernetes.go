@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Directory the kubelet keeps a symlink to every container's log in, named
+// <pod>_<namespace>_<container>-<container id>.log
+const kubeletContainerLogDir = "/var/log/containers"
+
+// Struct to hold the Kubernetes workload a process belongs to
+type PodInfo struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+}
+
+// Function to find the container ID in the contents of /proc/<pid>/cgroup of
+// a process in a cgroup whose path contains marker, such as "kubepods" for
+// the kubelet or "docker" for Docker, or in any cgroup for an empty marker.
+// Both the cgroupfs layout
+// (/kubepods/burstable/pod<uid>/<id>, /docker/<id>) and the systemd one
+// (/kubepods.slice/.../cri-containerd-<id>.scope, docker-<id>.scope) are
+// understood.
+func parseCgroupContainerID(r io.Reader, marker string) (string, bool) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 || !strings.Contains(parts[2], marker) {
+			continue
+		}
+		id := parts[2][strings.LastIndexByte(parts[2], '/')+1:]
+		id = strings.TrimSuffix(id, ".scope")
+		if i := strings.LastIndexByte(id, '-'); i >= 0 {
+			id = id[i+1:]
+		}
+		if isContainerID(id) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// Function to check for a 64 character hex container ID
+func isContainerID(id string) bool {
+	if len(id) != 64 {
+		return false
+	}
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Function to parse the name of a kubelet container log symlink into the
+// container ID and workload it belongs to. Pod, namespace and container
+// names cannot contain underscores, so splitting on them is unambiguous.
+func parseContainerLogName(name string) (string, PodInfo, bool) {
+	parts := strings.Split(strings.TrimSuffix(name, ".log"), "_")
+	if len(parts) != 3 {
+		return "", PodInfo{}, false
+	}
+	i := strings.LastIndexByte(parts[2], '-')
+	if i <= 0 || !isContainerID(parts[2][i+1:]) {
+		return "", PodInfo{}, false
+	}
+	return parts[2][i+1:], PodInfo{Namespace: parts[1], Pod: parts[0], Container: parts[2][:i]}, true
+}
+
+// Function to roll up the CPU usage of the listed processes by the container
+// they belong to. Perfdata has no tags, so the workload is carried in the
+// metric name as pod_cpu_<namespace>_<pod>_<container>.
+func podMetrics(processes []ProcessInfo) []Metric {
+	var metrics []Metric
+	index := make(map[PodInfo]int)
+	for _, p := range processes {
+		if p.Pod == nil {
+			continue
+		}
+		i, ok := index[*p.Pod]
+		if !ok {
+			i = len(metrics)
+			index[*p.Pod] = i
+			metrics = append(metrics, Metric{"pod_cpu_" + p.Pod.Namespace + "_" + p.Pod.Pod + "_" + p.Pod.Container, 0})
+		}
+		metrics[i].Value += p.CPU
+	}
+	return metrics
+}
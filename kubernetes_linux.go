@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+// Function to annotate processes with the Kubernetes workload they belong
+// to. Nothing is done on hosts that are not Kubernetes nodes.
+func attributePods(processes []ProcessInfo) {
+	entries, err := os.ReadDir(kubeletContainerLogDir)
+	if err != nil {
+		return
+	}
+	pods := make(map[string]PodInfo, len(entries))
+	for _, e := range entries {
+		if id, pod, ok := parseContainerLogName(e.Name()); ok {
+			pods[id] = pod
+		}
+	}
+	if len(pods) == 0 {
+		return
+	}
+
+	for i, p := range processes {
+		if id, ok := processContainerID(p.PID, "kubepods"); ok {
+			if pod, ok := pods[id]; ok {
+				processes[i].Pod = &pod
+			}
+		}
+	}
+}
+
+// Function to find the ID of the container a process runs in from its cgroup
+func processContainerID(pid int32, marker string) (string, bool) {
+	f, err := os.Open(hostProc(strconv.Itoa(int(pid)), "cgroup"))
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	return parseCgroupContainerID(f, marker)
+}
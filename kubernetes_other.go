@@ -0,0 +1,7 @@
+//go:build !linux
+
+package main
+
+// Function to annotate processes with the Kubernetes workload they belong
+// to. Nothing is done on hosts that are not Kubernetes nodes.
+func attributePods(processes []ProcessInfo) {}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testContainerID = "3f4e0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a"
+
+func TestParseCgroupContainerID(t *testing.T) {
+	assert := assert.New(t)
+	id, ok := parseCgroupContainerID(strings.NewReader("0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1234_5678.slice/cri-containerd-"+testContainerID+".scope\n"), "kubepods")
+	assert.True(ok)
+	assert.Equal(testContainerID, id)
+
+	id, ok = parseCgroupContainerID(strings.NewReader("12:cpuset:/kubepods/besteffort/pod1234-5678/"+testContainerID+"\n4:cpu,cpuacct:/kubepods/besteffort/pod1234-5678/"+testContainerID+"\n"), "kubepods")
+	assert.True(ok)
+	assert.Equal(testContainerID, id)
+
+	_, ok = parseCgroupContainerID(strings.NewReader("0::/system.slice/sensu-agent.service\n"), "kubepods")
+	assert.False(ok)
+	_, ok = parseCgroupContainerID(strings.NewReader("0::/kubepods.slice/kubepods-burstable.slice\n"), "kubepods")
+	assert.False(ok)
+
+	id, ok = parseCgroupContainerID(strings.NewReader("0::/system.slice/docker-"+testContainerID+".scope\n"), "docker")
+	assert.True(ok)
+	assert.Equal(testContainerID, id)
+	id, ok = parseCgroupContainerID(strings.NewReader("4:cpu,cpuacct:/docker/"+testContainerID+"\n"), "docker")
+	assert.True(ok)
+	assert.Equal(testContainerID, id)
+}
+
+func TestParseContainerLogName(t *testing.T) {
+	assert := assert.New(t)
+	id, pod, ok := parseContainerLogName("web-7d4b9c-x2k8p_shop_nginx-sidecar-" + testContainerID + ".log")
+	assert.True(ok)
+	assert.Equal(testContainerID, id)
+	assert.Equal(PodInfo{Namespace: "shop", Pod: "web-7d4b9c-x2k8p", Container: "nginx-sidecar"}, pod)
+
+	_, _, ok = parseContainerLogName("web_shop_nginx.log")
+	assert.False(ok)
+	_, _, ok = parseContainerLogName("kube-apiserver.log")
+	assert.False(ok)
+}
+
+func TestPodMetrics(t *testing.T) {
+	assert := assert.New(t)
+	web := &PodInfo{Namespace: "shop", Pod: "web-1", Container: "app"}
+	processes := []ProcessInfo{
+		{PID: 1, CPU: 40, Name: "java", Pod: web},
+		{PID: 2, CPU: 20, Name: "sshd"},
+		{PID: 3, CPU: 5, Name: "java", Pod: &PodInfo{Namespace: "shop", Pod: "web-1", Container: "app"}},
+	}
+	assert.Equal([]Metric{{"pod_cpu_shop_web-1_app", 45}}, podMetrics(processes))
+	assert.Empty(podMetrics(processes[1:2]))
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Function to parse the PID out of the output of "launchctl list <label>",
+// which prints the job as a dictionary with a "PID" = N; entry only while
+// the job is running. A job that is loaded but not running has PID 0.
+func parseLaunchctlPID(r io.Reader) (int32, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || strings.TrimSpace(key) != `"PID"` {
+			continue
+		}
+		pid, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), ";"), 10, 32)
+		if err != nil {
+			return 0, err
+		}
+		return int32(pid), nil
+	}
+	return 0, scanner.Err()
+}
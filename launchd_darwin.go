@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Function to list the processes of a launchd job, from the process launchd
+// started for it and its descendants. The job is looked up in the domain of
+// the user running the check, which is the system domain for root.
+func launchdPIDs(label string) ([]int32, error) {
+	out, err := runCommand("launchctl", "list", label)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find launchd job %s: %v", label, err)
+	}
+	pid, err := parseLaunchctlPID(out)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse launchd job %s: %v", label, err)
+	}
+	if pid == 0 {
+		return nil, nil
+	}
+	parents, err := processParents()
+	if err != nil {
+		return nil, err
+	}
+	return descendants(parents, pid), nil
+}
+
+// Function to check that launchd jobs can be looked up
+func probeLaunchd() error {
+	_, err := exec.LookPath("launchctl")
+	return err
+}
@@ -0,0 +1,13 @@
+//go:build !darwin
+
+package main
+
+// Function to list the processes of a launchd job
+func launchdPIDs(label string) ([]int32, error) {
+	return nil, errUnsupported
+}
+
+// Function to check that launchd jobs can be looked up
+func probeLaunchd() error {
+	return errUnsupported
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLaunchctlPID(t *testing.T) {
+	assert := assert.New(t)
+	running := `{
+	"LimitLoadToSessionType" = "System";
+	"Label" = "com.openssh.sshd";
+	"OnDemand" = true;
+	"LastExitStatus" = 0;
+	"PID" = 412;
+	"Program" = "/usr/sbin/sshd";
+	"ProgramArguments" = (
+		"/usr/sbin/sshd";
+		"-i";
+	);
+};
+`
+	pid, err := parseLaunchctlPID(strings.NewReader(running))
+	assert.NoError(err)
+	assert.Equal(int32(412), pid)
+
+	stopped := `{
+	"LimitLoadToSessionType" = "System";
+	"Label" = "com.example.agent";
+	"OnDemand" = true;
+	"LastExitStatus" = 256;
+};
+`
+	pid, err = parseLaunchctlPID(strings.NewReader(stopped))
+	assert.NoError(err)
+	assert.Equal(int32(0), pid)
+
+	_, err = parseLaunchctlPID(strings.NewReader("\t\"PID\" = abc;\n"))
+	assert.Error(err)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/load"
+)
+
+// Struct to hold 1, 5 and 15 minute load average values
+type LoadTriplet [3]float64
+
+// Function to parse a load threshold given either as a single value applied
+// to all three averages or as a "1m,5m,15m" triplet
+func parseLoadThreshold(s string) (LoadTriplet, error) {
+	var t LoadTriplet
+	parts := strings.Split(s, ",")
+	if len(parts) != 1 && len(parts) != 3 {
+		return t, fmt.Errorf("expected a value or a 1m,5m,15m triplet, got %q", s)
+	}
+	for i := range t {
+		part := parts[0]
+		if len(parts) == 3 {
+			part = parts[i]
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return t, fmt.Errorf("invalid load threshold %q", part)
+		}
+		t[i] = v
+	}
+	return t, nil
+}
+
+// Function to check whether any load average exceeds its threshold
+func (t LoadTriplet) exceededBy(avg LoadTriplet) bool {
+	for i := range t {
+		if avg[i] > t[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to read the load averages, optionally divided by the number of
+// logical CPUs
+func readLoadAvg(perCore bool, logical int) (LoadTriplet, error) {
+	avg, err := load.Avg()
+	if err != nil {
+		return LoadTriplet{}, err
+	}
+	t := LoadTriplet{avg.Load1, avg.Load5, avg.Load15}
+	if perCore && logical > 0 {
+		for i := range t {
+			t[i] /= float64(logical)
+		}
+	}
+	return t, nil
+}
+
+// Function to list the load average metrics
+func loadMetrics(avg LoadTriplet, perCore bool) []Metric {
+	prefix := "load"
+	if perCore {
+		prefix = "load_per_core"
+	}
+	return []Metric{
+		{prefix + "_1", avg[0]},
+		{prefix + "_5", avg[1]},
+		{prefix + "_15", avg[2]},
+	}
+}
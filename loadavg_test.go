@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLoadThreshold(t *testing.T) {
+	assert := assert.New(t)
+	th, err := parseLoadThreshold("4")
+	assert.NoError(err)
+	assert.Equal(LoadTriplet{4, 4, 4}, th)
+	th, err = parseLoadThreshold("4, 3,2.5")
+	assert.NoError(err)
+	assert.Equal(LoadTriplet{4, 3, 2.5}, th)
+	_, err = parseLoadThreshold("4,3")
+	assert.Error(err)
+	_, err = parseLoadThreshold("high")
+	assert.Error(err)
+}
+
+func TestLoadTripletExceededBy(t *testing.T) {
+	assert := assert.New(t)
+	th := LoadTriplet{4, 3, 2}
+	assert.False(th.exceededBy(LoadTriplet{1, 1, 1}))
+	assert.True(th.exceededBy(LoadTriplet{1, 1, 2.5}))
+	assert.Equal([]Metric{{"load_per_core_1", 1}, {"load_per_core_5", 2}, {"load_per_core_15", 3}}, loadMetrics(LoadTriplet{1, 2, 3}, true))
+}
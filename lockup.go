@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct to hold the count of lockup indicators found in the kernel log
+type LockupCounts struct {
+	SoftLockups int
+	RCUStalls   int
+}
+
+// Function to parse a /dev/kmsg record of the form
+// "priority,sequence,timestamp_us,flags;message", returning the time since
+// boot it was logged at and the message
+func parseKmsgRecord(record string) (time.Duration, string, bool) {
+	i := strings.IndexByte(record, ';')
+	if i < 0 {
+		return 0, "", false
+	}
+	prefix := strings.Split(record[:i], ",")
+	if len(prefix) < 3 {
+		return 0, "", false
+	}
+	us, err := strconv.ParseInt(prefix[2], 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	// Continuation lines carrying key=value metadata follow the message
+	msg := record[i+1:]
+	if j := strings.IndexByte(msg, '\n'); j >= 0 {
+		msg = msg[:j]
+	}
+	return time.Duration(us) * time.Microsecond, msg, true
+}
+
+// Function to count soft lockup and RCU stall messages in kernel log records
+// logged at or after since (time since boot)
+func countLockups(records []string, since time.Duration) LockupCounts {
+	var counts LockupCounts
+	for _, record := range records {
+		ts, msg, ok := parseKmsgRecord(record)
+		if !ok || ts < since {
+			continue
+		}
+		lower := strings.ToLower(msg)
+		switch {
+		case strings.Contains(lower, "soft lockup"):
+			counts.SoftLockups++
+		case strings.Contains(lower, "rcu") && strings.Contains(lower, "stall"):
+			counts.RCUStalls++
+		}
+	}
+	return counts
+}
+
+// Function to list the lockup metrics
+func lockupMetrics(counts LockupCounts) []Metric {
+	detected := 0.0
+	if counts.SoftLockups > 0 || counts.RCUStalls > 0 {
+		detected = 1
+	}
+	return []Metric{
+		{"kernel_soft_lockups", float64(counts.SoftLockups)},
+		{"kernel_rcu_stalls", float64(counts.RCUStalls)},
+		{"kernel_lockup_detected", detected},
+	}
+}
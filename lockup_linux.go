@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Function to check the kernel log can be read
+func probeKmsg() error {
+	fd, err := unix.Open("/dev/kmsg", unix.O_RDONLY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	return unix.Close(fd)
+}
+
+// Function to read every record currently held in the kernel log buffer,
+// along with the current time since boot on the same clock as the records
+func readKmsg() ([]string, time.Duration, error) {
+	// Raw syscalls are used as an os.File would park on EAGAIN waiting for
+	// new records instead of returning
+	fd, err := unix.Open("/dev/kmsg", unix.O_RDONLY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer unix.Close(fd)
+
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return nil, 0, err
+	}
+	now := time.Duration(ts.Nano())
+
+	// Each read returns a single record, EAGAIN marks the end of the buffer
+	// and EPIPE a record overwritten while reading, which is skipped
+	var records []string
+	buf := make([]byte, 8192)
+	for {
+		n, err := unix.Read(fd, buf)
+		if errors.Is(err, unix.EAGAIN) {
+			break
+		}
+		if errors.Is(err, unix.EPIPE) || errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		records = append(records, string(buf[:n]))
+	}
+	return records, now, nil
+}
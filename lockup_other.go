@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import "time"
+
+// Function to check the kernel log can be read
+func probeKmsg() error {
+	return errUnsupported
+}
+
+// Function to read every record currently held in the kernel log buffer
+func readKmsg() ([]string, time.Duration, error) {
+	return nil, 0, errUnsupported
+}
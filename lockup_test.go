@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKmsgRecord(t *testing.T) {
+	assert := assert.New(t)
+	ts, msg, ok := parseKmsgRecord("6,339,5140900,-;NET: Registered protocol family 10\n SUBSYSTEM=net\n")
+	assert.True(ok)
+	assert.Equal(5140900*time.Microsecond, ts)
+	assert.Equal("NET: Registered protocol family 10", msg)
+
+	_, _, ok = parseKmsgRecord("garbage")
+	assert.False(ok)
+}
+
+func TestCountLockups(t *testing.T) {
+	assert := assert.New(t)
+	records := []string{
+		"0,1,1000000,-;watchdog: BUG: soft lockup - CPU#3 stuck for 22s! [java:1234]",
+		"0,2,9000000,-;watchdog: BUG: soft lockup - CPU#1 stuck for 23s! [java:1234]",
+		"3,3,9500000,-;rcu: INFO: rcu_sched self-detected stall on CPU",
+		"6,4,9600000,-;eth0: link up",
+	}
+	assert.Equal(LockupCounts{SoftLockups: 1, RCUStalls: 1}, countLockups(records, 5*time.Second))
+	assert.Equal(LockupCounts{SoftLockups: 2, RCUStalls: 1}, countLockups(records, 0))
+
+	assert.Equal([]Metric{
+		{"kernel_soft_lockups", 0},
+		{"kernel_rcu_stalls", 0},
+		{"kernel_lockup_detected", 0},
+	}, lockupMetrics(LockupCounts{}))
+}
@@ -2,62 +2,299 @@ package main
 
 import (
 	"fmt"
-	"time"
+	"math"
+	"net/url"
+	"os"
+	"regexp"
 	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/sensu-community/sensu-plugin-sdk/sensu"
 	"github.com/sensu/sensu-go/types"
 	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/process"
 )
 
 // Config represents the check plugin config.
 type Config struct {
 	sensu.PluginConfig
-	Critical float64
-	Warning  float64
-	Interval int
+	Critical       float64
+	Warning        float64
+	StealCritical  float64
+	StealWarning   float64
+	CriticalCores  float64
+	WarningCores   float64
+	LoadCritical   string
+	LoadWarning    string
+	LoadPerCore    bool
+	BurstCritical  int
+	BurstWarning   int
+	LockupWindow   string
+	LockupCrit     bool
+	OutputJSON     bool
+	OutputTemplate string
+	MaxOutputBytes int
+	ProcessFormat  string
+	ProcessColumns []string
+	Color          bool
+	OnUnsupported  string
+	PSI            []string
+	PSICritical    float64
+	PSIWarning     float64
+	CgroupMode     string
+	Interval       string
+	Samples        int
+
+	HistoryFile string
+
+	DebugPprofListen string
+	DebugPprofToken  string
+
+	BreachCount         int
+	StateFile           string
+	StartJitter         string
+	Suppress            []string
+	SuppressFile        string
+	RankBy              string
+	TargetPID           int
+	TargetUnit          string
+	TargetCritical      float64
+	TargetWarning       float64
+	DockerSocket        string
+	DockerRollup        bool
+	CRISocket           string
+	WindowsBackend      string
+	PSCommand           string
+	PSFormat            string
+	ExecTimeout         string
+	Timeout             string
+	EventsAPIURL        string
+	EventsCheck         string
+	EventsHandlers      []string
+	EventsAnnotate      bool
+	ProcessEvents       bool
+	ProcessWarning      float64
+	ProcessCritical     float64
+	ProcessRules        []string
+	ConfigFile          string
+	MetricFormat        string
+	MetricTags          []string
+	MetricTagLabels     []string
+	Hostname            string
+	MetricPrecision     string
+	MetricPrefix        string
+	MetricScheme        string
+	HistoryDB           string
+	HistoryRetention    string
+	BaselineWarning     float64
+	BaselineCritical    float64
+	BaselineSamples     int
+	ThresholdProfile    []string
+	SuppressWindow      []string
+	EWMAAlpha           float64
+	EWMAThresholds      bool
+	SubInterval         string
+	SpikeThreshold      float64
+	CoreWarning         float64
+	CoreCritical        float64
+	CoreOverallMax      float64
+	CoreImbalance       bool
+	CoreSpreadWarning   float64
+	CoreSpreadCritical  float64
+	PhysicalCores       bool
+	Frequency           bool
+	Temperature         bool
+	TemperatureWarning  float64
+	TemperatureCritical float64
+	RAPL                bool
+	Throttling          bool
+	ThrottlingWarning   bool
+	Governor            bool
+	Perf                bool
+	OffCPU              bool
+	CaptureProfile      string
+	CaptureDuration     string
+	PprofPort           []string
+	JVMDiagnostics      bool
+	PyroscopeURL        string
+	ParcaURL            string
+	DState              bool
+	DStateWarning       int
+	DStateCritical      int
+	Zombies             bool
+	ZombieWarning       int
+	ForkRateWarning     float64
+	ForkRateCritical    float64
+	FDs                 bool
+	FDWarning           int
+	FDCritical          int
+	FDRules             []string
+	OOMScore            bool
+	RTSched             bool
+	RTCritical          float64
+	Nice                bool
+	NiceBuckets         bool
+	ProcessAge          bool
+	Cmdline             bool
+	CmdlineLength       int
+	RedactArgs          bool
+	RedactPattern       []string
+	WatchName           []string
+	RestartWarning      bool
+	PID                 []string
+	PIDFile             []string
+	PIDWarning          float64
+	PIDCritical         float64
+	Unit                []string
+	UnitWarning         float64
+	UnitCritical        float64
+	UnitRollup          bool
+	Service             []string
+	LaunchdLabel        []string
+
+	// Parsed forms of options, set by checkArgs
+	intervalDuration  time.Duration
+	jitterDuration    time.Duration
+	execTimeout       time.Duration
+	timeout           time.Duration
+	loadCritical      *LoadTriplet
+	loadWarning       *LoadTriplet
+	lockupDuration    time.Duration
+	disabled          []string
+	useCgroup         bool
+	outputTemplate    *template.Template
+	psCommand         []string
+	psColumns         []string
+	metricTags        []MetricTag
+	hostname          string
+	processRules      []ProcessRule
+	historyRetention  time.Duration
+	thresholdProfiles []ThresholdProfile
+	suppressWindows   []SuppressWindow
+	sampleCount       int
+	captureDuration   time.Duration
+	pprofPorts        []PprofPort
+	fdRules           []ProcessRule
+	pids              []WatchedPID
+	redactPatterns    []*regexp.Regexp
+	processColumns    []ProcessColumn
+	colorOutput       bool
+}
+
+// Struct to hold the CPU usage breakdown between two timings
+type CPUUsage struct {
+	Idle      float64 `json:"idle"`
+	Used      float64 `json:"used"`
+	User      float64 `json:"user"`
+	System    float64 `json:"system"`
+	Nice      float64 `json:"nice"`
+	Iowait    float64 `json:"iowait"`
+	Irq       float64 `json:"irq"`
+	Softirq   float64 `json:"softirq"`
+	Steal     float64 `json:"steal"`
+	Guest     float64 `json:"guest"`
+	GuestNice float64 `json:"guestnice"`
 }
 
-// Struct to hold process info
-type ProcessInfo struct {
-    PID  int32
-    CPU  float64
-    Name string
+// Struct to hold the statistics of the used CPU percentage across sub-samples
+type SampleStats struct {
+	Avg float64
+	Min float64
+	Max float64
+	P95 float64
 }
 
-// Function to get top 10 CPU consuming processes
-func getTopCPUProcesses() ([]ProcessInfo, error) {
-    procs, err := process.Processes()
-    if err != nil {
-        return nil, err
-    }
+// Function to parse the sample interval, accepting Go duration strings as well
+// as bare integers (seconds) for backward compatibility
+func parseInterval(s string) (time.Duration, error) {
+	if secs, err := strconv.Atoi(s); err == nil {
+		s = fmt.Sprintf("%ds", secs)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("interval must be greater than zero")
+	}
+	return d, nil
+}
 
-    var processList []ProcessInfo
-    for _, p := range procs {
-        cpuPercent, err := p.CPUPercent()
-        if err != nil {
-            continue
-        }
-        name, err := p.Name()
-        if err != nil {
-            continue
-        }
+// Function to sum all the fields of a CPU timing
+func totalCPUTime(t cpu.TimesStat) float64 {
+	return t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal + t.Guest + t.GuestNice
+}
 
-        processList = append(processList, ProcessInfo{p.Pid, cpuPercent, name})
-    }
+// Shortest sub-sample the CPU timings can measure. They advance in clock
+// ticks, of 10ms on Linux (USER_HZ 100) and about 15.6ms on Windows.
+const clockTick = 16 * time.Millisecond
 
-    // Sort the processes by CPU usage
-    sort.Slice(processList, func(i, j int) bool {
-        return processList[i].CPU > processList[j].CPU
-    })
+// Function to compute the CPU usage breakdown between two timings
+func cpuUsage(start, end cpu.TimesStat) CPUUsage {
+	diff := totalCPUTime(end) - totalCPUTime(start)
+	// Timings read within the same clock tick have no CPU time to divide
+	if diff <= 0 {
+		return CPUUsage{}
+	}
+	pct := func(s, e float64) float64 {
+		return ((e - s) / diff) * 100
+	}
 
-    // Keep only top 10
-    if len(processList) > 10 {
-        processList = processList[:10]
-    }
+	usage := CPUUsage{
+		Idle:      pct(start.Idle, end.Idle),
+		User:      pct(start.User, end.User),
+		System:    pct(start.System, end.System),
+		Nice:      pct(start.Nice, end.Nice),
+		Iowait:    pct(start.Iowait, end.Iowait),
+		Irq:       pct(start.Irq, end.Irq),
+		Softirq:   pct(start.Softirq, end.Softirq),
+		Steal:     pct(start.Steal, end.Steal),
+		Guest:     pct(start.Guest, end.Guest),
+		GuestNice: pct(start.GuestNice, end.GuestNice),
+	}
+	usage.Used = 100 - usage.Idle
+	return usage
+}
 
-    return processList, nil
+// Function to count the used CPU percentages over a threshold
+func countSpikes(values []float64, threshold float64) int {
+	var n int
+	for _, v := range values {
+		if v > threshold {
+			n++
+		}
+	}
+	return n
+}
+
+// Function to compute avg/min/max/p95 of a list of used CPU percentages
+func sampleStats(values []float64) SampleStats {
+	if len(values) == 0 {
+		return SampleStats{}
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+
+	// Nearest-rank percentile
+	rank := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return SampleStats{
+		Avg: sum / float64(len(sorted)),
+		Min: sorted[0],
+		Max: sorted[len(sorted)-1],
+		P95: sorted[rank],
+	}
 }
 
 var (
@@ -86,23 +323,996 @@ var (
 			Usage:     "Warning threshold for overall CPU usage",
 			Value:     &plugin.Warning,
 		},
+		{
+			Path:     "threshold-profile",
+			Argument: "threshold-profile",
+			Default:  []string{},
+			Usage:    "Overall CPU thresholds for a daily window in local time, in place of --warning and --critical, as HH:MM-HH:MM=warn:N,crit:N (repeatable, first match wins)",
+			Value:    &plugin.ThresholdProfile,
+		},
+		{
+			Path:     "critical-cores",
+			Argument: "critical-cores",
+			Default:  float64(0),
+			Usage:    "Critical threshold for the number of busy cores, 0 to disable",
+			Value:    &plugin.CriticalCores,
+		},
+		{
+			Path:     "warning-cores",
+			Argument: "warning-cores",
+			Default:  float64(0),
+			Usage:    "Warning threshold for the number of busy cores, 0 to disable",
+			Value:    &plugin.WarningCores,
+		},
+		{
+			Path:     "load-critical",
+			Argument: "load-critical",
+			Default:  "",
+			Usage:    "Critical threshold for load average, as a value or a 1m,5m,15m triplet",
+			Value:    &plugin.LoadCritical,
+		},
+		{
+			Path:     "load-warning",
+			Argument: "load-warning",
+			Default:  "",
+			Usage:    "Warning threshold for load average, as a value or a 1m,5m,15m triplet",
+			Value:    &plugin.LoadWarning,
+		},
+		{
+			Path:     "load-per-core",
+			Argument: "load-per-core",
+			Default:  false,
+			Usage:    "Divide load averages by the number of logical CPUs before reporting and thresholding",
+			Value:    &plugin.LoadPerCore,
+		},
+		{
+			Path:     "burst-critical",
+			Argument: "burst-critical",
+			Default:  0,
+			Usage:    "Critical threshold for the number of processes of the same name started during the sample, 0 to disable",
+			Value:    &plugin.BurstCritical,
+		},
+		{
+			Path:     "burst-warning",
+			Argument: "burst-warning",
+			Default:  0,
+			Usage:    "Warning threshold for the number of processes of the same name started during the sample, 0 to disable",
+			Value:    &plugin.BurstWarning,
+		},
+		{
+			Path:     "lockup-window",
+			Argument: "lockup-window",
+			Default:  "0s",
+			Usage:    "Look this far back in the kernel log for soft lockup and RCU stall messages (Linux only, needs access to /dev/kmsg), 0 to disable",
+			Value:    &plugin.LockupWindow,
+		},
+		{
+			Path:     "lockup-critical",
+			Argument: "lockup-critical",
+			Default:  false,
+			Usage:    "Return CRITICAL when a soft lockup or RCU stall is found within --lockup-window",
+			Value:    &plugin.LockupCrit,
+		},
+		{
+			Path:     "on-unsupported",
+			Argument: "on-unsupported",
+			Default:  unsupportedFail,
+			Usage:    "What to do when an enabled option is not supported on this host: fail, or disable it and note it in the output",
+			Value:    &plugin.OnUnsupported,
+		},
+		{
+			Path:     "psi",
+			Argument: "psi",
+			Default:  []string{},
+			Usage:    "Resources to report pressure stall information for, from cpu, io and memory (Linux 4.20+)",
+			Value:    &plugin.PSI,
+		},
+		{
+			Path:     "psi-critical",
+			Argument: "psi-critical",
+			Default:  float64(0),
+			Usage:    "Critical threshold for the some avg10 pressure of any resource given to --psi, 0 to disable",
+			Value:    &plugin.PSICritical,
+		},
+		{
+			Path:     "psi-warning",
+			Argument: "psi-warning",
+			Default:  float64(0),
+			Usage:    "Warning threshold for the some avg10 pressure of any resource given to --psi, 0 to disable",
+			Value:    &plugin.PSIWarning,
+		},
+		{
+			Path:     "cgroup-mode",
+			Argument: "cgroup-mode",
+			Default:  cgroupModeHost,
+			Usage:    "Measure utilization against the host (host), against the CPU limit of the cgroup the check runs in (cgroup), or the latter only when a quota is set (auto)",
+			Value:    &plugin.CgroupMode,
+		},
+		{
+			Path:     "output-json",
+			Argument: "output-json",
+			Default:  false,
+			Usage:    "Append a delimited machine-readable JSON block after the human-readable output",
+			Value:    &plugin.OutputJSON,
+		},
+		{
+			Path:     "output-template",
+			Argument: "output-template",
+			Default:  "",
+			Usage:    "Go template file to format the human-readable output with instead of the default layout",
+			Value:    &plugin.OutputTemplate,
+		},
+		{
+			Path:     "max-output-bytes",
+			Argument: "max-output-bytes",
+			Default:  0,
+			Usage:    "Truncate the process tables so the check output fits in this many bytes, keeping the summary and perfdata whole, 0 to disable",
+			Value:    &plugin.MaxOutputBytes,
+		},
+		{
+			Path:     "process-format",
+			Argument: "process-format",
+			Default:  processFormatText,
+			Usage:    "Format of the process table in the output, from text, csv or jsonl",
+			Value:    &plugin.ProcessFormat,
+		},
+		{
+			Path:     "process-columns",
+			Argument: "process-columns",
+			Default:  []string{},
+			Usage:    "Columns of the process table in text and csv format, from pid, name, user, cmdline, cpu, started, age, growth, rss, fds, oom_score, oom_adj, nice, priority, sched_policy, sched_priority, namespace, pod, container, image and suppressed_until (cmdline, growth, fds, oom_*, nice, priority and sched_* also need the option collecting them)",
+			Value:    &plugin.ProcessColumns,
+		},
+		{
+			Path:     "color",
+			Argument: "color",
+			Default:  false,
+			Usage:    "Highlight the processes and units over their warning or critical threshold in yellow or red, when writing to a terminal",
+			Value:    &plugin.Color,
+		},
+		{
+			Path:     "metric-format",
+			Argument: "metric-format",
+			Default:  metricFormatNagios,
+			Usage:    "Format of the metrics in the output, from nagios_perfdata on the first line, or graphite_plaintext, influxdb_line and opentsdb_line after the process list",
+			Value:    &plugin.MetricFormat,
+		},
+		{
+			Path:     "hostname",
+			Argument: "hostname",
+			Default:  "",
+			Usage:    "Host name to report metrics, proxy entities and the start jitter under instead of the one of the system, for containers or hosts behind NAT",
+			Value:    &plugin.Hostname,
+		},
+		{
+			Path:     "metric-precision",
+			Argument: "metric-precision",
+			Default:  precisionSeconds,
+			Usage:    "Precision of the timestamps of metric lines and of the metric points submitted with --events-api-url, from s, ms, us and ns (Graphite is always in s, OpenTSDB in s or ms)",
+			Value:    &plugin.MetricPrecision,
+		},
+		{
+			Path:     "metric-prefix",
+			Argument: "metric-prefix",
+			Default:  "",
+			Usage:    "Prefix for the names of every metric, separated by a dot (e.g. servers.linux)",
+			Value:    &plugin.MetricPrefix,
+		},
+		{
+			Path:     "metric-scheme",
+			Argument: "metric-scheme",
+			Default:  metricSchemeHost,
+			Usage:    "Name graphite_plaintext metrics {prefix}.{host}.{metric} (host) or {prefix}.{metric} (flat)",
+			Value:    &plugin.MetricScheme,
+		},
+		{
+			Path:     "metric-tag",
+			Argument: "metric-tag",
+			Default:  []string{},
+			Usage:    "Tag to attach to every metric point, as key=value (repeatable)",
+			Value:    &plugin.MetricTags,
+		},
+		{
+			Path:     "metric-tag-label",
+			Argument: "metric-tag-label",
+			Default:  []string{},
+			Usage:    "Entity label to attach to every metric point as a tag, read from the event on stdin so the check needs stdin enabled (repeatable)",
+			Value:    &plugin.MetricTagLabels,
+		},
+		{
+			Path:     "steal-critical",
+			Argument: "steal-critical",
+			Default:  float64(0),
+			Usage:    "Critical threshold for CPU steal time, 0 to disable",
+			Value:    &plugin.StealCritical,
+		},
+		{
+			Path:     "steal-warning",
+			Argument: "steal-warning",
+			Default:  float64(0),
+			Usage:    "Warning threshold for CPU steal time, 0 to disable",
+			Value:    &plugin.StealWarning,
+		},
+		{
+			Path:     "core-critical",
+			Argument: "core-critical",
+			Default:  float64(0),
+			Usage:    "Critical threshold for the CPU usage of the busiest logical CPU over the interval, 0 to disable",
+			Value:    &plugin.CoreCritical,
+		},
+		{
+			Path:     "core-warning",
+			Argument: "core-warning",
+			Default:  float64(0),
+			Usage:    "Warning threshold for the CPU usage of the busiest logical CPU over the interval, 0 to disable",
+			Value:    &plugin.CoreWarning,
+		},
+		{
+			Path:     "core-overall-max",
+			Argument: "core-overall-max",
+			Default:  float64(0),
+			Usage:    "Only apply --core-warning and --core-critical while overall CPU usage is below this percentage, to single out single-threaded bottlenecks, 0 to always apply",
+			Value:    &plugin.CoreOverallMax,
+		},
+		{
+			Path:     "core-imbalance",
+			Argument: "core-imbalance",
+			Default:  false,
+			Usage:    "Emit the standard deviation and the max-min spread of the CPU usage of the logical CPUs, to flag IRQ affinity or pinning problems",
+			Value:    &plugin.CoreImbalance,
+		},
+		{
+			Path:     "core-spread-critical",
+			Argument: "core-spread-critical",
+			Default:  float64(0),
+			Usage:    "Critical threshold for the spread in percentage points between the busiest and idlest logical CPU, 0 to disable",
+			Value:    &plugin.CoreSpreadCritical,
+		},
+		{
+			Path:     "core-spread-warning",
+			Argument: "core-spread-warning",
+			Default:  float64(0),
+			Usage:    "Warning threshold for the spread in percentage points between the busiest and idlest logical CPU, 0 to disable",
+			Value:    &plugin.CoreSpreadWarning,
+		},
+		{
+			Path:     "physical-cores",
+			Argument: "physical-cores",
+			Default:  false,
+			Usage:    "Also report utilization of the physical cores, counting a core as busy as its busiest SMT sibling (Linux only)",
+			Value:    &plugin.PhysicalCores,
+		},
+		{
+			Path:     "frequency",
+			Argument: "frequency",
+			Default:  false,
+			Usage:    "Sample the frequency of every logical CPU along with every sub-sample and report its average, minimum and maximum (Linux only)",
+			Value:    &plugin.Frequency,
+		},
+		{
+			Path:     "temperature",
+			Argument: "temperature",
+			Default:  false,
+			Usage:    "Report the temperature of every CPU package, from coretemp, k10temp or zenpower on Linux and the SMC on macOS",
+			Value:    &plugin.Temperature,
+		},
+		{
+			Path:     "temperature-critical",
+			Argument: "temperature-critical",
+			Default:  float64(0),
+			Usage:    "Critical threshold for the temperature of the hottest CPU package in degrees Celsius, 0 to disable",
+			Value:    &plugin.TemperatureCritical,
+		},
+		{
+			Path:     "temperature-warning",
+			Argument: "temperature-warning",
+			Default:  float64(0),
+			Usage:    "Warning threshold for the temperature of the hottest CPU package in degrees Celsius, 0 to disable",
+			Value:    &plugin.TemperatureWarning,
+		},
+		{
+			Path:     "rapl",
+			Argument: "rapl",
+			Default:  false,
+			Usage:    "Read the RAPL energy counters over the interval and report the power of every CPU package and its core, uncore and DRAM domains in watts (Linux only, usually needs root)",
+			Value:    &plugin.RAPL,
+		},
+		{
+			Path:     "throttling",
+			Argument: "throttling",
+			Default:  false,
+			Usage:    "Report the thermal throttling events of the CPU cores and packages during the interval (Linux only)",
+			Value:    &plugin.Throttling,
+		},
+		{
+			Path:     "throttling-warning",
+			Argument: "throttling-warning",
+			Default:  false,
+			Usage:    "Raise WARNING when any CPU core or package was throttled during the interval, implies --throttling",
+			Value:    &plugin.ThrottlingWarning,
+		},
+		{
+			Path:     "governor",
+			Argument: "governor",
+			Default:  false,
+			Usage:    "Report the cpufreq governor of the CPUs and whether turbo boost is enabled (Linux only)",
+			Value:    &plugin.Governor,
+		},
+		{
+			Path:     "perf",
+			Argument: "perf",
+			Default:  false,
+			Usage:    "Run perf stat over the interval to report instructions per cycle, the cache miss rate and stalled cycles (Linux only, needs perf and root or kernel.perf_event_paranoid <= 0)",
+			Value:    &plugin.Perf,
+		},
+		{
+			Path:     "off-cpu",
+			Argument: "off-cpu",
+			Default:  false,
+			Usage:    "Trace the scheduler with eBPF over the interval to list the processes blocked the longest (Linux only, needs root or CAP_BPF and CAP_PERFMON, and tracefs)",
+			Value:    &plugin.OffCPU,
+		},
+		{
+			Path:     "capture-profile",
+			Argument: "capture-profile",
+			Default:  "",
+			Usage:    "Directory to write a folded stack profile of the top offender to, recorded with perf when the check goes CRITICAL (Linux only, needs perf)",
+			Value:    &plugin.CaptureProfile,
+		},
+		{
+			Path:     "capture-duration",
+			Argument: "capture-duration",
+			Default:  "5s",
+			Usage:    "How long to record the profile of --capture-profile for",
+			Value:    &plugin.CaptureDuration,
+		},
+		{
+			Path:     "pprof-port",
+			Argument: "pprof-port",
+			Default:  []string{},
+			Usage:    "Port of the pprof endpoint of Go processes matching a name pattern, as pattern=port, to fetch the --capture-profile profile from instead of recording it with perf (repeatable, first match wins)",
+			Value:    &plugin.PprofPort,
+		},
+		{
+			Path:     "jvm-diagnostics",
+			Argument: "jvm-diagnostics",
+			Default:  false,
+			Usage:    "Write a thread dump of the top offender to the --capture-profile directory with jcmd or jstack when it is a JVM and the check goes CRITICAL",
+			Value:    &plugin.JVMDiagnostics,
+		},
+		{
+			Path:     "pyroscope-url",
+			Argument: "pyroscope-url",
+			Default:  "",
+			Usage:    "URL of a Pyroscope server to push the profiles captured by --capture-profile to",
+			Value:    &plugin.PyroscopeURL,
+		},
+		{
+			Path:     "parca-url",
+			Argument: "parca-url",
+			Default:  "",
+			Usage:    "URL of a Parca server to push the profiles captured by --capture-profile to",
+			Value:    &plugin.ParcaURL,
+		},
+		{
+			Path:     "dstate",
+			Argument: "dstate",
+			Default:  false,
+			Usage:    "Sample the processes in uninterruptible sleep (D state) along with every sub-sample and list those seen most (Linux only)",
+			Value:    &plugin.DState,
+		},
+		{
+			Path:     "dstate-critical",
+			Argument: "dstate-critical",
+			Default:  0,
+			Usage:    "Critical threshold for the most processes in D state at once during the interval, 0 to disable",
+			Value:    &plugin.DStateCritical,
+		},
+		{
+			Path:     "dstate-warning",
+			Argument: "dstate-warning",
+			Default:  0,
+			Usage:    "Warning threshold for the most processes in D state at once during the interval, 0 to disable",
+			Value:    &plugin.DStateWarning,
+		},
+		{
+			Path:     "zombies",
+			Argument: "zombies",
+			Default:  false,
+			Usage:    "Count the zombie processes and list the parents accumulating them (Linux only)",
+			Value:    &plugin.Zombies,
+		},
+		{
+			Path:     "zombie-warning",
+			Argument: "zombie-warning",
+			Default:  0,
+			Usage:    "Warning threshold for the number of zombie processes, 0 to disable",
+			Value:    &plugin.ZombieWarning,
+		},
+		{
+			Path:     "fork-rate-critical",
+			Argument: "fork-rate-critical",
+			Default:  float64(0),
+			Usage:    "Critical threshold for the processes created per second over the interval, 0 to disable (Linux only)",
+			Value:    &plugin.ForkRateCritical,
+		},
+		{
+			Path:     "fork-rate-warning",
+			Argument: "fork-rate-warning",
+			Default:  float64(0),
+			Usage:    "Warning threshold for the processes created per second over the interval, 0 to disable (Linux only)",
+			Value:    &plugin.ForkRateWarning,
+		},
+		{
+			Path:     "fds",
+			Argument: "fds",
+			Default:  false,
+			Usage:    "Count the open file descriptors of the top processes (Linux only)",
+			Value:    &plugin.FDs,
+		},
+		{
+			Path:     "fd-critical",
+			Argument: "fd-critical",
+			Default:  0,
+			Usage:    "Critical threshold for the open file descriptors of any top process, 0 to disable",
+			Value:    &plugin.FDCritical,
+		},
+		{
+			Path:     "fd-warning",
+			Argument: "fd-warning",
+			Default:  0,
+			Usage:    "Warning threshold for the open file descriptors of any top process, 0 to disable",
+			Value:    &plugin.FDWarning,
+		},
+		{
+			Path:     "fd-rule",
+			Argument: "fd-rule",
+			Default:  []string{},
+			Usage:    "Open file descriptor thresholds for the top processes whose name matches a pattern, in place of --fd-warning and --fd-critical, as pattern=warning:critical (repeatable, first match wins)",
+			Value:    &plugin.FDRules,
+		},
+		{
+			Path:     "oom-score",
+			Argument: "oom-score",
+			Default:  false,
+			Usage:    "Show the OOM killer score and adjustment of the top processes (Linux only)",
+			Value:    &plugin.OOMScore,
+		},
+		{
+			Path:     "rt-sched",
+			Argument: "rt-sched",
+			Default:  false,
+			Usage:    "Flag the top processes running under SCHED_FIFO or SCHED_RR, along with their priority (Linux only)",
+			Value:    &plugin.RTSched,
+		},
+		{
+			Path:     "rt-critical",
+			Argument: "rt-critical",
+			Default:  float64(0),
+			Usage:    "Critical threshold for the CPU usage of a top process running under SCHED_FIFO or SCHED_RR as a percentage of one core, 0 to disable",
+			Value:    &plugin.RTCritical,
+		},
+		{
+			Path:     "nice",
+			Argument: "nice",
+			Default:  false,
+			Usage:    "Show the nice value and priority of the top processes (Linux only)",
+			Value:    &plugin.Nice,
+		},
+		{
+			Path:     "nice-buckets",
+			Argument: "nice-buckets",
+			Default:  false,
+			Usage:    "List the niced background top processes apart from the interactive ones (Linux only)",
+			Value:    &plugin.NiceBuckets,
+		},
+		{
+			Path:     "process-age",
+			Argument: "process-age",
+			Default:  false,
+			Usage:    "Show when each top process started and how long ago",
+			Value:    &plugin.ProcessAge,
+		},
+		{
+			Path:     "cmdline",
+			Argument: "cmdline",
+			Default:  false,
+			Usage:    "Show the full command line of each top and watched process in place of its name",
+			Value:    &plugin.Cmdline,
+		},
+		{
+			Path:     "cmdline-length",
+			Argument: "cmdline-length",
+			Default:  80,
+			Usage:    "Number of characters command lines of --cmdline are truncated to in the output, 0 to show them whole",
+			Value:    &plugin.CmdlineLength,
+		},
+		{
+			Path:     "redact-args",
+			Argument: "redact-args",
+			Default:  false,
+			Usage:    "Mask passwords, tokens, keys and URL credentials in the command lines of --cmdline",
+			Value:    &plugin.RedactArgs,
+		},
+		{
+			Path:     "redact-pattern",
+			Argument: "redact-pattern",
+			Default:  []string{},
+			Usage:    "Regular expression to mask in the command lines of --cmdline, only its first group if it has one (repeatable)",
+			Value:    &plugin.RedactPattern,
+		},
+		{
+			Path:     "watch-name",
+			Argument: "watch-name",
+			Default:  []string{},
+			Usage:    "Name of processes to track between runs in --state-file, emitting how many times they restarted (repeatable)",
+			Value:    &plugin.WatchName,
+		},
+		{
+			Path:     "restart-warning",
+			Argument: "restart-warning",
+			Default:  false,
+			Usage:    "Return WARNING when a process of --watch-name restarted since the previous run",
+			Value:    &plugin.RestartWarning,
+		},
+		{
+			Path:     "pid",
+			Argument: "pid",
+			Default:  []string{},
+			Usage:    "PID of a process to always report with its own CPU and memory metrics, whether or not it is a top process (repeatable)",
+			Value:    &plugin.PID,
+		},
+		{
+			Path:     "pid-file",
+			Argument: "pid-file",
+			Default:  []string{},
+			Usage:    "Pidfile of a daemon to always report like --pid, read on every run (repeatable)",
+			Value:    &plugin.PIDFile,
+		},
+		{
+			Path:     "pid-critical",
+			Argument: "pid-critical",
+			Default:  float64(0),
+			Usage:    "Critical threshold for the CPU usage of a process of --pid or --pid-file as a percentage of one core, 0 to disable",
+			Value:    &plugin.PIDCritical,
+		},
+		{
+			Path:     "pid-warning",
+			Argument: "pid-warning",
+			Default:  float64(0),
+			Usage:    "Warning threshold for the CPU usage of a process of --pid or --pid-file as a percentage of one core, 0 to disable",
+			Value:    &plugin.PIDWarning,
+		},
+		{
+			Path:     "unit",
+			Argument: "unit",
+			Default:  []string{},
+			Usage:    "Systemd unit (e.g. nginx.service) to report the CPU usage of alongside the host, from the processes of its cgroup (repeatable, Linux only)",
+			Value:    &plugin.Unit,
+		},
+		{
+			Path:     "unit-critical",
+			Argument: "unit-critical",
+			Default:  float64(0),
+			Usage:    "Critical threshold for the CPU usage of a unit of --unit, --unit-rollup, --service or --launchd-label as a percentage of one core, 0 to disable",
+			Value:    &plugin.UnitCritical,
+		},
+		{
+			Path:     "unit-warning",
+			Argument: "unit-warning",
+			Default:  float64(0),
+			Usage:    "Warning threshold for the CPU usage of a unit of --unit, --unit-rollup, --service or --launchd-label as a percentage of one core, 0 to disable",
+			Value:    &plugin.UnitWarning,
+		},
+		{
+			Path:     "unit-rollup",
+			Argument: "unit-rollup",
+			Default:  false,
+			Usage:    "Group every process by the systemd unit of its cgroup, emitting unit_<name>_cpu metrics for all of them and listing the busiest units instead of the top processes (Linux only)",
+			Value:    &plugin.UnitRollup,
+		},
+		{
+			Path:     "service",
+			Argument: "service",
+			Default:  []string{},
+			Usage:    "Windows service to report the CPU usage of alongside the host, from its process and descendants (repeatable, Windows only)",
+			Value:    &plugin.Service,
+		},
+		{
+			Path:     "launchd-label",
+			Argument: "launchd-label",
+			Default:  []string{},
+			Usage:    "Label of a launchd job (e.g. com.openssh.sshd) to report the CPU usage of alongside the host, from its process and descendants (repeatable, macOS only)",
+			Value:    &plugin.LaunchdLabel,
+		},
+		{
+			Path:     "baseline-critical",
+			Argument: "baseline-critical",
+			Default:  float64(0),
+			Usage:    "Critical threshold for how many standard deviations CPU usage is above the baseline learned for the hour of day, 0 to disable",
+			Value:    &plugin.BaselineCritical,
+		},
+		{
+			Path:     "baseline-warning",
+			Argument: "baseline-warning",
+			Default:  float64(0),
+			Usage:    "Warning threshold for how many standard deviations CPU usage is above the baseline learned for the hour of day, 0 to disable",
+			Value:    &plugin.BaselineWarning,
+		},
+		{
+			Path:     "baseline-min-samples",
+			Argument: "baseline-min-samples",
+			Default:  30,
+			Usage:    "Number of samples to learn for an hour of day before alerting on its baseline",
+			Value:    &plugin.BaselineSamples,
+		},
+		{
+			Path:     "ewma-alpha",
+			Argument: "ewma-alpha",
+			Default:  float64(0),
+			Usage:    "Weight of each run in an exponentially weighted moving average of CPU usage kept in --state-file, between 0 and 1, 0 to disable",
+			Value:    &plugin.EWMAAlpha,
+		},
+		{
+			Path:     "ewma-thresholds",
+			Argument: "ewma-thresholds",
+			Default:  false,
+			Usage:    "Apply --warning and --critical to the moving average of --ewma-alpha instead of the CPU usage of the run",
+			Value:    &plugin.EWMAThresholds,
+		},
 		{
 			Path:      "sample-interval",
 			Argument:  "sample-interval",
 			Shorthand: "s",
-			Default:   2,
-			Usage:     "Length of sample interval in seconds",
+			Default:   "2s",
+			Usage:     "Length of sample interval as a duration (e.g. 500ms, 1.5s, 2m); a bare integer is taken as seconds",
 			Value:     &plugin.Interval,
 		},
+		{
+			Path:      "samples",
+			Argument:  "samples",
+			Shorthand: "n",
+			Default:   1,
+			Usage:     "Number of sub-samples to take across the sample interval",
+			Value:     &plugin.Samples,
+		},
+		{
+			Path:     "sub-interval",
+			Argument: "sub-interval",
+			Default:  "",
+			Usage:    "Take a sub-sample this often across the sample interval (e.g. 250ms) instead of a number of them given by --samples",
+			Value:    &plugin.SubInterval,
+		},
+		{
+			Path:     "spike-threshold",
+			Argument: "spike-threshold",
+			Default:  float64(0),
+			Usage:    "Count the sub-samples with CPU usage over this percentage as spikes, reported as cpu_spikes, 0 to disable",
+			Value:    &plugin.SpikeThreshold,
+		},
+		{
+			Path:     "history-file",
+			Argument: "history-file",
+			Default:  "",
+			Usage:    "Append each result and its top processes to this JSON Lines file, read by the recommend subcommand",
+			Value:    &plugin.HistoryFile,
+		},
+		{
+			Path:     "debug-pprof-listen",
+			Argument: "debug-pprof-listen",
+			Default:  "",
+			Usage:    "Serve the Go runtime profiles of the plugin itself under /debug/pprof/ on this address while it runs, loopback only unless --debug-pprof-token is set (e.g. 127.0.0.1:6060)",
+			Value:    &plugin.DebugPprofListen,
+		},
+		{
+			Path:     "debug-pprof-token",
+			Argument: "debug-pprof-token",
+			Default:  "",
+			Usage:    "Bearer token requests to /debug/pprof/ must carry in their Authorization header",
+			Value:    &plugin.DebugPprofToken,
+		},
+		{
+			Path:     "breach-count",
+			Argument: "breach-count",
+			Default:  1,
+			Usage:    "Number of consecutive runs over a threshold before returning WARNING/CRITICAL",
+			Value:    &plugin.BreachCount,
+		},
+		{
+			Path:     "state-file",
+			Argument: "state-file",
+			Default:  defaultStateFile(),
+			Usage:    "Path of the file used to persist state between runs",
+			Value:    &plugin.StateFile,
+		},
+		{
+			Path:     "history-db",
+			Argument: "history-db",
+			Default:  "",
+			Usage:    "Append every sample, with its top processes, to this SQLite database for later inspection",
+			Value:    &plugin.HistoryDB,
+		},
+		{
+			Path:     "history-retention",
+			Argument: "history-retention",
+			Default:  "168h",
+			Usage:    "Delete samples older than this from --history-db, 0 to keep everything",
+			Value:    &plugin.HistoryRetention,
+		},
+		{
+			Path:     "suppress",
+			Argument: "suppress",
+			Default:  []string{},
+			Usage:    "Exclude processes matching a name pattern from alerting for a TTL, as pattern=duration or pattern=RFC3339 expiry (repeatable)",
+			Value:    &plugin.Suppress,
+		},
+		{
+			Path:     "suppress-file",
+			Argument: "suppress-file",
+			Default:  "",
+			Usage:    "File of pattern=duration suppressions and suppress windows, one per line, re-read on every run",
+			Value:    &plugin.SuppressFile,
+		},
+		{
+			Path:     "suppress-window",
+			Argument: "suppress-window",
+			Default:  []string{},
+			Usage:    "Force OK while still emitting metrics during a window, as RFC3339/RFC3339 once or [Mon ]HH:MM-HH:MM daily or weekly in local time (repeatable)",
+			Value:    &plugin.SuppressWindow,
+		},
+		{
+			Path:     "rank-by",
+			Argument: "rank-by",
+			Default:  rankByCPU,
+			Usage:    "Rank the process list by CPU usage (cpu) or by how much their CPU share grew since the previous run (growth)",
+			Value:    &plugin.RankBy,
+		},
+		{
+			Path:     "docker-socket",
+			Argument: "docker-socket",
+			Default:  "",
+			Usage:    "Annotate top processes with their container name and image from the Docker API on this socket (e.g. /var/run/docker.sock, Linux only)",
+			Value:    &plugin.DockerSocket,
+		},
+		{
+			Path:     "docker-rollup",
+			Argument: "docker-rollup",
+			Default:  false,
+			Usage:    "Emit the CPU usage of every container as container_cpu_<name> metrics, requires --docker-socket or --cri-socket",
+			Value:    &plugin.DockerRollup,
+		},
+		{
+			Path:     "cri-socket",
+			Argument: "cri-socket",
+			Default:  "",
+			Usage:    "Annotate top processes with their container and pod from the CRI runtime service on this socket (e.g. /run/containerd/containerd.sock, Linux only)",
+			Value:    &plugin.CRISocket,
+		},
+		{
+			Path:     "windows-backend",
+			Argument: "windows-backend",
+			Default:  windowsBackendNative,
+			Usage:    "List processes on Windows through the native process APIs (native) or through WMI (wmi), for locked-down hosts",
+			Value:    &plugin.WindowsBackend,
+		},
+		{
+			Path:     "ps-command",
+			Argument: "ps-command",
+			Default:  "",
+			Usage:    "Command to list processes with instead of the platform backend, split on whitespace and run without a shell (e.g. \"ps -eo pid=,time=,etime=,comm=\")",
+			Value:    &plugin.PSCommand,
+		},
+		{
+			Path:     "ps-format",
+			Argument: "ps-format",
+			Default:  "pid,time,etime,comm",
+			Usage:    "Columns of the --ps-command output, from pid, time, etime, lstart and comm, with comm last",
+			Value:    &plugin.PSFormat,
+		},
+		{
+			Path:     "events-api-url",
+			Argument: "events-api-url",
+			Default:  "",
+			Usage:    "Also submit the result as an event to this Sensu agent events API URL (e.g. http://127.0.0.1:3031/events), for runs from cron or systemd timers",
+			Value:    &plugin.EventsAPIURL,
+		},
+		{
+			Path:     "events-check-name",
+			Argument: "events-check-name",
+			Default:  "cpu-process-profiler",
+			Usage:    "Check name of the events submitted to --events-api-url",
+			Value:    &plugin.EventsCheck,
+		},
+		{
+			Path:     "events-handlers",
+			Argument: "events-handlers",
+			Default:  []string{},
+			Usage:    "Handlers of the events submitted to --events-api-url",
+			Value:    &plugin.EventsHandlers,
+		},
+		{
+			Path:     "events-annotations",
+			Argument: "events-annotations",
+			Default:  false,
+			Usage:    "Add the top processes and CPU breakdown as JSON annotations of the event submitted to --events-api-url",
+			Value:    &plugin.EventsAnnotate,
+		},
+		{
+			Path:     "process-events",
+			Argument: "process-events",
+			Default:  false,
+			Usage:    "Also submit one event per process name to --events-api-url, against a <host>-<name> proxy entity, when its processes breach --process-warning or --process-critical",
+			Value:    &plugin.ProcessEvents,
+		},
+		{
+			Path:     "process-warning",
+			Argument: "process-warning",
+			Default:  float64(0),
+			Usage:    "Warning threshold for the CPU usage of the processes sharing a name as a percentage of one core, 0 to disable",
+			Value:    &plugin.ProcessWarning,
+		},
+		{
+			Path:     "process-critical",
+			Argument: "process-critical",
+			Default:  float64(0),
+			Usage:    "Critical threshold for the CPU usage of the processes sharing a name as a percentage of one core, 0 to disable",
+			Value:    &plugin.ProcessCritical,
+		},
+		{
+			Path:     "process-rule",
+			Argument: "process-rule",
+			Default:  []string{},
+			Usage:    "Thresholds for the processes whose name matches a pattern, in place of --process-warning and --process-critical, as pattern=warning:critical (repeatable, first match wins)",
+			Value:    &plugin.ProcessRules,
+		},
+		{
+			Argument: "config",
+			Default:  "",
+			Usage:    "YAML, TOML or JSON file of option values under their flag names, overridden by the flags given",
+			Value:    &plugin.ConfigFile,
+		},
+		{
+			Path:     "timeout",
+			Argument: "timeout",
+			Default:  "0s",
+			Usage:    "Return UNKNOWN with the partial result collected so far when the check has not finished after this long, 0 to disable",
+			Value:    &plugin.Timeout,
+		},
+		{
+			Path:     "exec-timeout",
+			Argument: "exec-timeout",
+			Default:  "10s",
+			Usage:    "Give up on an external command listing processes, such as ps, after this long, 0 to wait indefinitely",
+			Value:    &plugin.ExecTimeout,
+		},
+		{
+			Path:     "target-pid",
+			Argument: "target-pid",
+			Default:  0,
+			Usage:    "Profile only this process and its descendants, with per-thread CPU, context switches and cgroup throttling, instead of the whole host",
+			Value:    &plugin.TargetPID,
+		},
+		{
+			Path:     "target-unit",
+			Argument: "target-unit",
+			Default:  "",
+			Usage:    "Profile only the processes of this systemd unit (e.g. nginx.service) instead of the whole host (Linux only)",
+			Value:    &plugin.TargetUnit,
+		},
+		{
+			Path:     "target-critical",
+			Argument: "target-critical",
+			Default:  float64(0),
+			Usage:    "Critical threshold for the CPU usage of the target as a percentage of one core, 0 to disable",
+			Value:    &plugin.TargetCritical,
+		},
+		{
+			Path:     "target-warning",
+			Argument: "target-warning",
+			Default:  float64(0),
+			Usage:    "Warning threshold for the CPU usage of the target as a percentage of one core, 0 to disable",
+			Value:    &plugin.TargetWarning,
+		},
+		{
+			Path:     "start-jitter",
+			Argument: "start-jitter",
+			Default:  "0s",
+			Usage:    "Window over which to spread the start of sampling across hosts (e.g. 30s), each host gets a stable offset within it",
+			Value:    &plugin.StartJitter,
+		},
 	}
 )
 
+// Function to tell whether any enabled option needs the usage of every
+// logical CPU
+func (c *Config) usesPerCPU() bool {
+	return c.CoreWarning > 0 || c.CoreCritical > 0 || c.CoreImbalance || c.CoreSpreadWarning > 0 || c.CoreSpreadCritical > 0 || c.PhysicalCores
+}
+
+// Function to tell whether any enabled option needs the processes in D state
+func (c *Config) usesDState() bool {
+	return c.DState || c.DStateWarning > 0 || c.DStateCritical > 0
+}
+
+// Function to tell whether any enabled option needs the zombie processes
+func (c *Config) usesZombies() bool {
+	return c.Zombies || c.ZombieWarning > 0
+}
+
+// Function to tell whether any enabled option needs the scheduling policies
+// of the top processes
+func (c *Config) usesRTSched() bool {
+	return c.RTSched || c.RTCritical > 0
+}
+
+// Function to tell whether any enabled option needs the nice values of the
+// top processes
+func (c *Config) usesNice() bool {
+	return c.Nice || c.NiceBuckets
+}
+
+// Function to tell whether the process table shows a column, either chosen
+// with --process-columns or as one of all the columns of the CSV format
+func (c *Config) showsColumn(name string) bool {
+	columns := c.processColumns
+	if columns == nil && c.ProcessFormat == processFormatCSV {
+		columns = processColumns
+	}
+	for _, column := range columns {
+		if column.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to tell whether any enabled option needs the open file descriptors
+// of the top processes
+func (c *Config) usesFDs() bool {
+	return c.FDs || c.FDWarning > 0 || c.FDCritical > 0 || len(c.FDRules) > 0
+}
+
+// Function to tell whether any enabled option needs the throttling counters
+func (c *Config) usesThrottling() bool {
+	return c.Throttling || c.ThrottlingWarning
+}
+
+// Function to tell whether any enabled option needs the CPU temperatures
+func (c *Config) usesTemperature() bool {
+	return c.Temperature || c.TemperatureWarning > 0 || c.TemperatureCritical > 0
+}
+
+// Function to tell whether any enabled option persists state between runs
+func (c *Config) usesState() bool {
+	return c.BreachCount > 1 || len(c.Suppress) > 0 || c.SuppressFile != "" || c.RankBy == rankByGrowth || c.ProcessEvents || c.BaselineWarning > 0 || c.BaselineCritical > 0 || c.EWMAAlpha > 0 || len(c.WatchName) > 0
+}
+
 func main() {
-	check := sensu.NewGoCheck(&plugin.PluginConfig, options, checkArgs, executeCheck, false)
+	// The SDK parses os.Args itself, so strip the subcommand name before
+	// handing over
+	command, args := selectCommand(os.Args[1:])
+	os.Args = append(os.Args[:1], migrateArgs(args)...)
+	plugin.PluginConfig.Short = command.Short
+
+	opts := append(options, command.Options...)
+	if command.TakesArgs {
+		var flags []string
+		flags, commandArgs = splitArgs(os.Args[1:], opts)
+		os.Args = append(os.Args[:1], flags...)
+	}
+	if file := configFileArg(os.Args[1:]); file != "" {
+		configFileErr = loadConfigFile(file, opts)
+	}
+	opts = append(deprecatedOptions(opts), opts...)
+	check := sensu.NewGoCheck(&plugin.PluginConfig, opts, debugPprofArgs(command.Validate), withDebugPprof(command.Execute), replayStdinEvent())
 	check.Execute()
 }
 
 func checkArgs(event *types.Event) (int, error) {
+	warnDeprecatedAnnotations(event)
+	if configFileErr != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--config: %v", configFileErr)
+	}
 	if plugin.Critical == 0 {
 		return sensu.CheckStateWarning, fmt.Errorf("--critical is required")
 	}
@@ -112,69 +1322,580 @@ func checkArgs(event *types.Event) (int, error) {
 	if plugin.Warning > plugin.Critical {
 		return sensu.CheckStateWarning, fmt.Errorf("--warning cannot be greater than --critical")
 	}
-	if plugin.Interval == 0 {
-		return sensu.CheckStateWarning, fmt.Errorf("--interval is required")
+	profiles, err := parseThresholdProfiles(plugin.ThresholdProfile)
+	if err != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--threshold-profile: %v", err)
+	}
+	plugin.thresholdProfiles = profiles
+	plugin.suppressWindows = nil
+	for _, spec := range plugin.SuppressWindow {
+		w, err := parseSuppressWindow(spec)
+		if err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--suppress-window: %v", err)
+		}
+		plugin.suppressWindows = append(plugin.suppressWindows, w)
+	}
+	for _, p := range plugin.thresholdProfiles {
+		if warning, critical := p.thresholds(plugin.Warning, plugin.Critical); warning > critical {
+			return sensu.CheckStateWarning, fmt.Errorf("--threshold-profile: %s: the warning threshold cannot be greater than the critical one", p.Window)
+		}
+	}
+	if plugin.WarningCores > 0 && plugin.CriticalCores > 0 && plugin.WarningCores > plugin.CriticalCores {
+		return sensu.CheckStateWarning, fmt.Errorf("--warning-cores cannot be greater than --critical-cores")
+	}
+	plugin.loadCritical, plugin.loadWarning = nil, nil
+	if plugin.LoadCritical != "" {
+		t, err := parseLoadThreshold(plugin.LoadCritical)
+		if err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--load-critical: %v", err)
+		}
+		plugin.loadCritical = &t
+	}
+	if plugin.LoadWarning != "" {
+		t, err := parseLoadThreshold(plugin.LoadWarning)
+		if err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--load-warning: %v", err)
+		}
+		plugin.loadWarning = &t
+	}
+	if plugin.loadCritical != nil && plugin.loadWarning != nil && plugin.loadCritical.exceededBy(*plugin.loadWarning) {
+		return sensu.CheckStateWarning, fmt.Errorf("--load-warning cannot be greater than --load-critical")
+	}
+	if plugin.BurstWarning > 0 && plugin.BurstCritical > 0 && plugin.BurstWarning > plugin.BurstCritical {
+		return sensu.CheckStateWarning, fmt.Errorf("--burst-warning cannot be greater than --burst-critical")
+	}
+	plugin.lockupDuration = 0
+	if plugin.LockupWindow != "" {
+		window, err := time.ParseDuration(plugin.LockupWindow)
+		if err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--lockup-window: %v", err)
+		}
+		plugin.lockupDuration = window
+	}
+	if plugin.LockupCrit && plugin.lockupDuration <= 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--lockup-critical requires --lockup-window")
+	}
+	if plugin.StealWarning > 0 && plugin.StealCritical > 0 && plugin.StealWarning > plugin.StealCritical {
+		return sensu.CheckStateWarning, fmt.Errorf("--steal-warning cannot be greater than --steal-critical")
+	}
+	if plugin.BaselineWarning < 0 || plugin.BaselineCritical < 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--baseline-warning and --baseline-critical cannot be negative")
+	}
+	if plugin.BaselineWarning > 0 && plugin.BaselineCritical > 0 && plugin.BaselineWarning > plugin.BaselineCritical {
+		return sensu.CheckStateWarning, fmt.Errorf("--baseline-warning cannot be greater than --baseline-critical")
+	}
+	if plugin.BaselineSamples < 2 {
+		return sensu.CheckStateWarning, fmt.Errorf("--baseline-min-samples must be at least 2")
+	}
+	if (plugin.BaselineWarning > 0 || plugin.BaselineCritical > 0) && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--baseline-warning and --baseline-critical cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.EWMAAlpha < 0 || plugin.EWMAAlpha > 1 {
+		return sensu.CheckStateWarning, fmt.Errorf("--ewma-alpha must be between 0 and 1")
+	}
+	if plugin.EWMAThresholds && plugin.EWMAAlpha == 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--ewma-thresholds requires --ewma-alpha")
+	}
+	if plugin.EWMAAlpha > 0 && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--ewma-alpha cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.CoreWarning > 0 && plugin.CoreCritical > 0 && plugin.CoreWarning > plugin.CoreCritical {
+		return sensu.CheckStateWarning, fmt.Errorf("--core-warning cannot be greater than --core-critical")
+	}
+	if plugin.CoreOverallMax < 0 || plugin.CoreOverallMax > 100 {
+		return sensu.CheckStateWarning, fmt.Errorf("--core-overall-max must be between 0 and 100")
+	}
+	if plugin.CoreSpreadWarning > 0 && plugin.CoreSpreadCritical > 0 && plugin.CoreSpreadWarning > plugin.CoreSpreadCritical {
+		return sensu.CheckStateWarning, fmt.Errorf("--core-spread-warning cannot be greater than --core-spread-critical")
+	}
+	if plugin.usesPerCPU() && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--core-warning, --core-critical, --core-imbalance and --core-spread-warning/--core-spread-critical and --physical-cores cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.Frequency && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--frequency cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.TemperatureWarning > 0 && plugin.TemperatureCritical > 0 && plugin.TemperatureWarning > plugin.TemperatureCritical {
+		return sensu.CheckStateWarning, fmt.Errorf("--temperature-warning cannot be greater than --temperature-critical")
+	}
+	if plugin.usesTemperature() && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--temperature, --temperature-warning and --temperature-critical cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.RAPL && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--rapl cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.usesThrottling() && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--throttling and --throttling-warning cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.Governor && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--governor cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.Perf && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--perf cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.OffCPU && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--off-cpu cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.CaptureProfile != "" && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--capture-profile cannot be used with --target-pid or --target-unit")
+	}
+	plugin.captureDuration = 0
+	if plugin.CaptureProfile != "" {
+		d, err := time.ParseDuration(plugin.CaptureDuration)
+		if err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--capture-duration: %v", err)
+		}
+		if d <= 0 {
+			return sensu.CheckStateWarning, fmt.Errorf("--capture-duration must be positive")
+		}
+		plugin.captureDuration = d
+	}
+	if plugin.pprofPorts, err = parsePprofPorts(plugin.PprofPort); err != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--pprof-port: %v", err)
+	}
+	if len(plugin.pprofPorts) > 0 && plugin.CaptureProfile == "" {
+		return sensu.CheckStateWarning, fmt.Errorf("--pprof-port requires --capture-profile")
+	}
+	if plugin.JVMDiagnostics && plugin.CaptureProfile == "" {
+		return sensu.CheckStateWarning, fmt.Errorf("--jvm-diagnostics requires --capture-profile")
+	}
+	if plugin.DStateWarning < 0 || plugin.DStateCritical < 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--dstate-warning and --dstate-critical cannot be negative")
+	}
+	if plugin.DStateWarning > 0 && plugin.DStateCritical > 0 && plugin.DStateWarning > plugin.DStateCritical {
+		return sensu.CheckStateWarning, fmt.Errorf("--dstate-warning cannot be greater than --dstate-critical")
+	}
+	if plugin.usesDState() && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--dstate, --dstate-warning and --dstate-critical cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.ForkRateWarning > 0 && plugin.ForkRateCritical > 0 && plugin.ForkRateWarning > plugin.ForkRateCritical {
+		return sensu.CheckStateWarning, fmt.Errorf("--fork-rate-warning cannot be greater than --fork-rate-critical")
+	}
+	if (plugin.ForkRateWarning > 0 || plugin.ForkRateCritical > 0) && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--fork-rate-warning and --fork-rate-critical cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.ZombieWarning < 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--zombie-warning cannot be negative")
+	}
+	if plugin.usesZombies() && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--zombies and --zombie-warning cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.FDWarning < 0 || plugin.FDCritical < 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--fd-warning and --fd-critical cannot be negative")
+	}
+	if plugin.FDWarning > 0 && plugin.FDCritical > 0 && plugin.FDWarning > plugin.FDCritical {
+		return sensu.CheckStateWarning, fmt.Errorf("--fd-warning cannot be greater than --fd-critical")
+	}
+	if plugin.fdRules, err = parseProcessRules(plugin.FDRules); err != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--fd-rule: %v", err)
+	}
+	if plugin.usesFDs() && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--fds, --fd-warning, --fd-critical and --fd-rule cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.RestartWarning && len(plugin.WatchName) == 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--restart-warning requires --watch-name")
+	}
+	if len(plugin.WatchName) > 0 && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--watch-name cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.pids, err = parsePIDs(plugin.PID); err != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--pid: %v", err)
+	}
+	if plugin.PIDWarning > 0 && plugin.PIDCritical > 0 && plugin.PIDWarning > plugin.PIDCritical {
+		return sensu.CheckStateWarning, fmt.Errorf("--pid-warning cannot be greater than --pid-critical")
+	}
+	if (len(plugin.pids) > 0 || len(plugin.PIDFile) > 0) && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--pid and --pid-file cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.UnitWarning > 0 && plugin.UnitCritical > 0 && plugin.UnitWarning > plugin.UnitCritical {
+		return sensu.CheckStateWarning, fmt.Errorf("--unit-warning cannot be greater than --unit-critical")
+	}
+	if len(plugin.Unit) > 0 && plugin.UnitRollup {
+		return sensu.CheckStateWarning, fmt.Errorf("--unit and --unit-rollup cannot be used together")
+	}
+	if (len(plugin.Unit) > 0 || plugin.UnitRollup) && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--unit and --unit-rollup cannot be used with --target-pid or --target-unit")
+	}
+	if len(plugin.Service) > 0 && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--service cannot be used with --target-pid or --target-unit")
+	}
+	if len(plugin.LaunchdLabel) > 0 && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--launchd-label cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.OOMScore && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--oom-score cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.RTCritical < 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--rt-critical cannot be negative")
+	}
+	if plugin.usesRTSched() && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--rt-sched and --rt-critical cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.usesNice() && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--nice and --nice-buckets cannot be used with --target-pid or --target-unit")
+	}
+	if plugin.CmdlineLength < 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--cmdline-length cannot be negative")
+	}
+	if plugin.Cmdline && (plugin.TargetPID > 0 || plugin.TargetUnit != "") {
+		return sensu.CheckStateWarning, fmt.Errorf("--cmdline cannot be used with --target-pid or --target-unit")
+	}
+	if (plugin.RedactArgs || len(plugin.RedactPattern) > 0) && !plugin.Cmdline {
+		return sensu.CheckStateWarning, fmt.Errorf("--redact-args and --redact-pattern require --cmdline")
+	}
+	if plugin.redactPatterns, err = parseRedactPatterns(plugin.RedactArgs, plugin.RedactPattern); err != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--redact-pattern: %v", err)
+	}
+	if (plugin.PyroscopeURL != "" || plugin.ParcaURL != "") && plugin.CaptureProfile == "" {
+		return sensu.CheckStateWarning, fmt.Errorf("--pyroscope-url and --parca-url require --capture-profile")
+	}
+	if plugin.DockerSocket != "" && plugin.CRISocket != "" {
+		return sensu.CheckStateWarning, fmt.Errorf("--docker-socket and --cri-socket cannot be used together")
+	}
+	if plugin.DockerRollup && plugin.DockerSocket == "" && plugin.CRISocket == "" {
+		return sensu.CheckStateWarning, fmt.Errorf("--docker-rollup requires --docker-socket or --cri-socket")
+	}
+	if plugin.WindowsBackend != "" && plugin.WindowsBackend != windowsBackendNative && plugin.WindowsBackend != windowsBackendWMI {
+		return sensu.CheckStateWarning, fmt.Errorf("--windows-backend must be %s or %s", windowsBackendNative, windowsBackendWMI)
+	}
+	plugin.psCommand, plugin.psColumns = nil, nil
+	if plugin.PSCommand != "" {
+		if plugin.WindowsBackend == windowsBackendWMI {
+			return sensu.CheckStateWarning, fmt.Errorf("--ps-command and --windows-backend wmi cannot be used together")
+		}
+		columns, err := parsePSFormat(plugin.PSFormat)
+		if err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--ps-format: %v", err)
+		}
+		plugin.psCommand, plugin.psColumns = strings.Fields(plugin.PSCommand), columns
+	}
+	plugin.execTimeout = 0
+	if plugin.ExecTimeout != "" {
+		timeout, err := time.ParseDuration(plugin.ExecTimeout)
+		if err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--exec-timeout: %v", err)
+		}
+		if timeout < 0 {
+			return sensu.CheckStateWarning, fmt.Errorf("--exec-timeout cannot be negative")
+		}
+		plugin.execTimeout = timeout
+	}
+	plugin.outputTemplate = nil
+	if plugin.OutputTemplate != "" {
+		tmpl, err := loadOutputTemplate(plugin.OutputTemplate)
+		if err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--output-template: %v", err)
+		}
+		plugin.outputTemplate = tmpl
+	}
+	if plugin.MaxOutputBytes < 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--max-output-bytes cannot be negative")
+	}
+	if plugin.MaxOutputBytes > 0 && plugin.outputTemplate != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--max-output-bytes and --output-template cannot be used together")
+	}
+	switch plugin.ProcessFormat {
+	case "", processFormatText, processFormatCSV, processFormatJSONL:
+	default:
+		return sensu.CheckStateWarning, fmt.Errorf("--process-format must be %s, %s or %s", processFormatText, processFormatCSV, processFormatJSONL)
+	}
+	if plugin.MaxOutputBytes > 0 && (plugin.ProcessFormat == processFormatCSV || plugin.ProcessFormat == processFormatJSONL) {
+		return sensu.CheckStateWarning, fmt.Errorf("--max-output-bytes cannot be used with --process-format %s", plugin.ProcessFormat)
+	}
+	if plugin.processColumns, err = parseProcessColumns(plugin.ProcessColumns); err != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--process-columns: %v", err)
+	}
+	for _, column := range plugin.processColumns {
+		if option := columnOption(column.Name); option != "" {
+			return sensu.CheckStateWarning, fmt.Errorf("--process-columns %s requires %s", column.Name, option)
+		}
+	}
+	if plugin.processColumns != nil && plugin.ProcessFormat == processFormatJSONL {
+		return sensu.CheckStateWarning, fmt.Errorf("--process-columns cannot be used with --process-format jsonl")
+	}
+	plugin.colorOutput = plugin.Color && isTerminal(os.Stdout)
+	switch plugin.MetricFormat {
+	case "", metricFormatNagios, metricFormatGraphite, metricFormatInflux, metricFormatOpenTSDB:
+	default:
+		return sensu.CheckStateWarning, fmt.Errorf("--metric-format must be %s, %s, %s or %s", metricFormatNagios, metricFormatGraphite, metricFormatInflux, metricFormatOpenTSDB)
+	}
+	switch plugin.MetricPrecision {
+	case "", precisionSeconds:
+	case precisionMilliseconds, precisionMicroseconds, precisionNanoseconds:
+		if plugin.MetricFormat == metricFormatGraphite {
+			return sensu.CheckStateWarning, fmt.Errorf("--metric-precision must be %s with %s", precisionSeconds, metricFormatGraphite)
+		}
+		if plugin.MetricFormat == metricFormatOpenTSDB && plugin.MetricPrecision != precisionMilliseconds {
+			return sensu.CheckStateWarning, fmt.Errorf("--metric-precision must be %s or %s with %s", precisionSeconds, precisionMilliseconds, metricFormatOpenTSDB)
+		}
+	default:
+		return sensu.CheckStateWarning, fmt.Errorf("--metric-precision must be %s, %s, %s or %s", precisionSeconds, precisionMilliseconds, precisionMicroseconds, precisionNanoseconds)
+	}
+	if plugin.MetricScheme != "" && plugin.MetricScheme != metricSchemeHost && plugin.MetricScheme != metricSchemeFlat {
+		return sensu.CheckStateWarning, fmt.Errorf("--metric-scheme must be %s or %s", metricSchemeHost, metricSchemeFlat)
+	}
+	if strings.HasPrefix(plugin.MetricPrefix, ".") || strings.HasSuffix(plugin.MetricPrefix, ".") || strings.ContainsAny(plugin.MetricPrefix, " \t") {
+		return sensu.CheckStateWarning, fmt.Errorf("--metric-prefix cannot start or end with a dot or contain whitespace")
+	}
+	plugin.hostname = plugin.Hostname
+	if plugin.hostname == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--hostname is required when the host name cannot be found: %v", err)
+		}
+		plugin.hostname = host
+	}
+	tags, err := parseMetricTags(plugin.MetricTags)
+	if err != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--metric-tag: %v", err)
+	}
+	var labelTags []MetricTag
+	if len(plugin.MetricTagLabels) > 0 {
+		if event == nil || event.Entity == nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--metric-tag-label requires the event on stdin, enable stdin in the check definition")
+		}
+		labelTags = entityLabelTags(event.Entity, plugin.MetricTagLabels)
+	}
+	plugin.metricTags = mergeMetricTags(labelTags, tags)
+	if plugin.TargetPID < 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--target-pid must be a process ID")
+	}
+	if plugin.TargetPID > 0 && plugin.TargetUnit != "" {
+		return sensu.CheckStateWarning, fmt.Errorf("--target-pid and --target-unit cannot be used together")
+	}
+	if plugin.TargetWarning > 0 && plugin.TargetCritical > 0 && plugin.TargetWarning > plugin.TargetCritical {
+		return sensu.CheckStateWarning, fmt.Errorf("--target-warning cannot be greater than --target-critical")
+	}
+	if plugin.Interval == "" {
+		return sensu.CheckStateWarning, fmt.Errorf("--sample-interval is required")
+	}
+	interval, err := parseInterval(plugin.Interval)
+	if err != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--sample-interval: %v", err)
+	}
+	plugin.intervalDuration = interval
+	if plugin.Samples < 1 {
+		return sensu.CheckStateWarning, fmt.Errorf("--samples must be at least 1")
+	}
+	plugin.sampleCount = plugin.Samples
+	if plugin.SubInterval != "" {
+		sub, err := time.ParseDuration(plugin.SubInterval)
+		if err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--sub-interval: %v", err)
+		}
+		if sub <= 0 || sub > interval {
+			return sensu.CheckStateWarning, fmt.Errorf("--sub-interval must be positive and no longer than --sample-interval")
+		}
+		if plugin.Samples > 1 {
+			return sensu.CheckStateWarning, fmt.Errorf("--sub-interval and --samples cannot be used together")
+		}
+		plugin.sampleCount = int(math.Ceil(float64(interval) / float64(sub)))
+	}
+	if plugin.intervalDuration/time.Duration(plugin.sampleCount) < clockTick {
+		return sensu.CheckStateWarning, fmt.Errorf("--samples and --sub-interval cannot split the interval into sub-samples shorter than %v", clockTick)
+	}
+	if plugin.SpikeThreshold < 0 || plugin.SpikeThreshold > 100 {
+		return sensu.CheckStateWarning, fmt.Errorf("--spike-threshold must be between 0 and 100")
+	}
+	if plugin.SpikeThreshold > 0 && plugin.sampleCount < 2 {
+		return sensu.CheckStateWarning, fmt.Errorf("--spike-threshold requires --samples or --sub-interval")
+	}
+	if plugin.BreachCount < 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--breach-count cannot be negative")
+	}
+	if plugin.RankBy != "" && plugin.RankBy != rankByCPU && plugin.RankBy != rankByGrowth {
+		return sensu.CheckStateWarning, fmt.Errorf("--rank-by must be %s or %s", rankByCPU, rankByGrowth)
+	}
+	if plugin.usesState() && plugin.StateFile == "" {
+		return sensu.CheckStateWarning, fmt.Errorf("--state-file is required with --breach-count, --suppress, --suppress-file, --rank-by growth, --process-events, --baseline-warning/--baseline-critical and --ewma-alpha")
+	}
+	plugin.historyRetention = 0
+	if plugin.HistoryRetention != "" {
+		retention, err := time.ParseDuration(plugin.HistoryRetention)
+		if err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--history-retention: %v", err)
+		}
+		if retention < 0 {
+			return sensu.CheckStateWarning, fmt.Errorf("--history-retention cannot be negative")
+		}
+		plugin.historyRetention = retention
+	}
+	if plugin.StartJitter != "" {
+		jitter, err := time.ParseDuration(plugin.StartJitter)
+		if err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--start-jitter: %v", err)
+		}
+		if jitter < 0 {
+			return sensu.CheckStateWarning, fmt.Errorf("--start-jitter cannot be negative")
+		}
+		plugin.jitterDuration = jitter
+	}
+	if err := validatePSIResources(plugin.PSI); err != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--psi: %v", err)
+	}
+	if (plugin.PSICritical > 0 || plugin.PSIWarning > 0) && len(plugin.PSI) == 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--psi-warning and --psi-critical require --psi")
+	}
+	if plugin.PSIWarning > 0 && plugin.PSICritical > 0 && plugin.PSIWarning > plugin.PSICritical {
+		return sensu.CheckStateWarning, fmt.Errorf("--psi-warning cannot be greater than --psi-critical")
+	}
+	switch plugin.CgroupMode {
+	case "", cgroupModeHost:
+		plugin.useCgroup = false
+	case cgroupModeCgroup:
+		plugin.useCgroup = true
+	case cgroupModeAuto:
+		cg, err := readCgroupCPU()
+		plugin.useCgroup = err == nil && cg.Quota
+	default:
+		return sensu.CheckStateWarning, fmt.Errorf("--cgroup-mode must be %s, %s or %s", cgroupModeHost, cgroupModeAuto, cgroupModeCgroup)
+	}
+	switch plugin.OnUnsupported {
+	case "", unsupportedFail, unsupportedDisable:
+	default:
+		return sensu.CheckStateWarning, fmt.Errorf("--on-unsupported must be %s or %s", unsupportedFail, unsupportedDisable)
+	}
+	if plugin.EventsAPIURL != "" {
+		u, err := url.Parse(plugin.EventsAPIURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return sensu.CheckStateWarning, fmt.Errorf("--events-api-url must be an http or https URL")
+		}
+		if plugin.EventsCheck == "" {
+			return sensu.CheckStateWarning, fmt.Errorf("--events-check-name is required with --events-api-url")
+		}
+	}
+	if plugin.processRules, err = parseProcessRules(plugin.ProcessRules); err != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--process-rule: %v", err)
+	}
+	if plugin.EventsAnnotate && plugin.EventsAPIURL == "" {
+		return sensu.CheckStateWarning, fmt.Errorf("--events-annotations requires --events-api-url")
+	}
+	if plugin.ProcessWarning > 0 && plugin.ProcessCritical > 0 && plugin.ProcessWarning > plugin.ProcessCritical {
+		return sensu.CheckStateWarning, fmt.Errorf("--process-warning cannot be greater than --process-critical")
+	}
+	if plugin.ProcessEvents {
+		if plugin.EventsAPIURL == "" {
+			return sensu.CheckStateWarning, fmt.Errorf("--process-events requires --events-api-url")
+		}
+		if plugin.ProcessWarning <= 0 && plugin.ProcessCritical <= 0 && len(plugin.ProcessRules) == 0 {
+			return sensu.CheckStateWarning, fmt.Errorf("--process-events requires --process-warning, --process-critical or --process-rule")
+		}
+		if plugin.TargetPID > 0 || plugin.TargetUnit != "" {
+			return sensu.CheckStateWarning, fmt.Errorf("--process-events cannot be used with --target-pid or --target-unit")
+		}
+	}
+	plugin.timeout = 0
+	if plugin.Timeout != "" {
+		timeout, err := time.ParseDuration(plugin.Timeout)
+		if err != nil {
+			return sensu.CheckStateWarning, fmt.Errorf("--timeout: %v", err)
+		}
+		if timeout < 0 {
+			return sensu.CheckStateWarning, fmt.Errorf("--timeout cannot be negative")
+		}
+		if timeout > 0 && timeout <= plugin.intervalDuration+plugin.jitterDuration {
+			return sensu.CheckStateWarning, fmt.Errorf("--timeout must be longer than --sample-interval plus --start-jitter")
+		}
+		plugin.timeout = timeout
+	}
+	if plugin.disabled, err = probeFeatures(features, plugin.OnUnsupported); err != nil {
+		return sensu.CheckStateWarning, err
 	}
 	return sensu.CheckStateOK, nil
 }
 
 func executeCheck(event *types.Event) (int, error) {
-	start, err := cpu.Times(false)
+	result, err := runCheckWithin(plugin.timeout)
 	if err != nil {
-		return sensu.CheckStateCritical, fmt.Errorf("Error obtaining CPU timings: %v", err)
+		return sensu.CheckStateCritical, err
 	}
 
-	startTotal := start[0].User + start[0].System + start[0].Idle + start[0].Nice + start[0].Iowait + start[0].Irq + start[0].Softirq + start[0].Steal + start[0].Guest + start[0].GuestNice
+	// The JSON block cannot be truncated, so it comes out of the room left
+	// for the process table
+	var block string
+	if plugin.OutputJSON {
+		if block, err = formatJSONBlock(result); err != nil {
+			return sensu.CheckStateCritical, fmt.Errorf("Error encoding JSON output: %v", err)
+		}
+	}
+	var out string
+	if plugin.outputTemplate != nil {
+		if out, err = formatTemplate(plugin.outputTemplate, result); err != nil {
+			return sensu.CheckStateCritical, fmt.Errorf("Error executing output template: %v", err)
+		}
+	} else if plugin.MaxOutputBytes > 0 {
+		out = formatResultWithin(result, max(plugin.MaxOutputBytes-len(block), 1))
+	} else {
+		out = formatResult(result)
+	}
+	out += block
+	fmt.Print(out)
 
-	duration, err := time.ParseDuration(fmt.Sprintf("%ds", plugin.Interval))
-	if err != nil {
-		return sensu.CheckStateCritical, fmt.Errorf("Error parsing duration: %v", err)
+	if err := saveHistory(result); err != nil {
+		return sensu.CheckStateCritical, err
+	}
+	if plugin.EventsAPIURL != "" {
+		event := sensuEvent(result, out, plugin.EventsCheck, plugin.EventsHandlers, plugin.metricTags)
+		if plugin.EventsAnnotate {
+			if event.Check.Metadata.Annotations, err = resultAnnotations(result); err != nil {
+				return sensu.CheckStateCritical, fmt.Errorf("Error encoding event annotations: %v", err)
+			}
+		}
+		if err := postEvent(plugin.EventsAPIURL, event); err != nil {
+			return sensu.CheckStateCritical, fmt.Errorf("Error submitting event: %v", err)
+		}
+		for _, group := range result.Groups {
+			event := processGroupEvent(group, plugin.hostname, result.Timestamp, plugin.EventsCheck, plugin.EventsHandlers)
+			if err := postEvent(plugin.EventsAPIURL, event); err != nil {
+				return sensu.CheckStateCritical, fmt.Errorf("Error submitting event for %s: %v", group.Name, err)
+			}
+		}
 	}
+	return result.Status, nil
+}
 
-	time.Sleep(duration)
+func executeMetrics(event *types.Event) (int, error) {
+	result, err := runCheckWithin(plugin.timeout)
+	if err != nil {
+		return sensu.CheckStateCritical, err
+	}
+	result.Status = reportStatus(result)
+	fmt.Print(formatMetricsOutput(result))
+	if err := saveHistory(result); err != nil {
+		return sensu.CheckStateCritical, err
+	}
+	return result.Status, nil
+}
 
-	end, err := cpu.Times(false)
+func executeTop(event *types.Event) (int, error) {
+	result, err := runCheckWithin(plugin.timeout)
 	if err != nil {
-		return sensu.CheckStateCritical, fmt.Errorf("Error obtaining CPU timings: %v", err)
-	}
-
-	endTotal := end[0].User + end[0].System + end[0].Idle + end[0].Nice + end[0].Iowait + end[0].Irq + end[0].Softirq + end[0].Steal + end[0].Guest + end[0].GuestNice
-
-	diff := endTotal - startTotal
-	idlePct := ((end[0].Idle - start[0].Idle) / diff) * 100
-	usedPct := 100 - idlePct
-
-	userPct := ((end[0].User - start[0].User) / diff) * 100
-	sysPct := ((end[0].System - start[0].System) / diff) * 100
-	nicePct := ((end[0].Nice - start[0].Nice) / diff) * 100
-	iowaitPct := ((end[0].Iowait - start[0].Iowait) / diff) * 100
-	irqPct := ((end[0].Irq - start[0].Irq) / diff) * 100
-	softirqPct := ((end[0].Softirq - start[0].Softirq) / diff) * 100
-	stealPct := ((end[0].Steal - start[0].Steal) / diff) * 100
-	guestPct := ((end[0].Guest - start[0].Guest) / diff) * 100
-	guestnicePct := ((end[0].GuestNice - start[0].GuestNice) / diff) * 100
-	perfData := fmt.Sprintf("cpu_idle=%.2f, cpu_system=%.2f, cpu_user=%.2f, cpu_nice=%.2f, cpu_iowait=%.2f, cpu_irq=%.2f, cpu_softirq=%.2f, cpu_steal=%.2f, cpu_guest=%.2f, cpu_guestnice=%.2f", idlePct, sysPct, userPct, nicePct, iowaitPct, irqPct, softirqPct, stealPct, guestPct, guestnicePct)
-	
-	// Get top processes irrespective of the CPU state
-    topProcesses, err := getTopCPUProcesses()
-    if err != nil {
-        return sensu.CheckStateCritical, fmt.Errorf("Error obtaining top CPU processes: %v", err)
-    }
-
-    processInfo := "\nTop CPU processes:\n"
-    for _, p := range topProcesses {
-        processInfo += fmt.Sprintf("PID %d (%s): %.2f%%\n", p.PID, p.Name, p.CPU)
-    }
-
-    if usedPct > plugin.Critical {
-        fmt.Printf("%s Critical: %.2f%% CPU usage | %s\n%s\n", plugin.PluginConfig.Name, usedPct, perfData, processInfo)
-        return sensu.CheckStateCritical, nil
-    } else if usedPct > plugin.Warning {
-        fmt.Printf("%s Warning: %.2f%% CPU usage | %s\n%s\n", plugin.PluginConfig.Name, usedPct, perfData, processInfo)
-        return sensu.CheckStateWarning, nil
-    }
-
-    // Now also includes process list for OK responses
-    fmt.Printf("%s OK: %.2f%% CPU usage | %s\n%s\n", plugin.PluginConfig.Name, usedPct, perfData, processInfo)
-    return sensu.CheckStateOK, nil
+		return sensu.CheckStateCritical, err
+	}
+	if result.Status == sensu.CheckStateUnknown {
+		fmt.Printf("%s %s: %s\n\n", plugin.PluginConfig.Name, stateLabel(result.Status), result.Summary)
+	}
+	fmt.Print(formatProcessTable(result))
+	return reportStatus(result), nil
+}
+
+// Function to measure and redraw the screen until interrupted. Start jitter
+// only makes sense for fleets of scheduled runs, so it is not applied.
+func executeWatch(event *types.Event) (int, error) {
+	plugin.jitterDuration = 0
+	for {
+		result, err := runCheckWithin(plugin.timeout)
+		if err != nil {
+			return sensu.CheckStateCritical, err
+		}
+		fmt.Print(formatWatchScreen(result))
+	}
+}
+
+// Function to get the status of a subcommand that only reports and never
+// alerts: OK, unless the run was cut short by --timeout
+func reportStatus(result *Result) int {
+	if result.Status == sensu.CheckStateUnknown {
+		return sensu.CheckStateUnknown
+	}
+	return sensu.CheckStateOK
 }
@@ -2,9 +2,11 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"github.com/sensu-community/sensu-plugin-sdk/sensu"
 	corev2 "github.com/sensu/sensu-go/api/core/v2"
+	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,8 +32,337 @@ func TestCheckArgs(t *testing.T) {
 	assert.Equal(sensu.CheckStateWarning, i)
 	assert.Error(e)
 	plugin.Critical = float64(90)
-	plugin.Interval = 2
+	plugin.WarningCores = float64(8)
+	plugin.CriticalCores = float64(6)
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.CriticalCores = float64(12)
+	plugin.LoadWarning = "8,6,4"
+	plugin.LoadCritical = "6"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.LoadCritical = "10,8,6"
+	plugin.StealWarning = float64(20)
+	plugin.StealCritical = float64(10)
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.StealCritical = float64(30)
+	plugin.Interval = "2"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.Samples = 1000
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.Samples = 1
+	plugin.CgroupMode = "container"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.CgroupMode = cgroupModeHost
+	plugin.RankBy = "memory"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.RankBy = rankByCPU
+	plugin.TargetPID = 42
+	plugin.TargetUnit = "nginx.service"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.TargetUnit = ""
+	plugin.TargetWarning = float64(200)
+	plugin.TargetCritical = float64(150)
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.TargetCritical = float64(300)
+	plugin.DockerRollup = true
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.DockerRollup = false
+	plugin.DockerSocket = "/var/run/docker.sock"
+	plugin.CRISocket = "/run/containerd/containerd.sock"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.DockerSocket, plugin.CRISocket = "", ""
+	plugin.OutputTemplate = "/nonexistent/alert.tmpl"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.OutputTemplate = ""
+	plugin.MaxOutputBytes, plugin.ProcessFormat = 4096, processFormatCSV
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.ProcessFormat = processFormatJSONL
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.MaxOutputBytes, plugin.ProcessFormat = 0, ""
+	plugin.WindowsBackend = "pdh"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.WindowsBackend = windowsBackendNative
+	plugin.PSCommand = "ps -eo pid=,comm=,time="
+	plugin.PSFormat = "pid,comm,time"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.PSCommand, plugin.PSFormat = "", ""
+	plugin.ExecTimeout = "-1s"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.ExecTimeout = "10s"
+	plugin.Timeout = "1s"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.Timeout = "0s"
+	plugin.EventsAPIURL = "127.0.0.1:3031/events"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.EventsAPIURL = ""
+	plugin.EventsAnnotate = true
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.EventsAnnotate = false
+	plugin.ProcessEvents = true
+	plugin.ProcessWarning = float64(100)
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.ProcessEvents = false
+	plugin.ProcessWarning = float64(0)
+	plugin.MetricFormat = "graphite"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.MetricFormat = metricFormatOpenTSDB
+	plugin.MetricPrecision = precisionNanoseconds
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.MetricFormat = metricFormatNagios
+	plugin.MetricPrecision = precisionSeconds
+	plugin.MetricScheme = "tree"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.MetricScheme = metricSchemeHost
+	plugin.MetricPrefix = "servers."
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.MetricPrefix = ""
+	plugin.MetricTags = []string{"team"}
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.MetricTags = []string{}
+	plugin.HistoryRetention = "-24h"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.HistoryRetention = "168h"
+	plugin.BaselineWarning = float64(4)
+	plugin.BaselineCritical = float64(3)
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.BaselineWarning, plugin.BaselineCritical = 0, 0
+	plugin.BaselineSamples = 1
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.BaselineSamples = 30
+	plugin.ThresholdProfile = []string{"02:00-05:00=warn:95"}
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.ThresholdProfile = []string{"02:00-05:00=warn:95,crit:99"}
+	plugin.SuppressWindow = []string{"nightly"}
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.SuppressWindow = []string{"Sun 02:00-04:00"}
+	plugin.EWMAThresholds = true
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.EWMAAlpha = 1.5
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.EWMAAlpha, plugin.EWMAThresholds = 0, false
+	plugin.SubInterval = "250ms"
+	plugin.Samples = 4
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.Samples = 1
+	plugin.SubInterval = "3s"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.SubInterval = "5ms"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.SubInterval = ""
+	plugin.SpikeThreshold = float64(90)
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.SubInterval = "300ms"
+	plugin.CoreWarning = float64(95)
+	plugin.CoreCritical = float64(90)
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.CoreWarning, plugin.CoreCritical = 0, 0
+	plugin.CoreOverallMax = float64(150)
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.CoreOverallMax = float64(50)
+	plugin.CoreSpreadWarning = float64(60)
+	plugin.CoreSpreadCritical = float64(40)
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.CoreSpreadWarning, plugin.CoreSpreadCritical = 0, 0
+	plugin.TemperatureWarning = float64(90)
+	plugin.TemperatureCritical = float64(80)
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.TemperatureWarning, plugin.TemperatureCritical = 0, 0
+	plugin.TargetPID = 0
+	plugin.CaptureProfile = "/var/tmp"
+	plugin.CaptureDuration = "0s"
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.EqualError(e, "--capture-duration must be positive")
+	plugin.CaptureProfile, plugin.CaptureDuration = "", "5s"
+	plugin.PprofPort = []string{"api=6060"}
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.EqualError(e, "--pprof-port requires --capture-profile")
+	plugin.PprofPort = nil
+	plugin.JVMDiagnostics = true
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.EqualError(e, "--jvm-diagnostics requires --capture-profile")
+	plugin.JVMDiagnostics = false
+	plugin.DStateWarning, plugin.DStateCritical = 10, 5
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.EqualError(e, "--dstate-warning cannot be greater than --dstate-critical")
+	plugin.DStateWarning, plugin.DStateCritical = 0, 0
+	plugin.ForkRateWarning, plugin.ForkRateCritical = 500, 100
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.EqualError(e, "--fork-rate-warning cannot be greater than --fork-rate-critical")
+	plugin.ForkRateWarning, plugin.ForkRateCritical = 0, 0
+	plugin.FDWarning, plugin.FDCritical = 2000, 1000
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.EqualError(e, "--fd-warning cannot be greater than --fd-critical")
+	plugin.FDWarning, plugin.FDCritical = 0, 0
+	plugin.FDRules = []string{"nginx=1000"}
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.Error(e)
+	plugin.FDRules = nil
+	plugin.RTCritical = -1
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.EqualError(e, "--rt-critical cannot be negative")
+	plugin.RTCritical = 0
+	plugin.RestartWarning = true
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.EqualError(e, "--restart-warning requires --watch-name")
+	plugin.RestartWarning = false
+	plugin.PID = []string{"nginx"}
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.EqualError(e, "--pid: \"nginx\" is not a PID")
+	plugin.PID = nil
+	plugin.UnitWarning, plugin.UnitCritical = 200, 100
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.EqualError(e, "--unit-warning cannot be greater than --unit-critical")
+	plugin.UnitWarning, plugin.UnitCritical = 0, 0
+	plugin.Unit, plugin.UnitRollup = []string{"nginx.service"}, true
+	i, e = checkArgs(event)
+	assert.Equal(sensu.CheckStateWarning, i)
+	assert.EqualError(e, "--unit and --unit-rollup cannot be used together")
+	plugin.Unit, plugin.UnitRollup = nil, false
+	plugin.TargetPID = 42
 	i, e = checkArgs(event)
 	assert.Equal(sensu.CheckStateOK, i)
 	assert.NoError(e)
+	plugin.ThresholdProfile, plugin.thresholdProfiles = nil, nil
+	plugin.SuppressWindow, plugin.suppressWindows = nil, nil
+	assert.Equal(7, plugin.sampleCount)
+	plugin.SubInterval, plugin.SpikeThreshold = "", 0
+}
+
+func TestParseInterval(t *testing.T) {
+	assert := assert.New(t)
+	d, err := parseInterval("2")
+	assert.NoError(err)
+	assert.Equal(2*time.Second, d)
+	d, err = parseInterval("500ms")
+	assert.NoError(err)
+	assert.Equal(500*time.Millisecond, d)
+	d, err = parseInterval("1.5s")
+	assert.NoError(err)
+	assert.Equal(1500*time.Millisecond, d)
+	_, err = parseInterval("0s")
+	assert.Error(err)
+	_, err = parseInterval("soon")
+	assert.Error(err)
+}
+
+func TestCPUUsage(t *testing.T) {
+	assert := assert.New(t)
+	start := cpu.TimesStat{User: 100, System: 50, Idle: 850}
+	end := cpu.TimesStat{User: 130, System: 70, Idle: 900}
+	usage := cpuUsage(start, end)
+	assert.InDelta(50, usage.Idle, 0.001)
+	assert.InDelta(50, usage.Used, 0.001)
+	assert.InDelta(30, usage.User, 0.001)
+	assert.InDelta(20, usage.System, 0.001)
+
+	// Within the same clock tick
+	assert.Equal(CPUUsage{}, cpuUsage(end, end))
+}
+
+func TestSampleStats(t *testing.T) {
+	assert := assert.New(t)
+	stats := sampleStats([]float64{10, 20, 30, 40, 100})
+	assert.InDelta(40, stats.Avg, 0.001)
+	assert.Equal(float64(10), stats.Min)
+	assert.Equal(float64(100), stats.Max)
+	assert.Equal(float64(100), stats.P95)
+	assert.Equal(SampleStats{}, sampleStats(nil))
+}
+
+func TestCountSpikes(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(2, countSpikes([]float64{10, 95, 100, 90, 40}, 90))
+	assert.Equal(0, countSpikes(nil, 90))
 }
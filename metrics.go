@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Formats the metrics can be emitted in, named after the Sensu
+// output_metric_format to use with each
+const (
+	metricFormatNagios   = "nagios_perfdata"
+	metricFormatGraphite = "graphite_plaintext"
+	metricFormatInflux   = "influxdb_line"
+	metricFormatOpenTSDB = "opentsdb_line"
+)
+
+// Ways to name Graphite metrics
+const (
+	metricSchemeHost = "host"
+	metricSchemeFlat = "flat"
+)
+
+// Precisions of metric timestamps
+const (
+	precisionSeconds      = "s"
+	precisionMilliseconds = "ms"
+	precisionMicroseconds = "us"
+	precisionNanoseconds  = "ns"
+)
+
+// Characters escaped in InfluxDB line protocol names and tags
+var influxEscaper = strings.NewReplacer(",", "\\,", " ", "\\ ", "=", "\\=")
+
+// Characters not allowed in OpenTSDB tags
+var openTSDBInvalid = regexp.MustCompile(`[^a-zA-Z0-9\-_./]+`)
+
+// Struct to hold a single metric point
+type Metric struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// Function to list the metrics of a CPU usage breakdown
+func usageMetrics(usage CPUUsage) []Metric {
+	return []Metric{
+		{"cpu_idle", usage.Idle},
+		{"cpu_system", usage.System},
+		{"cpu_user", usage.User},
+		{"cpu_nice", usage.Nice},
+		{"cpu_iowait", usage.Iowait},
+		{"cpu_irq", usage.Irq},
+		{"cpu_softirq", usage.Softirq},
+		{"cpu_steal", usage.Steal},
+		{"cpu_guest", usage.Guest},
+		{"cpu_guestnice", usage.GuestNice},
+	}
+}
+
+// Function to format metrics as nagios perfdata
+func formatPerfData(metrics []Metric) string {
+	fields := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		fields = append(fields, fmt.Sprintf("%s=%.2f", m.Name, m.Value))
+	}
+	return strings.Join(fields, ", ")
+}
+
+// Function to get a metric timestamp since the epoch in a precision
+func metricTimestamp(t time.Time, precision string) int64 {
+	switch precision {
+	case precisionMilliseconds:
+		return t.UnixMilli()
+	case precisionMicroseconds:
+		return t.UnixMicro()
+	case precisionNanoseconds:
+		return t.UnixNano()
+	default:
+		return t.Unix()
+	}
+}
+
+// Function to format metrics as Graphite plaintext lines under a path, empty
+// for none. Graphite timestamps are always in seconds.
+func formatGraphiteLines(metrics []Metric, path string, timestamp time.Time) string {
+	if path != "" {
+		path += "."
+	}
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "%s%s %.2f %d\n", path, m.Name, m.Value, timestamp.Unix())
+	}
+	return b.String()
+}
+
+// Function to get the Graphite path of the metrics, {prefix}.{host} with the
+// host scheme and {prefix} with the flat one. The dots of the host name are
+// replaced so it stays one node of the tree.
+func graphitePath(prefix string, scheme string, host string) string {
+	var nodes []string
+	if prefix != "" {
+		nodes = append(nodes, prefix)
+	}
+	if scheme != metricSchemeFlat {
+		nodes = append(nodes, strings.ReplaceAll(host, ".", "_"))
+	}
+	return strings.Join(nodes, ".")
+}
+
+// Function to prefix the names of metrics, separated by a dot
+func prefixMetrics(metrics []Metric, prefix string) []Metric {
+	if prefix == "" {
+		return metrics
+	}
+	prefixed := make([]Metric, len(metrics))
+	for i, m := range metrics {
+		prefixed[i] = Metric{prefix + "." + m.Name, m.Value}
+	}
+	return prefixed
+}
+
+// Function to format metrics as InfluxDB line protocol, one line per metric
+// with its value in a value field
+func formatInfluxLines(metrics []Metric, tags []MetricTag, timestamp int64) string {
+	var tagSet string
+	for _, t := range tags {
+		tagSet += "," + influxEscaper.Replace(t.Name) + "=" + influxEscaper.Replace(t.Value)
+	}
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "%s%s value=%.2f %d\n", influxEscaper.Replace(m.Name), tagSet, m.Value, timestamp)
+	}
+	return b.String()
+}
+
+// Function to format metrics as OpenTSDB put lines. Characters OpenTSDB does
+// not allow in tags are replaced.
+func formatOpenTSDBLines(metrics []Metric, tags []MetricTag, timestamp int64) string {
+	var tagSet string
+	for _, t := range tags {
+		tagSet += " " + openTSDBInvalid.ReplaceAllString(t.Name, "_") + "=" + openTSDBInvalid.ReplaceAllString(t.Value, "_")
+	}
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "put %s %d %.2f%s\n", m.Name, timestamp, m.Value, tagSet)
+	}
+	return b.String()
+}
+
+// Function to format metrics as lines in the format selected with
+// --metric-format, empty for perfdata which goes on the first line of the
+// output instead. Names are prefixed with --metric-prefix, under the host as
+// well for Graphite unless --metric-scheme is flat. Tagged formats get a host
+// tag, unless --metric-tag sets one, and a timestamp in --metric-precision.
+func formatMetricLines(metrics []Metric, timestamp time.Time) string {
+	if plugin.MetricFormat != metricFormatGraphite {
+		metrics = prefixMetrics(metrics, plugin.MetricPrefix)
+	}
+	tags := mergeMetricTags([]MetricTag{{"host", plugin.hostname}}, plugin.metricTags)
+	switch plugin.MetricFormat {
+	case metricFormatGraphite:
+		return formatGraphiteLines(metrics, graphitePath(plugin.MetricPrefix, plugin.MetricScheme, plugin.hostname), timestamp)
+	case metricFormatInflux:
+		return formatInfluxLines(metrics, tags, metricTimestamp(timestamp, plugin.MetricPrecision))
+	case metricFormatOpenTSDB:
+		return formatOpenTSDBLines(metrics, tags, metricTimestamp(timestamp, plugin.MetricPrecision))
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatPerfData(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("cpu_idle=5.00, cpu_user=95.00", formatPerfData([]Metric{{"cpu_idle", 5}, {"cpu_user", 95}}))
+	assert.Equal("", formatPerfData(nil))
+}
+
+func TestMetricTimestamp(t *testing.T) {
+	assert := assert.New(t)
+	ts := time.Unix(1725278400, 123456789)
+	assert.Equal(int64(1725278400), metricTimestamp(ts, precisionSeconds))
+	assert.Equal(int64(1725278400123), metricTimestamp(ts, precisionMilliseconds))
+	assert.Equal(int64(1725278400123456), metricTimestamp(ts, precisionMicroseconds))
+	assert.Equal(int64(1725278400123456789), metricTimestamp(ts, precisionNanoseconds))
+}
+
+func TestFormatGraphiteLines(t *testing.T) {
+	assert := assert.New(t)
+	ts := time.Unix(1725278400, 0)
+	metrics := []Metric{{"cpu_idle", 5}, {"cpu_user", 95}}
+	assert.Equal("db1.cpu_idle 5.00 1725278400\ndb1.cpu_user 95.00 1725278400\n", formatGraphiteLines(metrics, "db1", ts))
+	assert.Equal("cpu_idle 5.00 1725278400\n", formatGraphiteLines(metrics[:1], "", ts))
+}
+
+func TestGraphitePath(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("db1_example_com", graphitePath("", metricSchemeHost, "db1.example.com"))
+	assert.Equal("servers.linux.db1", graphitePath("servers.linux", metricSchemeHost, "db1"))
+	assert.Equal("servers.linux", graphitePath("servers.linux", metricSchemeFlat, "db1"))
+	assert.Equal("", graphitePath("", metricSchemeFlat, "db1"))
+}
+
+func TestPrefixMetrics(t *testing.T) {
+	assert := assert.New(t)
+	metrics := []Metric{{"cpu_idle", 5}}
+	assert.Equal(metrics, prefixMetrics(metrics, ""))
+	assert.Equal([]Metric{{"profiler.cpu_idle", 5}}, prefixMetrics(metrics, "profiler"))
+	assert.Equal("cpu_idle", metrics[0].Name)
+}
+
+func TestFormatInfluxLines(t *testing.T) {
+	assert := assert.New(t)
+	ts := int64(1725278400)
+	metrics := []Metric{{"cpu_idle", 5}, {"cpu_user", 95}}
+	assert.Equal("cpu_idle value=5.00 1725278400\ncpu_user value=95.00 1725278400\n", formatInfluxLines(metrics, nil, ts))
+	tags := []MetricTag{{"service", "web shop"}, {"team", "a,b"}}
+	assert.Equal("cpu_idle,service=web\\ shop,team=a\\,b value=5.00 1725278400\n", formatInfluxLines(metrics[:1], tags, ts))
+}
+
+func TestFormatOpenTSDBLines(t *testing.T) {
+	assert := assert.New(t)
+	ts := int64(1725278400000)
+	metrics := []Metric{{"cpu_idle", 5}}
+	assert.Equal("put cpu_idle 1725278400000 5.00\n", formatOpenTSDBLines(metrics, nil, ts))
+	tags := []MetricTag{{"service", "web shop"}, {"team", "ops"}}
+	assert.Equal("put cpu_idle 1725278400000 5.00 service=web_shop team=ops\n", formatOpenTSDBLines(metrics, tags, ts))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// Struct to hold the nice value of a process and its priority as seen by
+// the kernel, 20 plus the nice value for a process that is not real-time
+type PriorityInfo struct {
+	Nice     int `json:"nice"`
+	Priority int `json:"priority"`
+}
+
+// Function to parse the priority and nice value of a task from its stat file
+// in /proc, the 18th and 19th fields
+func parseStatPriority(data []byte) (PriorityInfo, error) {
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return PriorityInfo{}, fmt.Errorf("invalid stat line %q", data)
+	}
+	// The fields after the name start with the third, the state
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) < 17 {
+		return PriorityInfo{}, fmt.Errorf("invalid stat line %q", data)
+	}
+	priority, err := strconv.Atoi(string(fields[15]))
+	if err != nil {
+		return PriorityInfo{}, fmt.Errorf("invalid stat line %q", data)
+	}
+	nice, err := strconv.Atoi(string(fields[16]))
+	if err != nil {
+		return PriorityInfo{}, fmt.Errorf("invalid stat line %q", data)
+	}
+	return PriorityInfo{Nice: nice, Priority: priority}, nil
+}
+
+// Function to read the nice values of the processes, leaving them unset for
+// those that cannot be read
+func readProcessPriorities(processes []ProcessInfo) {
+	for i := range processes {
+		if priority, err := readPriority(processes[i].PID); err == nil {
+			processes[i].Priority = &priority
+		}
+	}
+}
+
+// Function to tell whether a process was niced into the background, those
+// whose nice value is unknown being taken as interactive
+func (p ProcessInfo) background() bool {
+	return p.Priority != nil && p.Priority.Nice > 0
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+// Function to read the priority and nice value of a process from
+// /proc/<pid>/stat
+func readPriority(pid int32) (PriorityInfo, error) {
+	data, err := os.ReadFile(hostProc(strconv.Itoa(int(pid)), "stat"))
+	if err != nil {
+		return PriorityInfo{}, err
+	}
+	return parseStatPriority(data)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// Function to read the priority and nice value of a process
+func readPriority(pid int32) (PriorityInfo, error) {
+	return PriorityInfo{}, errUnsupported
+}
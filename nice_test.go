@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStatPriority(t *testing.T) {
+	assert := assert.New(t)
+	priority, err := parseStatPriority([]byte(fmt.Sprintf(testStatLine, "0", "0")))
+	assert.NoError(err)
+	assert.Equal(PriorityInfo{Nice: 0, Priority: -51}, priority)
+	priority, err = parseStatPriority([]byte("4321 (backup) S 1 0 0 0 -1 0 0 0 0 0 0 0 0 0 30 10 1"))
+	assert.NoError(err)
+	assert.Equal(PriorityInfo{Nice: 10, Priority: 30}, priority)
+	_, err = parseStatPriority([]byte("4321 (backup) S 1 0 0"))
+	assert.Error(err)
+}
+
+func TestProcessBackground(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(ProcessInfo{Priority: &PriorityInfo{Nice: 10, Priority: 30}}.background())
+	assert.False(ProcessInfo{Priority: &PriorityInfo{Nice: -5, Priority: 15}}.background())
+	assert.False(ProcessInfo{}.background())
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Struct to hold the time a process spent blocked during the interval, over
+// all its threads
+type OffCPUProcess struct {
+	PID     int32   `json:"pid"`
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Function to parse the offsets of the given fields from the format of a
+// tracepoint in tracefs, lines such as
+// "field:pid_t prev_pid;	offset:24;	size:4;	signed:1;"
+func parseTracepointOffsets(r io.Reader, fields ...string) (map[string]int16, error) {
+	offsets := make(map[string]int16, len(fields))
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var name string
+		var offset int16 = -1
+		for _, part := range strings.Split(scanner.Text(), ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(part), ":")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "field":
+				decl := strings.Fields(value)
+				if len(decl) > 0 {
+					name, _, _ = strings.Cut(decl[len(decl)-1], "[")
+				}
+			case "offset":
+				v, err := strconv.ParseInt(value, 10, 16)
+				if err != nil {
+					return nil, fmt.Errorf("invalid offset in %q", scanner.Text())
+				}
+				offset = int16(v)
+			}
+		}
+		if name != "" && offset >= 0 {
+			offsets[name] = offset
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		if _, ok := offsets[f]; !ok {
+			return nil, fmt.Errorf("no field %s in the tracepoint format", f)
+		}
+	}
+	return offsets, nil
+}
+
+// Function to add up the blocked time of threads, in nanoseconds by thread
+// id, into that of their processes and get the n processes blocked the
+// longest. owner gives the process of a thread, false for threads that have
+// exited since, which are left out.
+func topOffCPUProcesses(threads map[uint32]uint64, owner func(tid uint32) (int32, string, bool), n int) []OffCPUProcess {
+	byPID := make(map[int32]*OffCPUProcess)
+	for tid, ns := range threads {
+		pid, name, ok := owner(tid)
+		if !ok {
+			continue
+		}
+		p, ok := byPID[pid]
+		if !ok {
+			p = &OffCPUProcess{PID: pid, Name: name}
+			byPID[pid] = p
+		}
+		p.Seconds += float64(ns) / 1e9
+	}
+	processes := make([]OffCPUProcess, 0, len(byPID))
+	for _, p := range byPID {
+		processes = append(processes, *p)
+	}
+	sort.Slice(processes, func(i, j int) bool {
+		if processes[i].Seconds != processes[j].Seconds {
+			return processes[i].Seconds > processes[j].Seconds
+		}
+		return processes[i].PID < processes[j].PID
+	})
+	if len(processes) > n {
+		processes = processes[:n]
+	}
+	return processes
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/rlimit"
+	"golang.org/x/sys/unix"
+)
+
+// Most threads tracked by the off-CPU collector at once
+const offCPUMaxThreads = 16384
+
+// Struct to hold the eBPF program counting off-CPU time and its maps: the
+// time every blocked thread went off CPU, and the blocked time of every
+// thread since it was attached, by thread id
+type offCPUCollector struct {
+	starts *ebpf.Map
+	totals *ebpf.Map
+	prog   *ebpf.Program
+	link   link.Link
+}
+
+// Function to find the mount point of tracefs
+func tracefsPath() (string, error) {
+	for _, dir := range []string{"/sys/kernel/tracing", "/sys/kernel/debug/tracing"} {
+		if _, err := os.Stat(filepath.Join(dir, "events")); err == nil {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("tracefs is not mounted")
+}
+
+// Function to load the off-CPU collector and attach it to the sched_switch
+// tracepoint. When a thread goes off CPU in an interruptible or
+// uninterruptible sleep the time is recorded, and when it is switched back in
+// the time since is added to its total. The fields of the tracepoint are
+// located from its format in tracefs.
+func loadOffCPUCollector() (*offCPUCollector, error) {
+	tracefs, err := tracefsPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(tracefs, "events", "sched", "sched_switch", "format"))
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := parseTracepointOffsets(f, "prev_pid", "prev_state", "next_pid")
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// Kernels before 5.11 charge BPF memory against RLIMIT_MEMLOCK
+	if err := rlimit.RemoveMemlock(); err != nil {
+		return nil, err
+	}
+	c := &offCPUCollector{}
+	if c.starts, err = ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.Hash, KeySize: 4, ValueSize: 8, MaxEntries: offCPUMaxThreads}); err != nil {
+		return nil, err
+	}
+	if c.totals, err = ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.Hash, KeySize: 4, ValueSize: 8, MaxEntries: offCPUMaxThreads}); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	// R6 holds the context, R7 the time, the thread id is kept at fp-4 and a
+	// time or duration at fp-16
+	insns := asm.Instructions{
+		asm.Mov.Reg(asm.R6, asm.R1),
+		asm.FnKtimeGetNs.Call(),
+		asm.Mov.Reg(asm.R7, asm.R0),
+
+		// Record when the previous thread went to sleep
+		asm.LoadMem(asm.R1, asm.R6, offsets["prev_state"], asm.DWord),
+		asm.And.Imm(asm.R1, 3),
+		asm.JEq.Imm(asm.R1, 0, "next"),
+		asm.LoadMem(asm.R1, asm.R6, offsets["prev_pid"], asm.Word),
+		asm.StoreMem(asm.RFP, -4, asm.R1, asm.Word),
+		asm.StoreMem(asm.RFP, -16, asm.R7, asm.DWord),
+		asm.LoadMapPtr(asm.R1, c.starts.FD()),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, -16),
+		asm.Mov.Imm(asm.R4, 0),
+		asm.FnMapUpdateElem.Call(),
+
+		// Add the time the next thread slept to its total
+		asm.LoadMem(asm.R1, asm.R6, offsets["next_pid"], asm.Word).WithSymbol("next"),
+		asm.StoreMem(asm.RFP, -4, asm.R1, asm.Word),
+		asm.LoadMapPtr(asm.R1, c.starts.FD()),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "exit"),
+		asm.LoadMem(asm.R1, asm.R0, 0, asm.DWord),
+		asm.Sub.Reg(asm.R7, asm.R1),
+		asm.StoreMem(asm.RFP, -16, asm.R7, asm.DWord),
+		asm.LoadMapPtr(asm.R1, c.starts.FD()),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.FnMapDeleteElem.Call(),
+		asm.LoadMapPtr(asm.R1, c.totals.FD()),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "insert"),
+		asm.StoreXAdd(asm.R0, asm.R7, asm.DWord),
+		asm.Ja.Label("exit"),
+		asm.LoadMapPtr(asm.R1, c.totals.FD()).WithSymbol("insert"),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, -16),
+		asm.Mov.Imm(asm.R4, 0),
+		asm.FnMapUpdateElem.Call(),
+
+		asm.Mov.Imm(asm.R0, 0).WithSymbol("exit"),
+		asm.Return(),
+	}
+	if c.prog, err = ebpf.NewProgram(&ebpf.ProgramSpec{Type: ebpf.TracePoint, License: "GPL", Instructions: insns}); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if c.link, err = link.Tracepoint("sched", "sched_switch", c.prog, nil); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Function to detach the collector and release its maps
+func (c *offCPUCollector) Close() {
+	if c.link != nil {
+		c.link.Close()
+	}
+	if c.prog != nil {
+		c.prog.Close()
+	}
+	if c.totals != nil {
+		c.totals.Close()
+	}
+	if c.starts != nil {
+		c.starts.Close()
+	}
+}
+
+// Function to read the blocked time of every thread in nanoseconds, counting
+// threads still asleep up to now
+func (c *offCPUCollector) threads() (map[uint32]uint64, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return nil, err
+	}
+	now := uint64(ts.Nano())
+
+	threads := make(map[uint32]uint64)
+	var tid uint32
+	var ns uint64
+	it := c.totals.Iterate()
+	for it.Next(&tid, &ns) {
+		threads[tid] += ns
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	it = c.starts.Iterate()
+	for it.Next(&tid, &ns) {
+		if now > ns {
+			threads[tid] += now - ns
+		}
+	}
+	return threads, it.Err()
+}
+
+// Function to get the process a thread belongs to from /proc, which lists
+// every thread by its id even though it only shows processes
+func threadOwner(tid uint32) (int32, string, bool) {
+	f, err := os.Open(hostProc(strconv.FormatUint(uint64(tid), 10), "status"))
+	if err != nil {
+		return 0, "", false
+	}
+	defer f.Close()
+	var name string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), ":")
+		switch key {
+		case "Name":
+			name = strings.TrimSpace(value)
+		case "Tgid":
+			pid, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+			if err != nil {
+				return 0, "", false
+			}
+			// The status of a thread names the thread, the process is named
+			// after its main thread
+			if comm, err := os.ReadFile(hostProc(strconv.FormatInt(pid, 10), "comm")); err == nil {
+				name = strings.TrimSpace(string(comm))
+			}
+			return int32(pid), name, pid != 0
+		}
+	}
+	return 0, "", false
+}
+
+// Function to collect the n processes blocked the longest over the given
+// duration with eBPF
+func runOffCPU(d time.Duration, n int) ([]OffCPUProcess, error) {
+	c, err := loadOffCPUCollector()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	time.Sleep(d)
+	threads, err := c.threads()
+	if err != nil {
+		return nil, err
+	}
+	return topOffCPUProcesses(threads, threadOwner, n), nil
+}
+
+// Function to probe for a kernel and privileges the off-CPU collector can be
+// loaded with
+func probeOffCPU() error {
+	c, err := loadOffCPUCollector()
+	if err != nil {
+		return err
+	}
+	c.Close()
+	return nil
+}
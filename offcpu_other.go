@@ -0,0 +1,17 @@
+//go:build !linux
+
+package main
+
+import "time"
+
+// Function to collect the n processes blocked the longest over the given
+// duration
+func runOffCPU(d time.Duration, n int) ([]OffCPUProcess, error) {
+	return nil, errUnsupported
+}
+
+// Function to probe for a kernel and privileges the off-CPU collector can be
+// loaded with
+func probeOffCPU() error {
+	return errUnsupported
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTracepointOffsets(t *testing.T) {
+	assert := assert.New(t)
+	format := `name: sched_switch
+ID: 316
+format:
+	field:unsigned short common_type;	offset:0;	size:2;	signed:0;
+	field:unsigned char common_flags;	offset:2;	size:1;	signed:0;
+
+	field:char prev_comm[16];	offset:8;	size:16;	signed:0;
+	field:pid_t prev_pid;	offset:24;	size:4;	signed:1;
+	field:int prev_prio;	offset:28;	size:4;	signed:1;
+	field:long prev_state;	offset:32;	size:8;	signed:1;
+	field:char next_comm[16];	offset:40;	size:16;	signed:0;
+	field:pid_t next_pid;	offset:56;	size:4;	signed:1;
+
+print fmt: "prev_comm=%s prev_pid=%d"
+`
+	offsets, err := parseTracepointOffsets(strings.NewReader(format), "prev_pid", "prev_state", "next_pid")
+	assert.NoError(err)
+	assert.Equal(int16(24), offsets["prev_pid"])
+	assert.Equal(int16(32), offsets["prev_state"])
+	assert.Equal(int16(56), offsets["next_pid"])
+	assert.Equal(int16(8), offsets["prev_comm"])
+
+	_, err = parseTracepointOffsets(strings.NewReader(format), "next_prio")
+	assert.Error(err)
+}
+
+func TestTopOffCPUProcesses(t *testing.T) {
+	assert := assert.New(t)
+	owners := map[uint32]int32{100: 100, 101: 100, 200: 200, 300: 300}
+	owner := func(tid uint32) (int32, string, bool) {
+		pid, ok := owners[tid]
+		return pid, map[int32]string{100: "postgres", 200: "nginx", 300: "sshd"}[pid], ok
+	}
+	threads := map[uint32]uint64{
+		100: 1500000000,
+		101: 1000000000,
+		200: 2000000000,
+		300: 500000000,
+		// exited before it could be looked up
+		400: 9000000000,
+	}
+	assert.Equal([]OffCPUProcess{
+		{PID: 100, Name: "postgres", Seconds: 2.5},
+		{PID: 200, Name: "nginx", Seconds: 2},
+	}, topOffCPUProcesses(threads, owner, 2))
+	assert.Empty(topOffCPUProcesses(nil, owner, 10))
+}
@@ -0,0 +1,18 @@
+package main
+
+// Struct to hold the badness score the OOM killer gives a process, and the
+// adjustment applied to it
+type OOMScore struct {
+	Score int `json:"score"`
+	Adj   int `json:"adj"`
+}
+
+// Function to read the OOM scores of the processes, leaving them unset for
+// those that cannot be read
+func readProcessOOMScores(processes []ProcessInfo) {
+	for i := range processes {
+		if score, err := readOOMScore(processes[i].PID); err == nil {
+			processes[i].OOM = &score
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Function to read the OOM score of a process from /proc/<pid>/oom_score and
+// oom_score_adj
+func readOOMScore(pid int32) (OOMScore, error) {
+	var score OOMScore
+	for _, f := range []struct {
+		name string
+		dest *int
+	}{{"oom_score", &score.Score}, {"oom_score_adj", &score.Adj}} {
+		data, err := os.ReadFile(hostProc(strconv.Itoa(int(pid)), f.name))
+		if err != nil {
+			return OOMScore{}, err
+		}
+		if *f.dest, err = strconv.Atoi(strings.TrimSpace(string(data))); err != nil {
+			return OOMScore{}, err
+		}
+	}
+	return score, nil
+}
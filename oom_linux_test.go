@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOOMScore(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	t.Setenv("HOST_PROC", root)
+	dir := filepath.Join(root, "42")
+	assert.NoError(os.MkdirAll(dir, 0755))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "oom_score"), []byte("812\n"), 0644))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "oom_score_adj"), []byte("-500\n"), 0644))
+
+	score, err := readOOMScore(42)
+	assert.NoError(err)
+	assert.Equal(OOMScore{Score: 812, Adj: -500}, score)
+	_, err = readOOMScore(43)
+	assert.Error(err)
+}
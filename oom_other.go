@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// Function to read the OOM score of a process
+func readOOMScore(pid int32) (OOMScore, error) {
+	return OOMScore{}, errUnsupported
+}
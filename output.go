@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Delimiters around the JSON block so consumers can find it in the output
+const (
+	jsonBlockBegin = "-----BEGIN CPU-PROCESS-PROFILER JSON-----"
+	jsonBlockEnd   = "-----END CPU-PROCESS-PROFILER JSON-----"
+)
+
+// ANSI escape sequences used by the watch subcommand and --color
+const (
+	ansiClear  = "\033[H\033[2J"
+	ansiBold   = "\033[1m"
+	ansiRed    = "\033[31m"
+	ansiYellow = "\033[33m"
+	ansiReset  = "\033[0m"
+)
+
+// Function to format the human-readable check output within --max-output-bytes
+func formatResult(result *Result) string {
+	return formatResultWithin(result, plugin.MaxOutputBytes)
+}
+
+// Function to format the human-readable check output, truncating the process
+// table so the output fits in a number of bytes. The summary, perfdata and
+// metrics are always kept whole. A limit of 0 leaves the output whole.
+func formatResultWithin(result *Result, limit int) string {
+	status := formatStatusLine(result)
+	var tail string
+	if len(result.Disabled) > 0 {
+		tail += fmt.Sprintf("Unsupported options disabled: %s\n", strings.Join(result.Disabled, ", "))
+	}
+	if result.Fingerprint != "" {
+		tail += fmt.Sprintf("Fingerprint: %s\n", result.Fingerprint)
+	}
+	if lines := formatMetricLines(result.Metrics, result.Timestamp); lines != "" {
+		tail += "\n" + lines
+	}
+	// Output includes the process list irrespective of the state
+	table := formatProcessTable(result)
+	if limit > 0 {
+		table = truncateLines(table, limit-len(status)-len(tail)-2)
+	}
+	return fmt.Sprintf("%s\n%s\n", status, table) + tail
+}
+
+// Function to truncate text to whole lines fitting in a number of bytes,
+// along with a line telling how many were left out
+func truncateLines(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(s, "\n"), "\n")
+	kept := len(s)
+	for n := len(lines) - 1; n >= 0; n-- {
+		kept -= len(lines[n])
+		marker := "... 1 more line truncated\n"
+		if len(lines)-n > 1 {
+			marker = fmt.Sprintf("... %d more lines truncated\n", len(lines)-n)
+		}
+		if kept+len(marker) <= limit {
+			return strings.Join(lines[:n], "") + marker
+		}
+	}
+	return ""
+}
+
+// Function to format the first line of the output, followed by the metrics
+// as perfdata unless another --metric-format is selected
+func formatStatusLine(result *Result) string {
+	line := fmt.Sprintf("%s %s: %s", plugin.PluginConfig.Name, stateLabel(result.Status), result.Summary)
+	if plugin.MetricFormat == "" || plugin.MetricFormat == metricFormatNagios {
+		line += " | " + formatPerfData(prefixMetrics(result.Metrics, plugin.MetricPrefix))
+	}
+	return line + "\n"
+}
+
+// Function to format the output of the metrics subcommand: the summary and
+// the metrics, without the process list
+func formatMetricsOutput(result *Result) string {
+	return formatStatusLine(result) + formatMetricLines(result.Metrics, result.Timestamp)
+}
+
+// Function to format the top processes, and threads in target mode, as
+// aligned tables. With --nice-buckets, the niced background processes are
+// listed apart from the interactive ones, and with --unit-rollup the busiest
+// units are listed instead. With --process-format csv or jsonl, only the top
+// processes are written, in that format.
+func formatProcessTable(result *Result) string {
+	switch plugin.ProcessFormat {
+	case processFormatCSV:
+		columns := plugin.processColumns
+		if columns == nil {
+			columns = processColumns
+		}
+		return formatProcessCSV(result.Processes, columns, result.Timestamp)
+	case processFormatJSONL:
+		return formatProcessJSONL(result.Processes)
+	}
+	var processInfo string
+	if plugin.UnitRollup {
+		processInfo = "Top CPU units:\n" + formatUnits(result.Units)
+	} else if plugin.NiceBuckets {
+		var interactive, background []ProcessInfo
+		for _, p := range result.Processes {
+			if p.background() {
+				background = append(background, p)
+			} else {
+				interactive = append(interactive, p)
+			}
+		}
+		processInfo = "Top interactive CPU processes:\n" + formatProcesses(interactive, result.Timestamp, topProcessThresholds)
+		if len(background) > 0 {
+			processInfo += "\nTop niced background CPU processes:\n" + formatProcesses(background, result.Timestamp, topProcessThresholds)
+		}
+	} else {
+		processInfo = "Top CPU processes:\n" + formatProcesses(result.Processes, result.Timestamp, topProcessThresholds)
+	}
+
+	if len(result.Watched) > 0 {
+		processInfo += "\nWatched processes:\n" + formatProcesses(result.Watched, result.Timestamp, func(ProcessInfo) (float64, float64) {
+			return plugin.PIDWarning, plugin.PIDCritical
+		})
+	}
+
+	if len(result.Units) > 0 && !plugin.UnitRollup {
+		processInfo += "\nUnits:\n" + formatUnits(result.Units)
+	}
+
+	if len(result.Threads) > 0 {
+		rows := make([][]string, len(result.Threads))
+		for i, t := range result.Threads {
+			rows[i] = []string{strconv.Itoa(int(t.TID)), strconv.Itoa(int(t.PID)), fmt.Sprintf("%.2f%%", t.CPU), t.Name}
+		}
+		processInfo += "\nTop CPU threads:\n" + formatTable([]string{"TID", "PID", "CPU", "NAME"}, rows)
+	}
+
+	if len(result.DState) > 0 {
+		rows := make([][]string, len(result.DState))
+		for i, p := range result.DState {
+			rows[i] = []string{strconv.Itoa(int(p.PID)), strconv.Itoa(p.Samples), p.Name}
+		}
+		processInfo += "\nTop D state processes:\n" + formatTable([]string{"PID", "SAMPLES", "NAME"}, rows)
+	}
+
+	if len(result.Zombies) > 0 {
+		rows := make([][]string, len(result.Zombies))
+		for i, p := range result.Zombies {
+			rows[i] = []string{strconv.Itoa(int(p.PID)), strconv.Itoa(p.Zombies), p.Name}
+		}
+		processInfo += "\nParents of zombie processes:\n" + formatTable([]string{"PID", "ZOMBIES", "NAME"}, rows)
+	}
+
+	if len(result.OffCPU) > 0 {
+		rows := make([][]string, len(result.OffCPU))
+		for i, p := range result.OffCPU {
+			rows[i] = []string{strconv.Itoa(int(p.PID)), fmt.Sprintf("%.2fs", p.Seconds), p.Name}
+		}
+		processInfo += "\nTop off-CPU processes:\n" + formatTable([]string{"PID", "BLOCKED", "NAME"}, rows)
+	}
+
+	return processInfo
+}
+
+// Function to get the thresholds a top process is highlighted against with
+// --color, those of the processes sharing its name
+func topProcessThresholds(p ProcessInfo) (float64, float64) {
+	return processThresholds(plugin.processRules, p.Name)
+}
+
+// Function to format processes as a table of the columns of
+// --process-columns, or else of the default columns for them. With --color,
+// the processes over their warning or critical threshold are highlighted.
+func formatProcesses(processes []ProcessInfo, now time.Time, thresholds func(ProcessInfo) (float64, float64)) string {
+	columns := plugin.processColumns
+	if columns == nil {
+		columns = defaultProcessColumns(processes, now)
+	}
+	table := formatProcessColumns(processes, columns, now)
+	if !plugin.colorOutput {
+		return table
+	}
+	colors := make([]string, len(processes))
+	for i, p := range processes {
+		warning, critical := thresholds(p)
+		colors[i] = thresholdColor(p.CPU, warning, critical)
+	}
+	return colorTable(table, colors)
+}
+
+// Function to format the CPU usage of units as a table. With --color, the
+// units over --unit-warning or --unit-critical are highlighted.
+func formatUnits(units []UnitUsage) string {
+	rows := make([][]string, len(units))
+	colors := make([]string, len(units))
+	for i, u := range units {
+		rows[i] = []string{u.Unit, fmt.Sprintf("%.2f%%", u.CPU), strconv.Itoa(u.Processes)}
+		colors[i] = thresholdColor(u.CPU, plugin.UnitWarning, plugin.UnitCritical)
+	}
+	table := formatTable([]string{"UNIT", "CPU", "PROCESSES"}, rows)
+	if !plugin.colorOutput {
+		return table
+	}
+	return colorTable(table, colors)
+}
+
+// Function to format the age of a process in its largest whole unit, from
+// seconds up to days
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	}
+	return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+}
+
+// Function to format one refresh of the watch subcommand: the screen is
+// cleared and redrawn with the CPU breakdown and a table of top processes.
+// With --color, the summary is highlighted by the status and the processes
+// over their warning or critical threshold are highlighted.
+func formatWatchScreen(result *Result) string {
+	var b strings.Builder
+	u := result.Usage
+	b.WriteString(ansiClear)
+	summary := result.Summary
+	if color := statusColor(result.Status); plugin.colorOutput && color != "" {
+		summary = color + summary + ansiReset
+	}
+	fmt.Fprintf(&b, "%s%s %s%s  %s\n\n", ansiBold, plugin.PluginConfig.Name, result.Timestamp.Format("15:04:05"), ansiReset, summary)
+	fmt.Fprintf(&b, "%%Cpu: %5.1f us %5.1f sy %5.1f ni %5.1f id %5.1f wa %5.1f hi %5.1f si %5.1f st\n\n",
+		u.User, u.System, u.Nice, u.Idle, u.Iowait, u.Irq, u.Softirq, u.Steal)
+	fmt.Fprintf(&b, "%s%8s %7s  %s%s\n", ansiBold, "PID", "%CPU", "COMMAND", ansiReset)
+	for _, p := range result.Processes {
+		line := fmt.Sprintf("%8d %7.2f  %s", p.PID, p.CPU, p.Name)
+		warning, critical := topProcessThresholds(p)
+		if color := thresholdColor(p.CPU, warning, critical); plugin.colorOutput && color != "" {
+			line = color + line + ansiReset
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// Function to format the result as a delimited JSON block, to be appended
+// after the human-readable output
+func formatJSONBlock(result *Result) (string, error) {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\n%s\n%s\n", jsonBlockBegin, data, jsonBlockEnd), nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func testResult() *Result {
+	expires := time.Date(2024, 9, 2, 13, 0, 0, 0, time.UTC)
+	growth := 12.5
+	return &Result{
+		Timestamp: time.Date(2024, 9, 2, 12, 0, 0, 0, time.UTC),
+		Status:    sensu.CheckStateCritical,
+		Summary:   "95.00% CPU usage",
+		Usage:     CPUUsage{Idle: 5, Used: 95},
+		Metrics:   []Metric{{"cpu_idle", 5}, {"cpu_user", 95}},
+		Processes: []ProcessInfo{
+			{PID: 42, CPU: 90, Name: "java", Growth: &growth, Pod: &PodInfo{Namespace: "shop", Pod: "web-1", Container: "app"}},
+			{PID: 7, CPU: 5, Name: "backup", SuppressedUntil: &expires},
+		},
+		Breached:    []string{"cpu_critical"},
+		Fingerprint: "0123456789abcdef",
+	}
+}
+
+func TestFormatResult(t *testing.T) {
+	assert := assert.New(t)
+	out := formatResult(testResult())
+	assert.Equal("cpu-process-profiler Critical: 95.00% CPU usage | cpu_idle=5.00, cpu_user=95.00\n"+
+		"\nTop CPU processes:\n"+
+		"PID  CPU     GROWTH   NAMESPACE  POD    CONTAINER  SUPPRESSED_UNTIL      NAME\n"+
+		"42   90.00%  +12.50%  shop       web-1  app                              java\n"+
+		"7    5.00%                                         2024-09-02T13:00:00Z  backup\n"+
+		"\nFingerprint: 0123456789abcdef\n", out)
+}
+
+func TestFormatProcessCSV(t *testing.T) {
+	assert := assert.New(t)
+	result := testResult()
+	result.Processes = append(result.Processes, ProcessInfo{PID: 9, CPU: 1.5, Name: "python3", Cmdline: `python3 -c "print('a, b')"`})
+	plugin.ProcessFormat = processFormatCSV
+	defer func() { plugin.ProcessFormat = "" }()
+	assert.Equal("pid,name,user,cmdline,cpu,started,age,growth,rss,fds,oom_score,oom_adj,nice,priority,sched_policy,sched_priority,namespace,pod,container,image,suppressed_until\n"+
+		"42,java,,,90.00,,,12.50,,,,,,,,,shop,web-1,app,,\n"+
+		"7,backup,,,5.00,,,,,,,,,,,,,,,,2024-09-02T13:00:00Z\n"+
+		`9,python3,,"python3 -c ""print('a, b')""",1.50,,,,,,,,,,,,,,,,`+"\n", formatProcessTable(result))
+
+	plugin.processColumns, _ = parseProcessColumns([]string{"pid", "cpu", "name"})
+	defer func() { plugin.processColumns = nil }()
+	assert.Equal("pid,cpu,name\n42,90.00,java\n7,5.00,backup\n9,1.50,python3\n", formatProcessTable(result))
+}
+
+func TestFormatProcessColumns(t *testing.T) {
+	assert := assert.New(t)
+	result := testResult()
+	rss := uint64(1536 << 20)
+	result.Processes[0].User, result.Processes[0].RSS = "tomcat", &rss
+	result.Watched = []ProcessInfo{{PID: 1234, CPU: 0.5, Name: "nginx", User: "www-data"}}
+	plugin.processColumns, _ = parseProcessColumns([]string{"pid", "user", "cpu", "rss", "name"})
+	defer func() { plugin.processColumns = nil }()
+	assert.Equal("Top CPU processes:\n"+
+		"PID  USER    CPU     RSS         NAME\n"+
+		"42   tomcat  90.00%  1536.0 MiB  java\n"+
+		"7            5.00%               backup\n"+
+		"\nWatched processes:\n"+
+		"PID   USER      CPU    RSS  NAME\n"+
+		"1234  www-data  0.50%       nginx\n", formatProcessTable(result))
+
+	_, err := parseProcessColumns([]string{"pid", "color"})
+	assert.Error(err)
+}
+
+func TestColumnOption(t *testing.T) {
+	assert := assert.New(t)
+	for _, name := range []string{"pid", "user", "age", "rss", "pod", "suppressed_until"} {
+		assert.Empty(columnOption(name), name)
+	}
+	assert.Equal("--cmdline", columnOption("cmdline"))
+	assert.Equal("--fds", columnOption("fds"))
+	assert.Equal("--oom-score", columnOption("oom_adj"))
+	assert.Equal("--nice", columnOption("priority"))
+	assert.Equal("--rt-sched", columnOption("sched_policy"))
+	assert.Equal("--rank-by growth", columnOption("growth"))
+
+	defer func() { plugin.FDWarning, plugin.Nice = 0, false }()
+	plugin.FDWarning, plugin.Nice = 100, true
+	assert.Empty(columnOption("fds"))
+	assert.Empty(columnOption("nice"))
+}
+
+func TestFormatProcessJSONL(t *testing.T) {
+	assert := assert.New(t)
+	result := testResult()
+	plugin.ProcessFormat = processFormatJSONL
+	defer func() { plugin.ProcessFormat = "" }()
+	assert.Equal(`{"pid":42,"cpu":90,"name":"java","created_at":"0001-01-01T00:00:00Z","growth":12.5,"pod":{"namespace":"shop","pod":"web-1","container":"app"}}`+"\n"+
+		`{"pid":7,"cpu":5,"name":"backup","created_at":"0001-01-01T00:00:00Z","suppressed_until":"2024-09-02T13:00:00Z"}`+"\n", formatProcessTable(result))
+
+	result.Processes[1].CPU = math.NaN()
+	assert.Equal(`{"pid":42,"cpu":90,"name":"java","created_at":"0001-01-01T00:00:00Z","growth":12.5,"pod":{"namespace":"shop","pod":"web-1","container":"app"}}`+"\n", formatProcessTable(result))
+}
+
+func TestFormatResultWithin(t *testing.T) {
+	assert := assert.New(t)
+	result := testResult()
+	assert.Equal(formatResult(result), formatResultWithin(result, 1000))
+	assert.Equal("cpu-process-profiler Critical: 95.00% CPU usage | cpu_idle=5.00, cpu_user=95.00\n"+
+		"\nTop CPU processes:\n"+
+		"PID  CPU     GROWTH   NAMESPACE  POD    CONTAINER  SUPPRESSED_UNTIL      NAME\n"+
+		"42   90.00%  +12.50%  shop       web-1  app                              java\n"+
+		"... 1 more line truncated\n"+
+		"\nFingerprint: 0123456789abcdef\n", formatResultWithin(result, 350))
+	// The summary and perfdata are kept even when they do not fit
+	assert.Equal("cpu-process-profiler Critical: 95.00% CPU usage | cpu_idle=5.00, cpu_user=95.00\n"+
+		"\n\nFingerprint: 0123456789abcdef\n", formatResultWithin(result, 10))
+}
+
+func TestTruncateLines(t *testing.T) {
+	assert := assert.New(t)
+	table := "Top CPU processes:\nPID 1 (a): 1.00%\nPID 2 (b): 1.00%\n"
+	assert.Equal(table, truncateLines(table, len(table)))
+	assert.Equal("Top CPU processes:\n... 2 more lines truncated\n", truncateLines(table, 50))
+	assert.Equal("... 3 more lines truncated\n", truncateLines(table, 30))
+	assert.Equal("", truncateLines(table, 5))
+}
+
+func TestFormatMetricsOutput(t *testing.T) {
+	assert := assert.New(t)
+	result := testResult()
+	result.Status = sensu.CheckStateOK
+	assert.Equal("cpu-process-profiler OK: 95.00% CPU usage | cpu_idle=5.00, cpu_user=95.00\n", formatMetricsOutput(result))
+
+	plugin.MetricFormat, plugin.hostname = metricFormatInflux, "web01.example.com"
+	defer func() { plugin.MetricFormat, plugin.hostname = metricFormatNagios, "" }()
+	assert.Equal("cpu-process-profiler OK: 95.00% CPU usage\n"+
+		"cpu_idle,host=web01.example.com value=5.00 1725278400\n"+
+		"cpu_user,host=web01.example.com value=95.00 1725278400\n", formatMetricsOutput(result))
+
+	plugin.MetricFormat = metricFormatGraphite
+	assert.Equal("cpu-process-profiler OK: 95.00% CPU usage\n"+
+		"web01_example_com.cpu_idle 5.00 1725278400\n"+
+		"web01_example_com.cpu_user 95.00 1725278400\n", formatMetricsOutput(result))
+
+	plugin.MetricPrefix = "servers"
+	defer func() { plugin.MetricPrefix = "" }()
+	assert.Equal("cpu-process-profiler OK: 95.00% CPU usage\n"+
+		"servers.web01_example_com.cpu_idle 5.00 1725278400\n"+
+		"servers.web01_example_com.cpu_user 95.00 1725278400\n", formatMetricsOutput(result))
+	plugin.MetricFormat = metricFormatNagios
+	assert.Equal("cpu-process-profiler OK: 95.00% CPU usage | servers.cpu_idle=5.00, servers.cpu_user=95.00\n", formatMetricsOutput(result))
+}
+
+func TestFormatProcessTable(t *testing.T) {
+	assert := assert.New(t)
+	result := testResult()
+	result.Processes = result.Processes[:1]
+	result.Threads = []ThreadInfo{{TID: 43, PID: 42, Name: "java", CPU: 60}}
+	assert.Equal("Top CPU processes:\n"+
+		"PID  CPU     GROWTH   NAMESPACE  POD    CONTAINER  NAME\n"+
+		"42   90.00%  +12.50%  shop       web-1  app        java\n"+
+		"\nTop CPU threads:\n"+
+		"TID  PID  CPU     NAME\n"+
+		"43   42   60.00%  java\n", formatProcessTable(result))
+
+	result.Threads = nil
+	result.OffCPU = []OffCPUProcess{{PID: 7, Name: "postgres", Seconds: 4.25}}
+	result.DState = []DStateProcess{{PID: 7, Name: "postgres", Samples: 3}}
+	result.Zombies = []ZombieParent{{PID: 1, Name: "init", Zombies: 2}}
+	assert.Equal("Top CPU processes:\n"+
+		"PID  CPU     GROWTH   NAMESPACE  POD    CONTAINER  NAME\n"+
+		"42   90.00%  +12.50%  shop       web-1  app        java\n"+
+		"\nTop D state processes:\n"+
+		"PID  SAMPLES  NAME\n"+
+		"7    3        postgres\n"+
+		"\nParents of zombie processes:\n"+
+		"PID  ZOMBIES  NAME\n"+
+		"1    2        init\n"+
+		"\nTop off-CPU processes:\n"+
+		"PID  BLOCKED  NAME\n"+
+		"7    4.25s    postgres\n", formatProcessTable(result))
+
+	fds := 1024
+	result.OffCPU, result.DState, result.Zombies = nil, nil, nil
+	result.Processes[0].FDs = &fds
+	result.Processes[0].OOM = &OOMScore{Score: 812, Adj: -500}
+	result.Processes[0].Sched = &SchedInfo{Policy: "SCHED_FIFO", Priority: 50}
+	assert.Equal("Top CPU processes:\n"+
+		"PID  CPU     GROWTH   FDS   OOM_SCORE  OOM_ADJ  SCHED_POLICY  SCHED_PRIORITY  NAMESPACE  POD    CONTAINER  NAME\n"+
+		"42   90.00%  +12.50%  1024  812        -500     SCHED_FIFO    50              shop       web-1  app        java\n", formatProcessTable(result))
+
+	plugin.NiceBuckets = true
+	defer func() { plugin.NiceBuckets = false }()
+	result.Processes = []ProcessInfo{
+		{PID: 42, CPU: 90, Name: "java", Priority: &PriorityInfo{Nice: 0, Priority: 20}},
+		{PID: 7, CPU: 60, Name: "backup", Priority: &PriorityInfo{Nice: 10, Priority: 30}},
+		{PID: 9, CPU: 5, Name: "sshd"},
+	}
+	assert.Equal("Top interactive CPU processes:\n"+
+		"PID  CPU     NICE  PRIORITY  NAME\n"+
+		"42   90.00%  0     20        java\n"+
+		"9    5.00%                   sshd\n"+
+		"\nTop niced background CPU processes:\n"+
+		"PID  CPU     NICE  PRIORITY  NAME\n"+
+		"7    60.00%  10    30        backup\n", formatProcessTable(result))
+
+	rss := uint64(512 << 20)
+	plugin.NiceBuckets = false
+	result.Processes = result.Processes[:1]
+	result.Watched = []ProcessInfo{{PID: 9, CPU: 5, Name: "sshd", RSS: &rss}}
+	assert.Equal("Top CPU processes:\n"+
+		"PID  CPU     NICE  PRIORITY  NAME\n"+
+		"42   90.00%  0     20        java\n"+
+		"\nWatched processes:\n"+
+		"PID  CPU    RSS        NAME\n"+
+		"9    5.00%  512.0 MiB  sshd\n", formatProcessTable(result))
+
+	result.Watched = nil
+	result.Units = []UnitUsage{{Kind: unitKindSystemd, Unit: "nginx.service", CPU: 12.5, Processes: 4}}
+	assert.Equal("Top CPU processes:\n"+
+		"PID  CPU     NICE  PRIORITY  NAME\n"+
+		"42   90.00%  0     20        java\n"+
+		"\nUnits:\n"+
+		"UNIT           CPU     PROCESSES\n"+
+		"nginx.service  12.50%  4\n", formatProcessTable(result))
+
+	plugin.UnitRollup = true
+	assert.Equal("Top CPU units:\n"+
+		"UNIT           CPU     PROCESSES\n"+
+		"nginx.service  12.50%  4\n", formatProcessTable(result))
+	plugin.UnitRollup = false
+
+	result.Units = nil
+	plugin.ProcessAge = true
+	defer func() { plugin.ProcessAge = false }()
+	result.Processes = []ProcessInfo{
+		{PID: 42, CPU: 90, Name: "java", CreatedAt: result.Timestamp.Add(-40 * 24 * time.Hour)},
+		{PID: 7, CPU: 60, Name: "backup", CreatedAt: result.Timestamp.Add(-30 * time.Second)},
+		{PID: 9, CPU: 5, Name: "sshd"},
+	}
+	assert.Equal("Top CPU processes:\n"+
+		"PID  CPU     STARTED               AGE  NAME\n"+
+		"42   90.00%  2024-07-24T12:00:00Z  40d  java\n"+
+		"7    60.00%  2024-09-02T11:59:30Z  30s  backup\n"+
+		"9    5.00%                              sshd\n", formatProcessTable(result))
+
+	plugin.ProcessAge, plugin.Cmdline = false, true
+	plugin.CmdlineLength = 20
+	defer func() { plugin.Cmdline, plugin.CmdlineLength = false, 0 }()
+	result.Processes = []ProcessInfo{
+		{PID: 42, CPU: 90, Name: "java", Cmdline: "/usr/bin/java -Xmx2g -jar app.jar"},
+		{PID: 2, CPU: 5, Name: "kthreadd"},
+	}
+	assert.Equal("Top CPU processes:\n"+
+		"PID  CPU     CMDLINE\n"+
+		"42   90.00%  /usr/bin/java -Xmx2g...\n"+
+		"2    5.00%   kthreadd\n", formatProcessTable(result))
+}
+
+func TestFormatWatchScreen(t *testing.T) {
+	assert := assert.New(t)
+	result := testResult()
+	result.Usage = CPUUsage{Idle: 5, Used: 95, User: 80, System: 15}
+	assert.Equal(ansiClear+
+		ansiBold+"cpu-process-profiler 12:00:00"+ansiReset+"  95.00% CPU usage\n\n"+
+		"%Cpu:  80.0 us  15.0 sy   0.0 ni   5.0 id   0.0 wa   0.0 hi   0.0 si   0.0 st\n\n"+
+		ansiBold+"     PID    %CPU  COMMAND"+ansiReset+"\n"+
+		"      42   90.00  java\n"+
+		"       7    5.00  backup\n", formatWatchScreen(result))
+}
+
+func TestFormatJSONBlock(t *testing.T) {
+	assert := assert.New(t)
+	block, err := formatJSONBlock(testResult())
+	assert.NoError(err)
+
+	lines := strings.Split(strings.TrimSuffix(block, "\n"), "\n")
+	assert.Equal(jsonBlockBegin, lines[0])
+	assert.Equal(jsonBlockEnd, lines[len(lines)-1])
+
+	var decoded map[string]interface{}
+	assert.NoError(json.Unmarshal([]byte(strings.Join(lines[1:len(lines)-1], "\n")), &decoded))
+	assert.Equal(float64(sensu.CheckStateCritical), decoded["status"])
+	assert.Equal("0123456789abcdef", decoded["fingerprint"])
+	assert.Len(decoded["processes"], 2)
+}
+
+func TestFormatAge(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("30s", formatAge(30*time.Second))
+	assert.Equal("12m", formatAge(12*time.Minute+30*time.Second))
+	assert.Equal("5h", formatAge(5*time.Hour+59*time.Minute))
+	assert.Equal("40d", formatAge(40*24*time.Hour+3*time.Hour))
+}
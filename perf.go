@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Hardware events counted by --perf
+var perfEvents = []string{
+	"cycles",
+	"instructions",
+	"cache-references",
+	"cache-misses",
+	"stalled-cycles-frontend",
+	"stalled-cycles-backend",
+}
+
+// Struct to hold the hardware event counts of a perf stat run by event name.
+// Events the CPU does not support or that were not counted are left out.
+type PerfCounters map[string]float64
+
+// Function to parse the CSV output of "perf stat -x,". On hybrid CPUs an
+// event is counted on every kind of core, as cpu_core/cycles/ and
+// cpu_atom/cycles/, and the counts are added up.
+func parsePerfStat(r io.Reader) (PerfCounters, error) {
+	counters := PerfCounters{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) < 3 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			// <not counted> or <not supported>
+			continue
+		}
+		counters[perfEventName(fields[2])] += value
+	}
+	return counters, scanner.Err()
+}
+
+// Function to reduce an event as printed by perf stat to its name, without
+// the PMU of hybrid CPUs or modifiers such as :u
+func perfEventName(event string) string {
+	if parts := strings.Split(event, "/"); len(parts) == 3 {
+		event = parts[1]
+	}
+	name, _, _ := strings.Cut(event, ":")
+	return name
+}
+
+// Function to get a counter as a ratio of another, false when either was not
+// counted
+func (c PerfCounters) ratio(numerator, denominator string) (float64, bool) {
+	n, ok := c[numerator]
+	d, ok2 := c[denominator]
+	if !ok || !ok2 || d == 0 {
+		return 0, false
+	}
+	return n / d, true
+}
+
+// Function to get the metrics of the counters: instructions per cycle, the
+// cache miss rate and the share of cycles stalled in the front and back end,
+// as far as they were counted
+func (c PerfCounters) metrics() []Metric {
+	var metrics []Metric
+	if ipc, ok := c.ratio("instructions", "cycles"); ok {
+		metrics = append(metrics, Metric{"cpu_ipc", ipc})
+	}
+	if misses, ok := c.ratio("cache-misses", "cache-references"); ok {
+		metrics = append(metrics, Metric{"cpu_cache_miss_pct", misses * 100})
+	}
+	if stalled, ok := c.ratio("stalled-cycles-frontend", "cycles"); ok {
+		metrics = append(metrics, Metric{"cpu_stalled_frontend_pct", stalled * 100})
+	}
+	if stalled, ok := c.ratio("stalled-cycles-backend", "cycles"); ok {
+		metrics = append(metrics, Metric{"cpu_stalled_backend_pct", stalled * 100})
+	}
+	return metrics
+}
+
+// Function to describe the counters for the summary
+func (c PerfCounters) String() string {
+	var parts []string
+	if ipc, ok := c.ratio("instructions", "cycles"); ok {
+		parts = append(parts, fmt.Sprintf("IPC %.2f", ipc))
+	}
+	if misses, ok := c.ratio("cache-misses", "cache-references"); ok {
+		parts = append(parts, fmt.Sprintf("%.1f%% cache misses", misses*100))
+	}
+	return strings.Join(parts, ", ")
+}
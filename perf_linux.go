@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Function to count the hardware events of every CPU with perf stat for the
+// given duration, killing it when it runs past that by more than
+// --exec-timeout. perf stat writes its counts to stderr. Counting every CPU
+// needs root or kernel.perf_event_paranoid set to 0 or less.
+func runPerfStat(d time.Duration) (PerfCounters, error) {
+	ctx := context.Background()
+	if plugin.execTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d+plugin.execTimeout)
+		defer cancel()
+	}
+	args := []string{"stat", "-a", "-x,", "-e", strings.Join(perfEvents, ","), "--", "sleep", fmt.Sprintf("%.3f", d.Seconds())}
+	cmd := exec.CommandContext(ctx, "perf", args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return parsePerfStat(&stderr)
+}
+
+// Function to probe for a perf binary to run
+func probePerf() error {
+	_, err := exec.LookPath("perf")
+	return err
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import "time"
+
+// Function to count the hardware events of every CPU for the given duration
+func runPerfStat(d time.Duration) (PerfCounters, error) {
+	return nil, errUnsupported
+}
+
+// Function to probe for a perf binary to run
+func probePerf() error {
+	return errUnsupported
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePerfStat(t *testing.T) {
+	assert := assert.New(t)
+	out := `# started on Thu Oct 15 10:00:00 2026
+
+4000000000,,cpu_core/cycles/,2001234567,100.00,,
+1000000000,,cpu_atom/cycles/,2001234567,100.00,,
+7500000000,,instructions:u,2001234567,100.00,1.50,insn per cycle
+2000000,,cache-references,2001234567,100.00,,
+500000,,cache-misses,2001234567,100.00,25.00,of all cache refs
+<not supported>,,stalled-cycles-frontend,0,100.00,,
+1250000000,,stalled-cycles-backend,2001234567,100.00,25.00,backend cycles idle
+`
+	counters, err := parsePerfStat(strings.NewReader(out))
+	assert.NoError(err)
+	assert.Equal(PerfCounters{
+		"cycles":                 5e9,
+		"instructions":           7.5e9,
+		"cache-references":       2e6,
+		"cache-misses":           5e5,
+		"stalled-cycles-backend": 1.25e9,
+	}, counters)
+	assert.Equal([]Metric{
+		{"cpu_ipc", 1.5},
+		{"cpu_cache_miss_pct", 25},
+		{"cpu_stalled_backend_pct", 25},
+	}, counters.metrics())
+	assert.Equal("IPC 1.50, 25.0% cache misses", counters.String())
+
+	assert.Nil(PerfCounters{}.metrics())
+	assert.Equal("", PerfCounters{}.String())
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+)
+
+// Struct to hold a process to always report, given by PID or read from the
+// pidfile of a daemon
+type WatchedPID struct {
+	PID  int32
+	File string
+}
+
+// Function to get the prefix of the metrics of a watched process. Those read
+// from a pidfile are named after the file so they survive a restart.
+func (w WatchedPID) metricPrefix() string {
+	if w.File != "" {
+		name := strings.TrimSuffix(filepath.Base(w.File), ".pid")
+		return "pidfile_" + entityNameInvalid.ReplaceAllString(name, "_") + "_"
+	}
+	return fmt.Sprintf("pid_%d_", w.PID)
+}
+
+// Function to describe a watched process for the summary
+func (w WatchedPID) String() string {
+	if w.File != "" {
+		return fmt.Sprintf("PID %d from %s", w.PID, w.File)
+	}
+	return fmt.Sprintf("PID %d", w.PID)
+}
+
+// Function to parse the PIDs given to --pid
+func parsePIDs(specs []string) ([]WatchedPID, error) {
+	pids := make([]WatchedPID, 0, len(specs))
+	for _, spec := range specs {
+		pid, err := strconv.ParseInt(spec, 10, 32)
+		if err != nil || pid <= 0 {
+			return nil, fmt.Errorf("%q is not a PID", spec)
+		}
+		pids = append(pids, WatchedPID{PID: int32(pid)})
+	}
+	return pids, nil
+}
+
+// Function to read the PID of a daemon from its pidfile, the first line of
+// which holds it
+func readPIDFile(path string) (WatchedPID, error) {
+	watch := WatchedPID{File: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return watch, err
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	pid, err := strconv.ParseInt(strings.TrimSpace(line), 10, 32)
+	if err != nil || pid <= 0 {
+		return watch, fmt.Errorf("%s does not hold a PID", path)
+	}
+	watch.PID = int32(pid)
+	return watch, nil
+}
+
+// Function to find the watched PIDs in the process list, returning the
+// processes found and the watches that are not running
+func watchedProcesses(processList []ProcessInfo, watches []WatchedPID) ([]ProcessInfo, []WatchedPID) {
+	byPID := make(map[int32]ProcessInfo, len(processList))
+	for _, p := range processList {
+		byPID[p.PID] = p
+	}
+	var found []ProcessInfo
+	var missing []WatchedPID
+	for _, w := range watches {
+		if p, ok := byPID[w.PID]; ok {
+			found = append(found, p)
+		} else {
+			missing = append(missing, w)
+		}
+	}
+	return found, missing
+}
+
+// Function to list the metrics of the watched processes, with those not
+// running reported as such
+func watchedMetrics(watches []WatchedPID, found []ProcessInfo) []Metric {
+	byPID := make(map[int32]ProcessInfo, len(found))
+	for _, p := range found {
+		byPID[p.PID] = p
+	}
+	var metrics []Metric
+	for _, w := range watches {
+		prefix := w.metricPrefix()
+		p, ok := byPID[w.PID]
+		if !ok {
+			metrics = append(metrics, Metric{prefix + "running", 0})
+			continue
+		}
+		metrics = append(metrics, Metric{prefix + "running", 1}, Metric{prefix + "cpu", p.CPU})
+		if p.RSS != nil {
+			metrics = append(metrics, Metric{prefix + "rss_bytes", float64(*p.RSS)})
+		}
+	}
+	return metrics
+}
+
+// Function to evaluate the CPU usage of the watched processes against the
+// thresholds, as a percentage of one core, returning the worst state along
+// with the busiest process over a threshold
+func watchedBreach(processes []ProcessInfo, warning, critical float64) (int, *ProcessInfo) {
+	status := sensu.CheckStateOK
+	var offender *ProcessInfo
+	for i, p := range processes {
+		s := sensu.CheckStateOK
+		if critical > 0 && p.CPU > critical {
+			s = sensu.CheckStateCritical
+		} else if warning > 0 && p.CPU > warning {
+			s = sensu.CheckStateWarning
+		}
+		if s == sensu.CheckStateOK {
+			continue
+		}
+		if s > status || (s == status && p.CPU > offender.CPU) {
+			status, offender = s, &processes[i]
+		}
+	}
+	return status, offender
+}
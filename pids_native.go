@@ -0,0 +1,20 @@
+//go:build !openbsd
+
+package main
+
+import "github.com/shirou/gopsutil/v3/process"
+
+// Function to read the resident memory of the processes, leaving it unset
+// for those that cannot be read
+func readProcessMemory(processes []ProcessInfo) {
+	for i := range processes {
+		p, err := process.NewProcess(processes[i].PID)
+		if err != nil {
+			continue
+		}
+		if mem, err := p.MemoryInfo(); err == nil {
+			rss := mem.RSS
+			processes[i].RSS = &rss
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+// Function to read the resident memory of the processes, leaving it unset
+// for those that cannot be read. gopsutil's process support needs cgo on
+// OpenBSD, so it comes from a single ps call.
+func readProcessMemory(processes []ProcessInfo) {
+	out, err := runPS(psEveryProcess, "-o", "pid=,rss=")
+	if err != nil {
+		return
+	}
+	rss, err := parsePSRSS(out)
+	if err != nil {
+		return
+	}
+	for i := range processes {
+		if v, ok := rss[processes[i].PID]; ok {
+			processes[i].RSS = &v
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePIDs(t *testing.T) {
+	assert := assert.New(t)
+	pids, err := parsePIDs([]string{"1234", "42"})
+	assert.NoError(err)
+	assert.Equal([]WatchedPID{{PID: 1234}, {PID: 42}}, pids)
+	_, err = parsePIDs([]string{"nginx"})
+	assert.Error(err)
+	_, err = parsePIDs([]string{"0"})
+	assert.Error(err)
+}
+
+func TestReadPIDFile(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nginx.pid")
+	assert.NoError(os.WriteFile(path, []byte("1234\n"), 0644))
+	w, err := readPIDFile(path)
+	assert.NoError(err)
+	assert.Equal(WatchedPID{PID: 1234, File: path}, w)
+	assert.Equal("pidfile_nginx_", w.metricPrefix())
+	assert.Equal("PID 1234 from "+path, w.String())
+
+	assert.NoError(os.WriteFile(path, []byte("\n"), 0644))
+	_, err = readPIDFile(path)
+	assert.EqualError(err, path+" does not hold a PID")
+	w, err = readPIDFile(filepath.Join(dir, "missing.pid"))
+	assert.Error(err)
+	assert.Equal("pidfile_missing_", w.metricPrefix())
+}
+
+func TestWatchedProcesses(t *testing.T) {
+	assert := assert.New(t)
+	processList := []ProcessInfo{
+		{PID: 10, Name: "java", CPU: 90},
+		{PID: 20, Name: "nginx", CPU: 5},
+	}
+	found, missing := watchedProcesses(processList, []WatchedPID{{PID: 20}, {PID: 30}})
+	assert.Equal([]ProcessInfo{{PID: 20, Name: "nginx", CPU: 5}}, found)
+	assert.Equal([]WatchedPID{{PID: 30}}, missing)
+}
+
+func TestWatchedMetrics(t *testing.T) {
+	assert := assert.New(t)
+	rss := uint64(1 << 20)
+	watches := []WatchedPID{{PID: 20}, {PID: 21, File: "/run/php-fpm/php-fpm.pid"}, {PID: 30}}
+	found := []ProcessInfo{
+		{PID: 20, Name: "nginx", CPU: 5, RSS: &rss},
+		{PID: 21, Name: "php-fpm", CPU: 1},
+	}
+	assert.Equal([]Metric{
+		{"pid_20_running", 1},
+		{"pid_20_cpu", 5},
+		{"pid_20_rss_bytes", 1 << 20},
+		{"pidfile_php-fpm_running", 1},
+		{"pidfile_php-fpm_cpu", 1},
+		{"pid_30_running", 0},
+	}, watchedMetrics(watches, found))
+}
+
+func TestWatchedBreach(t *testing.T) {
+	assert := assert.New(t)
+	processes := []ProcessInfo{
+		{PID: 10, Name: "java", CPU: 90},
+		{PID: 20, Name: "nginx", CPU: 150},
+		{PID: 30, Name: "cron", CPU: 1},
+	}
+	status, offender := watchedBreach(processes, 50, 100)
+	assert.Equal(sensu.CheckStateCritical, status)
+	assert.Equal(int32(20), offender.PID)
+	status, offender = watchedBreach(processes, 50, 0)
+	assert.Equal(sensu.CheckStateWarning, status)
+	assert.Equal(int32(20), offender.PID)
+	status, offender = watchedBreach(processes, 0, 0)
+	assert.Equal(sensu.CheckStateOK, status)
+	assert.Nil(offender)
+}
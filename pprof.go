@@ -0,0 +1,95 @@
+package main
+
+import (
+	"debug/buildinfo"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct to hold the port the pprof endpoint of the Go processes named after
+// a pattern listens on
+type PprofPort struct {
+	Pattern string
+	Port    int
+}
+
+// Function to parse pattern=port specs mapping process names to the port of
+// their pprof endpoint
+func parsePprofPorts(specs []string) ([]PprofPort, error) {
+	ports := make([]PprofPort, 0, len(specs))
+	for _, spec := range specs {
+		pattern, port, ok := strings.Cut(spec, "=")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("%q is not a pattern=port mapping", spec)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%q: %v", spec, err)
+		}
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return nil, fmt.Errorf("%q: %q is not a port", spec, port)
+		}
+		ports = append(ports, PprofPort{Pattern: pattern, Port: p})
+	}
+	return ports, nil
+}
+
+// Function to get the pprof port of a process from the first mapping its name
+// matches
+func pprofPortFor(ports []PprofPort, name string) (int, bool) {
+	for _, p := range ports {
+		if ok, _ := path.Match(p.Pattern, name); ok {
+			return p.Port, true
+		}
+	}
+	return 0, false
+}
+
+// Function to tell whether the executable of a process was built by Go, from
+// the build information the Go linker embeds
+func isGoProcess(pid int32) bool {
+	exe, err := processExecutable(pid)
+	if err != nil {
+		return false
+	}
+	_, err = buildinfo.ReadFile(exe)
+	return err == nil
+}
+
+// Function to fetch a CPU profile of the given duration from the pprof
+// endpoint on a local port and write it into dir. Returns the path of the
+// file written.
+func fetchPprofProfile(port int, pid int32, d time.Duration, dir string) (string, error) {
+	seconds := int(d.Round(time.Second).Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	url := fmt.Sprintf("http://127.0.0.1:%d/debug/pprof/profile?seconds=%d", port, seconds)
+	client := &http.Client{Timeout: time.Duration(seconds)*time.Second + 10*time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+
+	name := captureFileName(dir, "cpu-profile", pid, "pprof")
+	f, err := os.Create(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		os.Remove(name)
+		return "", err
+	}
+	return name, f.Close()
+}
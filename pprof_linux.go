@@ -0,0 +1,10 @@
+package main
+
+import "strconv"
+
+// Function to get a path the executable of a process can be opened at. The
+// exe link in /proc opens it even when the process runs in another mount
+// namespace.
+func processExecutable(pid int32) (string, error) {
+	return hostProc(strconv.Itoa(int(pid)), "exe"), nil
+}
@@ -0,0 +1,8 @@
+package main
+
+// Function to get a path the executable of a process can be opened at.
+// OpenBSD keeps no record of the path a process was started from, and
+// gopsutil's process package, which would look for one, needs cgo there.
+func processExecutable(pid int32) (string, error) {
+	return "", errUnsupported
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !openbsd
+
+package main
+
+import "github.com/shirou/gopsutil/v3/process"
+
+// Function to get a path the executable of a process can be opened at
+func processExecutable(pid int32) (string, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return "", err
+	}
+	return p.Exe()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePprofPorts(t *testing.T) {
+	assert := assert.New(t)
+	ports, err := parsePprofPorts([]string{"api-*=6060", "*=8080"})
+	assert.NoError(err)
+	assert.Equal([]PprofPort{{"api-*", 6060}, {"*", 8080}}, ports)
+
+	port, ok := pprofPortFor(ports, "api-gateway")
+	assert.True(ok)
+	assert.Equal(6060, port)
+	port, ok = pprofPortFor(ports, "worker")
+	assert.True(ok)
+	assert.Equal(8080, port)
+	_, ok = pprofPortFor(ports[:1], "worker")
+	assert.False(ok)
+
+	for _, spec := range []string{"api", "=6060", "api=http", "api=70000", "[=6060"} {
+		_, err := parsePprofPorts([]string{spec})
+		assert.Error(err, spec)
+	}
+}
+
+func TestIsGoProcess(t *testing.T) {
+	assert := assert.New(t)
+	// The executable of a process cannot be found on OpenBSD
+	assert.Equal(runtime.GOOS != "openbsd", isGoProcess(int32(os.Getpid())))
+}
+
+func TestFetchPprofProfile(t *testing.T) {
+	assert := assert.New(t)
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/debug/pprof/profile" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.RawQuery
+		w.Write([]byte("profile"))
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	assert.NoError(err)
+	port, err := strconv.Atoi(u.Port())
+	assert.NoError(err)
+
+	dir := t.TempDir()
+	file, err := fetchPprofProfile(port, 42, 2*time.Second, dir)
+	assert.NoError(err)
+	assert.Equal("seconds=2", query)
+	assert.Regexp(`/cpu-profile-42-\d{8}T\d{6}\.pprof$`, file)
+	data, err := os.ReadFile(file)
+	assert.NoError(err)
+	assert.Equal("profile", string(data))
+
+	server.Config.Handler = http.NotFoundHandler()
+	_, err = fetchPprofProfile(port, 42, time.Second, dir)
+	assert.Error(err)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+)
+
+// Struct to hold the CPU usage of the processes sharing a name, evaluated
+// against the per-process thresholds
+type ProcessGroup struct {
+	Name   string  `json:"name"`
+	CPU    float64 `json:"cpu"`
+	Count  int     `json:"count"`
+	Status int     `json:"status"`
+}
+
+// Struct to hold the thresholds of the process groups matching a name pattern
+type ProcessRule struct {
+	Pattern  string
+	Warning  float64
+	Critical float64
+}
+
+// Characters not allowed in Sensu entity names
+var entityNameInvalid = regexp.MustCompile(`[^\w.\-]+`)
+
+// Function to parse pattern=warning:critical process rules. Either threshold
+// may be left empty, or 0, to disable it.
+func parseProcessRules(specs []string) ([]ProcessRule, error) {
+	rules := make([]ProcessRule, 0, len(specs))
+	for _, spec := range specs {
+		pattern, thresholds, ok := strings.Cut(spec, "=")
+		warning, critical, ok2 := strings.Cut(thresholds, ":")
+		if !ok || !ok2 || pattern == "" {
+			return nil, fmt.Errorf("%q is not a pattern=warning:critical rule", spec)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%q: %v", spec, err)
+		}
+		rule := ProcessRule{Pattern: pattern}
+		for _, t := range []struct {
+			value string
+			dest  *float64
+		}{{warning, &rule.Warning}, {critical, &rule.Critical}} {
+			if t.value == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(t.value, 64)
+			if err != nil || v < 0 {
+				return nil, fmt.Errorf("%q: %q is not a threshold", spec, t.value)
+			}
+			*t.dest = v
+		}
+		if rule.Warning > 0 && rule.Critical > 0 && rule.Warning > rule.Critical {
+			return nil, fmt.Errorf("%q: the warning threshold cannot be greater than the critical one", spec)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Function to get the thresholds of a process group, from the first rule
+// matching its name or else --process-warning and --process-critical
+func processThresholds(rules []ProcessRule, name string) (float64, float64) {
+	for _, r := range rules {
+		if ok, _ := path.Match(r.Pattern, name); ok {
+			return r.Warning, r.Critical
+		}
+	}
+	return plugin.ProcessWarning, plugin.ProcessCritical
+}
+
+// Function to group every process by name, summing their CPU usage, and
+// evaluate each group against its thresholds.
+// Groups over a threshold are returned along with those that were over one in
+// the previous run, which come back OK so their events resolve. Suppressed
+// processes are left out. The alerting groups are stored in the state.
+func processGroups(processList []ProcessInfo, suppressions []Suppression, state *State, now time.Time) []ProcessGroup {
+	groups := make(map[string]*ProcessGroup)
+	for _, p := range processList {
+		if _, ok := suppressedUntil(suppressions, p.Name, now); ok {
+			continue
+		}
+		g, ok := groups[p.Name]
+		if !ok {
+			g = &ProcessGroup{Name: p.Name}
+			groups[p.Name] = g
+		}
+		g.CPU += p.CPU
+		g.Count++
+	}
+
+	previous := make(map[string]bool, len(state.AlertingGroups))
+	for _, name := range state.AlertingGroups {
+		previous[name] = true
+	}
+	var evaluated []ProcessGroup
+	state.AlertingGroups = nil
+	for name, g := range groups {
+		warning, critical := processThresholds(plugin.processRules, name)
+		if critical > 0 && g.CPU > critical {
+			g.Status = sensu.CheckStateCritical
+		} else if warning > 0 && g.CPU > warning {
+			g.Status = sensu.CheckStateWarning
+		}
+		if g.Status != sensu.CheckStateOK {
+			state.AlertingGroups = append(state.AlertingGroups, name)
+		}
+		if g.Status != sensu.CheckStateOK || previous[name] {
+			evaluated = append(evaluated, *g)
+		}
+		delete(previous, name)
+	}
+	// Groups that are gone entirely resolve as well
+	for name := range previous {
+		evaluated = append(evaluated, ProcessGroup{Name: name})
+	}
+	sort.Strings(state.AlertingGroups)
+	sort.Slice(evaluated, func(i, j int) bool {
+		return evaluated[i].Name < evaluated[j].Name
+	})
+	return evaluated
+}
+
+// Function to build the event of a process group, reported against a proxy
+// entity named after the host and the group so it can be routed and silenced
+// on its own
+func processGroupEvent(group ProcessGroup, host string, timestamp time.Time, name string, handlers []string) *SensuEvent {
+	output := fmt.Sprintf("%s %s: %d %s processes using %.2f%% CPU", name, stateLabel(group.Status), group.Count, group.Name, group.CPU)
+	return &SensuEvent{
+		Timestamp: timestamp.Unix(),
+		Check: SensuCheck{
+			Metadata:        SensuMetadata{Name: name},
+			Status:          group.Status,
+			Output:          output + "\n",
+			Executed:        timestamp.Unix(),
+			Handlers:        handlers,
+			ProxyEntityName: entityNameInvalid.ReplaceAllString(host+"-"+group.Name, "_"),
+		},
+	}
+}
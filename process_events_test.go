@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessGroups(t *testing.T) {
+	assert := assert.New(t)
+	plugin.ProcessWarning, plugin.ProcessCritical = 50, 150
+	defer func() { plugin.ProcessWarning, plugin.ProcessCritical = 0, 0 }()
+
+	now := time.Now()
+	processList := []ProcessInfo{
+		{PID: 1, Name: "java", CPU: 90},
+		{PID: 2, Name: "java", CPU: 80},
+		{PID: 3, Name: "nginx", CPU: 60},
+		{PID: 4, Name: "sshd", CPU: 1},
+		{PID: 5, Name: "importer", CPU: 99},
+	}
+	suppressions := []Suppression{{Pattern: "importer", Expires: now.Add(time.Hour)}}
+	state := State{AlertingGroups: []string{"cron", "sshd"}}
+	groups := processGroups(processList, suppressions, &state, now)
+	assert.Equal([]ProcessGroup{
+		{Name: "cron"},
+		{Name: "java", CPU: 170, Count: 2, Status: sensu.CheckStateCritical},
+		{Name: "nginx", CPU: 60, Count: 1, Status: sensu.CheckStateWarning},
+		{Name: "sshd", CPU: 1, Count: 1},
+	}, groups)
+	assert.Equal([]string{"java", "nginx"}, state.AlertingGroups)
+
+	// Nothing to report once every group has resolved
+	processList[0].CPU, processList[1].CPU, processList[2].CPU = 1, 1, 1
+	groups = processGroups(processList, suppressions, &state, now)
+	assert.Len(groups, 2)
+	groups = processGroups(processList, suppressions, &state, now)
+	assert.Empty(groups)
+	assert.Empty(state.AlertingGroups)
+}
+
+func TestProcessGroupEvent(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	group := ProcessGroup{Name: "php-fpm: pool www", CPU: 120.5, Count: 4, Status: sensu.CheckStateWarning}
+	event := processGroupEvent(group, "web01.example.com", now, "cpu-process-profiler", []string{"pagerduty"})
+	assert.Equal("web01.example.com-php-fpm_pool_www", event.Check.ProxyEntityName)
+	assert.Equal("cpu-process-profiler", event.Check.Metadata.Name)
+	assert.Equal(sensu.CheckStateWarning, event.Check.Status)
+	assert.Equal("cpu-process-profiler Warning: 4 php-fpm: pool www processes using 120.50% CPU\n", event.Check.Output)
+	assert.Equal([]string{"pagerduty"}, event.Check.Handlers)
+	assert.Nil(event.Metrics)
+}
+
+func TestParseProcessRules(t *testing.T) {
+	assert := assert.New(t)
+	rules, err := parseProcessRules([]string{"java*=200:400", "nginx=:150", "cron=50:"})
+	assert.NoError(err)
+	assert.Equal([]ProcessRule{
+		{Pattern: "java*", Warning: 200, Critical: 400},
+		{Pattern: "nginx", Critical: 150},
+		{Pattern: "cron", Warning: 50},
+	}, rules)
+
+	for _, spec := range []string{"java", "java=200", "=1:2", "java=a:2", "java=300:200", "[=1:2"} {
+		_, err := parseProcessRules([]string{spec})
+		assert.Error(err, spec)
+	}
+}
+
+func TestProcessThresholds(t *testing.T) {
+	assert := assert.New(t)
+	plugin.ProcessWarning, plugin.ProcessCritical = 50, 150
+	defer func() { plugin.ProcessWarning, plugin.ProcessCritical = 0, 0 }()
+	rules := []ProcessRule{{Pattern: "java*", Warning: 200, Critical: 400}, {Pattern: "*", Critical: 100}}
+	warning, critical := processThresholds(rules, "java-agent")
+	assert.Equal(200.0, warning)
+	assert.Equal(400.0, critical)
+	warning, critical = processThresholds(rules, "nginx")
+	assert.Equal(0.0, warning)
+	assert.Equal(100.0, critical)
+	warning, critical = processThresholds(nil, "nginx")
+	assert.Equal(50.0, warning)
+	assert.Equal(150.0, critical)
+}
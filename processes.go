@@ -0,0 +1,124 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Struct to hold process info
+type ProcessInfo struct {
+	PID             int32          `json:"pid"`
+	CPU             float64        `json:"cpu"`
+	Name            string         `json:"name"`
+	Cmdline         string         `json:"cmdline,omitempty"`
+	User            string         `json:"user,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	CPUTime         float64        `json:"-"`
+	Growth          *float64       `json:"growth,omitempty"`
+	Pod             *PodInfo       `json:"pod,omitempty"`
+	Container       *ContainerInfo `json:"container,omitempty"`
+	FDs             *int           `json:"fds,omitempty"`
+	RSS             *uint64        `json:"rss,omitempty"`
+	OOM             *OOMScore      `json:"oom,omitempty"`
+	Priority        *PriorityInfo  `json:"priority,omitempty"`
+	Sched           *SchedInfo     `json:"sched,omitempty"`
+	SuppressedUntil *time.Time     `json:"suppressed_until,omitempty"`
+}
+
+// Function to list every running process along with the CPU time it has
+// used so far, through --ps-command or the process backend selected for this
+// platform
+func listProcesses() ([]ProcessInfo, error) {
+	if plugin.psCommand != nil {
+		return commandProcesses()
+	}
+	if plugin.WindowsBackend == windowsBackendWMI {
+		return wmiProcesses()
+	}
+	return nativeProcesses()
+}
+
+// Function to read the CPU time used so far by every running process, keyed
+// by processKey, to measure process CPU usage over the sample interval from
+func processCPUTimes() (map[string]float64, error) {
+	processList, err := listProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	times := make(map[string]float64, len(processList))
+	for _, p := range processList {
+		times[processKey(p)] = p.CPUTime
+	}
+	return times, nil
+}
+
+// Function to get all running processes, with their CPU usage over the
+// interval since the start times were read as a percentage of one core. The
+// same is measured on every platform, like the "% Processor Time" counter on
+// Windows, rather than an average over each process's lifetime.
+func getProcesses(start map[string]float64, elapsed time.Duration) ([]ProcessInfo, error) {
+	processList, err := listProcesses()
+	if err != nil {
+		return nil, err
+	}
+	sampleStart := time.Now().Add(-elapsed)
+	for i := range processList {
+		processList[i].CPU = intervalCPU(processList[i], start, sampleStart, elapsed)
+	}
+	return processList, nil
+}
+
+// Function to compute the CPU usage of a process over the interval that
+// began at sampleStart. A process started during the interval used all of its
+// CPU time within it. One that was already running but missing from the start
+// times, as when reading them failed, has no usage to measure.
+func intervalCPU(p ProcessInfo, start map[string]float64, sampleStart time.Time, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	startTime, ok := start[processKey(p)]
+	if !ok && p.CreatedAt.Before(sampleStart) {
+		return 0
+	}
+	used := p.CPUTime - startTime
+	if used < 0 {
+		used = 0
+	}
+	return used / elapsed.Seconds() * 100
+}
+
+// Function to get the top n CPU consuming processes of a list
+func topCPUProcesses(processList []ProcessInfo, n int) []ProcessInfo {
+	sorted := append([]ProcessInfo(nil), processList...)
+
+	// Sort the processes by CPU usage
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].CPU > sorted[j].CPU
+	})
+
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// Function to find the process name with the most processes started since a
+// point in time, returning the name and how many were started
+func startBurst(processList []ProcessInfo, since time.Time) (string, int) {
+	counts := make(map[string]int)
+	for _, p := range processList {
+		if !p.CreatedAt.Before(since) {
+			counts[p.Name]++
+		}
+	}
+
+	var name string
+	var max int
+	for n, c := range counts {
+		if c > max || (c == max && n < name) {
+			name, max = n, c
+		}
+	}
+	return name, max
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Function to list every running process along with the CPU time it has
+// used so far. gopsutil runs ps once per process and attribute on darwin,
+// which takes seconds on a busy host, so the names and CPU times of all
+// processes come from a single ps call using only options BSD ps
+// understands, sorted in Go by the caller, and start times from sysctl.
+func nativeProcesses() ([]ProcessInfo, error) {
+	out, err := runPS("-axo", "pid=,time=,comm=")
+	if err != nil {
+		return nil, err
+	}
+	listed, err := parsePSProcesses(out)
+	if err != nil {
+		return nil, err
+	}
+
+	procs, err := unix.SysctlKinfoProcSlice("kern.proc.all")
+	if err != nil {
+		return nil, err
+	}
+	var processList []ProcessInfo
+	for _, k := range procs {
+		p, ok := listed[k.Proc.P_pid]
+		if !ok {
+			continue
+		}
+		start := k.Proc.P_starttime
+		processList = append(processList, ProcessInfo{
+			PID:       k.Proc.P_pid,
+			Name:      p.Name,
+			CreatedAt: time.Unix(start.Sec, int64(start.Usec)*int64(time.Microsecond)),
+			CPUTime:   p.CPUTime,
+		})
+	}
+	return processList, nil
+}
@@ -0,0 +1,42 @@
+//go:build !darwin && !openbsd && !solaris
+
+package main
+
+import (
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Function to list every running process along with the CPU time it has
+// used so far, through the native process APIs
+func nativeProcesses() ([]ProcessInfo, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	var processList []ProcessInfo
+	for _, p := range procs {
+		name, err := p.Name()
+		if err != nil {
+			continue
+		}
+		created, err := p.CreateTime()
+		if err != nil {
+			continue
+		}
+		times, err := p.Times()
+		if err != nil {
+			continue
+		}
+
+		processList = append(processList, ProcessInfo{
+			PID:       p.Pid,
+			Name:      name,
+			CreatedAt: time.Unix(0, created*int64(time.Millisecond)),
+			CPUTime:   times.User + times.System,
+		})
+	}
+	return processList, nil
+}
@@ -0,0 +1,17 @@
+package main
+
+import "time"
+
+// Option to make ps list every process
+const psEveryProcess = "-ax"
+
+// Function to list every running process along with the CPU time it has
+// used so far. gopsutil's process support needs cgo on OpenBSD, which the
+// release builds do not use, so processes come from a single ps call.
+func nativeProcesses() ([]ProcessInfo, error) {
+	out, err := runPS(psEveryProcess, "-o", "pid=,time=,lstart=,comm=")
+	if err != nil {
+		return nil, err
+	}
+	return parsePSStartProcesses(out, time.Local)
+}
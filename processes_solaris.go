@@ -0,0 +1,20 @@
+package main
+
+import "time"
+
+// Option to make ps list every process. Solaris ps is the System V one,
+// where -a only lists processes attached to a terminal.
+const psEveryProcess = "-e"
+
+// Function to list every running process along with the CPU time it has
+// used so far. gopsutil has no process support on Solaris and illumos, and
+// their ps has no lstart column, so processes come from a single ps call
+// with start times derived from the elapsed time since each one started.
+func nativeProcesses() ([]ProcessInfo, error) {
+	now := time.Now()
+	out, err := runPS(psEveryProcess, "-o", "pid=,time=,etime=,comm=")
+	if err != nil {
+		return nil, err
+	}
+	return parsePSElapsedProcesses(out, now)
+}
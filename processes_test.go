@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopCPUProcesses(t *testing.T) {
+	assert := assert.New(t)
+	list := []ProcessInfo{
+		{PID: 1, CPU: 1, Name: "init"},
+		{PID: 2, CPU: 50, Name: "java"},
+		{PID: 3, CPU: 20, Name: "nginx"},
+	}
+	top := topCPUProcesses(list, 2)
+	assert.Equal([]int32{2, 3}, []int32{top[0].PID, top[1].PID})
+	assert.Len(topCPUProcesses(list, 10), 3)
+	assert.Equal(int32(1), list[0].PID)
+}
+
+func TestStartBurst(t *testing.T) {
+	assert := assert.New(t)
+	since := time.Date(2024, 9, 2, 12, 0, 0, 0, time.UTC)
+	list := []ProcessInfo{
+		{PID: 1, Name: "init", CreatedAt: since.Add(-time.Hour)},
+		{PID: 2, Name: "php", CreatedAt: since.Add(time.Second)},
+		{PID: 3, Name: "php", CreatedAt: since.Add(time.Second)},
+		{PID: 4, Name: "cron", CreatedAt: since},
+	}
+	name, count := startBurst(list, since)
+	assert.Equal("php", name)
+	assert.Equal(2, count)
+
+	name, count = startBurst(list[:1], since)
+	assert.Equal("", name)
+	assert.Equal(0, count)
+}
+
+func TestIntervalCPU(t *testing.T) {
+	assert := assert.New(t)
+	created := time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC)
+	p := ProcessInfo{PID: 42, Name: "java", CreatedAt: created, CPUTime: 13}
+	start := map[string]float64{processKey(p): 10}
+	sampleStart := created.Add(time.Minute)
+	assert.InDelta(150, intervalCPU(p, start, sampleStart, 2*time.Second), 0.001)
+
+	// Running before the interval but missing from the start times
+	assert.Equal(0.0, intervalCPU(p, map[string]float64{}, sampleStart, 2*time.Second))
+
+	// Started during the interval
+	assert.InDelta(650, intervalCPU(p, map[string]float64{}, created, 2*time.Second), 0.001)
+	assert.InDelta(650, intervalCPU(p, map[string]float64{}, created.Add(-time.Second), 2*time.Second), 0.001)
+	assert.Equal(0.0, intervalCPU(p, start, sampleStart, 0))
+}
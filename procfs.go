@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Function to build a path under /proc, honouring HOST_PROC like gopsutil
+// does so a containerised agent can read the host's /proc
+func hostProc(elem ...string) string {
+	root := os.Getenv("HOST_PROC")
+	if root == "" {
+		root = "/proc"
+	}
+	return filepath.Join(append([]string{root}, elem...)...)
+}
+
+// Function to build a path under /sys, honouring HOST_SYS like gopsutil does
+func hostSys(elem ...string) string {
+	root := os.Getenv("HOST_SYS")
+	if root == "" {
+		root = "/sys"
+	}
+	return filepath.Join(append([]string{root}, elem...)...)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error returned by collectors that are not available on this platform
+var errUnsupported = errors.New("not supported on this platform")
+
+// Struct to hold the system activity counters of /proc/stat
+type ProcStat struct {
+	Ctxt         uint64
+	Intr         uint64
+	Softirq      uint64
+	Processes    uint64
+	ProcsRunning uint64
+	ProcsBlocked uint64
+	BootTime     uint64
+}
+
+// Function to parse the contents of /proc/stat
+func parseProcStat(r io.Reader) (ProcStat, error) {
+	var stat ProcStat
+	fields := map[string]*uint64{
+		"ctxt":          &stat.Ctxt,
+		"intr":          &stat.Intr,
+		"softirq":       &stat.Softirq,
+		"processes":     &stat.Processes,
+		"procs_running": &stat.ProcsRunning,
+		"procs_blocked": &stat.ProcsBlocked,
+		"btime":         &stat.BootTime,
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 2 {
+			continue
+		}
+		dst, ok := fields[parts[0]]
+		if !ok {
+			continue
+		}
+		// The first value of intr and softirq is the total, the rest are
+		// per source
+		v, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return stat, err
+		}
+		*dst = v
+	}
+	return stat, scanner.Err()
+}
+
+// Function to get the rate per second of a counter read at both ends of
+// elapsed, 0 if it went backwards
+func counterRate(start, end uint64, elapsed time.Duration) float64 {
+	secs := elapsed.Seconds()
+	if secs <= 0 || end < start {
+		return 0
+	}
+	return float64(end-start) / secs
+}
+
+// Function to get the processes created per second across /proc/stat reads
+// taken over elapsed, false without any
+func forkRate(reads []ProcStat, elapsed time.Duration) (float64, bool) {
+	if len(reads) == 0 {
+		return 0, false
+	}
+	return counterRate(reads[0].Processes, reads[len(reads)-1].Processes, elapsed), true
+}
+
+// Function to list the system_activity metrics of /proc/stat reads taken
+// across a sample. Rates are computed between the first and last read over
+// elapsed, the process counts are taken from the last read along with their
+// peak across all reads.
+func systemActivityMetrics(reads []ProcStat, elapsed time.Duration) []Metric {
+	if len(reads) == 0 {
+		return nil
+	}
+	start, end := reads[0], reads[len(reads)-1]
+	rate := func(s, e uint64) float64 {
+		return counterRate(s, e, elapsed)
+	}
+
+	var runningMax, blockedMax uint64
+	for _, r := range reads {
+		if r.ProcsRunning > runningMax {
+			runningMax = r.ProcsRunning
+		}
+		if r.ProcsBlocked > blockedMax {
+			blockedMax = r.ProcsBlocked
+		}
+	}
+
+	return []Metric{
+		{"system_activity_ctxt_per_sec", rate(start.Ctxt, end.Ctxt)},
+		{"system_activity_intr_per_sec", rate(start.Intr, end.Intr)},
+		{"system_activity_softirq_per_sec", rate(start.Softirq, end.Softirq)},
+		{"system_activity_forks_per_sec", rate(start.Processes, end.Processes)},
+		{"system_activity_procs_running", float64(end.ProcsRunning)},
+		{"system_activity_procs_running_max", float64(runningMax)},
+		{"system_activity_procs_blocked", float64(end.ProcsBlocked)},
+		{"system_activity_procs_blocked_max", float64(blockedMax)},
+		{"system_activity_boot_time", float64(end.BootTime)},
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import "os"
+
+// Function to read the system activity counters of /proc/stat
+func readProcStat() (ProcStat, error) {
+	f, err := os.Open(hostProc("stat"))
+	if err != nil {
+		return ProcStat{}, err
+	}
+	defer f.Close()
+	return parseProcStat(f)
+}
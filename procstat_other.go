@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// Function to read the system activity counters of /proc/stat
+func readProcStat() (ProcStat, error) {
+	return ProcStat{}, errUnsupported
+}
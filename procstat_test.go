@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const procStatFixture = `cpu  2255 34 2290 22625563 6290 127 456 0 0 0
+cpu0 1132 34 1441 11311718 3675 127 438 0 0 0
+intr 114930548 113199788 3 0 5 263 0 4 [...]
+ctxt 1990473
+btime 1062191376
+processes 2915
+procs_running 1
+procs_blocked 0
+softirq 183433 0 21755 12 39 1137 231 21459 2263
+`
+
+func TestParseProcStat(t *testing.T) {
+	assert := assert.New(t)
+	stat, err := parseProcStat(strings.NewReader(procStatFixture))
+	assert.NoError(err)
+	assert.Equal(ProcStat{
+		Ctxt:         1990473,
+		Intr:         114930548,
+		Softirq:      183433,
+		Processes:    2915,
+		ProcsRunning: 1,
+		ProcsBlocked: 0,
+		BootTime:     1062191376,
+	}, stat)
+}
+
+func TestSystemActivityMetrics(t *testing.T) {
+	assert := assert.New(t)
+	reads := []ProcStat{
+		{Ctxt: 1000, Intr: 500, Softirq: 100, Processes: 10, ProcsRunning: 2},
+		{Ctxt: 2000, Intr: 1000, Softirq: 200, Processes: 12, ProcsRunning: 9, ProcsBlocked: 4},
+		{Ctxt: 3000, Intr: 1500, Softirq: 300, Processes: 14, ProcsRunning: 3, ProcsBlocked: 1, BootTime: 42},
+	}
+	metrics := systemActivityMetrics(reads, 2*time.Second)
+	assert.Equal([]Metric{
+		{"system_activity_ctxt_per_sec", 1000},
+		{"system_activity_intr_per_sec", 500},
+		{"system_activity_softirq_per_sec", 100},
+		{"system_activity_forks_per_sec", 2},
+		{"system_activity_procs_running", 3},
+		{"system_activity_procs_running_max", 9},
+		{"system_activity_procs_blocked", 1},
+		{"system_activity_procs_blocked_max", 4},
+		{"system_activity_boot_time", 42},
+	}, metrics)
+	assert.Nil(systemActivityMetrics(nil, time.Second))
+
+	forks, ok := forkRate(reads, 2*time.Second)
+	assert.True(ok)
+	assert.Equal(float64(2), forks)
+	_, ok = forkRate(nil, time.Second)
+	assert.False(ok)
+	// A counter going backwards, such as across a reboot, is no rate
+	assert.Equal(float64(0), counterRate(14, 10, time.Second))
+}
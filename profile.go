@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct to hold the overall CPU thresholds applying during a daily window,
+// in minutes since local midnight. A window ending before it starts runs past
+// midnight. A threshold left at 0 keeps --warning or --critical.
+type ThresholdProfile struct {
+	Window   string
+	Start    int
+	End      int
+	Warning  float64
+	Critical float64
+}
+
+// Function to parse a clock time of day into minutes since midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day as HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Function to rejoin the profiles split on commas by the flag parser, the
+// pieces without a window belonging to the profile before them
+func joinProfileSpecs(specs []string) []string {
+	var joined []string
+	for _, spec := range specs {
+		if len(joined) > 0 && !strings.Contains(spec, "=") {
+			joined[len(joined)-1] += "," + spec
+			continue
+		}
+		joined = append(joined, spec)
+	}
+	return joined
+}
+
+// Function to parse HH:MM-HH:MM=warn:N,crit:N threshold profiles, with at
+// least one of warn and crit
+func parseThresholdProfiles(specs []string) ([]ThresholdProfile, error) {
+	specs = joinProfileSpecs(specs)
+	profiles := make([]ThresholdProfile, 0, len(specs))
+	for _, spec := range specs {
+		window, thresholds, ok := strings.Cut(spec, "=")
+		from, to, ok2 := strings.Cut(window, "-")
+		if !ok || !ok2 || thresholds == "" {
+			return nil, fmt.Errorf("%q is not a HH:MM-HH:MM=warn:N,crit:N profile", spec)
+		}
+		profile := ThresholdProfile{Window: window}
+		var err error
+		if profile.Start, err = parseClock(from); err != nil {
+			return nil, fmt.Errorf("%q: %v", spec, err)
+		}
+		if profile.End, err = parseClock(to); err != nil {
+			return nil, fmt.Errorf("%q: %v", spec, err)
+		}
+		if profile.Start == profile.End {
+			return nil, fmt.Errorf("%q: the window cannot start and end at the same time", spec)
+		}
+		for _, t := range strings.Split(thresholds, ",") {
+			key, value, _ := strings.Cut(t, ":")
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil || v <= 0 {
+				return nil, fmt.Errorf("%q: %q is not a threshold", spec, value)
+			}
+			switch key {
+			case "warn":
+				profile.Warning = v
+			case "crit":
+				profile.Critical = v
+			default:
+				return nil, fmt.Errorf("%q: unknown threshold %q, expected warn or crit", spec, key)
+			}
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// Function to tell whether a time falls within the window of a profile
+func (p ThresholdProfile) covers(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if p.Start < p.End {
+		return m >= p.Start && m < p.End
+	}
+	return m >= p.Start || m < p.End
+}
+
+// Function to get the thresholds of a profile, keeping the given ones where
+// it leaves them unset
+func (p ThresholdProfile) thresholds(warning, critical float64) (float64, float64) {
+	if p.Warning > 0 {
+		warning = p.Warning
+	}
+	if p.Critical > 0 {
+		critical = p.Critical
+	}
+	return warning, critical
+}
+
+// Function to get the overall CPU thresholds at a time, from the first
+// profile covering it or else the given defaults, along with the window of
+// that profile
+func profileThresholds(profiles []ThresholdProfile, t time.Time, warning, critical float64) (float64, float64, string) {
+	for _, p := range profiles {
+		if p.covers(t) {
+			warning, critical = p.thresholds(warning, critical)
+			return warning, critical, p.Window
+		}
+	}
+	return warning, critical, ""
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseThresholdProfiles(t *testing.T) {
+	assert := assert.New(t)
+
+	profiles, err := parseThresholdProfiles([]string{"02:00-05:00=warn:95,crit:99", "22:30-06:00=crit:98"})
+	assert.NoError(err)
+	assert.Equal([]ThresholdProfile{
+		{Window: "02:00-05:00", Start: 120, End: 300, Warning: 95, Critical: 99},
+		{Window: "22:30-06:00", Start: 1350, End: 360, Critical: 98},
+	}, profiles)
+
+	// As split on commas by the flag parser
+	split, err := parseThresholdProfiles([]string{"02:00-05:00=warn:95", "crit:99", "22:30-06:00=crit:98"})
+	assert.NoError(err)
+	assert.Equal(profiles, split)
+
+	for _, spec := range []string{
+		"02:00-05:00",
+		"02:00=warn:95",
+		"2am-5am=warn:95",
+		"02:00-25:00=warn:95",
+		"02:00-02:00=warn:95",
+		"02:00-05:00=",
+		"02:00-05:00=warn:high",
+		"02:00-05:00=warn:0",
+		"02:00-05:00=warning:95",
+	} {
+		_, err := parseThresholdProfiles([]string{spec})
+		assert.Error(err, spec)
+	}
+}
+
+func TestProfileThresholds(t *testing.T) {
+	assert := assert.New(t)
+	profiles := []ThresholdProfile{
+		{Window: "02:00-05:00", Start: 120, End: 300, Warning: 95, Critical: 99},
+		{Window: "22:30-06:00", Start: 1350, End: 360, Critical: 98},
+	}
+	at := func(hour, min int) time.Time {
+		return time.Date(2024, 5, 2, hour, min, 0, 0, time.Local)
+	}
+
+	cases := []struct {
+		time     time.Time
+		warning  float64
+		critical float64
+		window   string
+	}{
+		{at(12, 0), 75, 90, ""},
+		{at(2, 0), 95, 99, "02:00-05:00"},
+		{at(4, 59), 95, 99, "02:00-05:00"},
+		{at(5, 0), 75, 98, "22:30-06:00"},
+		{at(23, 15), 75, 98, "22:30-06:00"},
+		{at(6, 0), 75, 90, ""},
+		{at(22, 29), 75, 90, ""},
+	}
+	for _, c := range cases {
+		warning, critical, window := profileThresholds(profiles, c.time, 75, 90)
+		assert.Equal(c.warning, warning, c.time)
+		assert.Equal(c.critical, critical, c.time)
+		assert.Equal(c.window, window, c.time)
+	}
+}
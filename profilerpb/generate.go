@@ -0,0 +1,5 @@
+// Package profilerpb holds the protobuf messages and gRPC service the daemon
+// streams its samples with.
+package profilerpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative profiler.proto
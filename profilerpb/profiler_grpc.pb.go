@@ -0,0 +1,136 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v25.1.0
+// source: profiler.proto
+
+package profilerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Profiler_Subscribe_FullMethodName = "/cpuprocessprofiler.v1.Profiler/Subscribe"
+)
+
+// ProfilerClient is the client API for Profiler service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ProfilerClient interface {
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Profiler_SubscribeClient, error)
+}
+
+type profilerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProfilerClient(cc grpc.ClientConnInterface) ProfilerClient {
+	return &profilerClient{cc}
+}
+
+func (c *profilerClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Profiler_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Profiler_ServiceDesc.Streams[0], Profiler_Subscribe_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &profilerSubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Profiler_SubscribeClient interface {
+	Recv() (*Sample, error)
+	grpc.ClientStream
+}
+
+type profilerSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *profilerSubscribeClient) Recv() (*Sample, error) {
+	m := new(Sample)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ProfilerServer is the server API for Profiler service.
+// All implementations must embed UnimplementedProfilerServer
+// for forward compatibility
+type ProfilerServer interface {
+	Subscribe(*SubscribeRequest, Profiler_SubscribeServer) error
+	mustEmbedUnimplementedProfilerServer()
+}
+
+// UnimplementedProfilerServer must be embedded to have forward compatible implementations.
+type UnimplementedProfilerServer struct {
+}
+
+func (UnimplementedProfilerServer) Subscribe(*SubscribeRequest, Profiler_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedProfilerServer) mustEmbedUnimplementedProfilerServer() {}
+
+// UnsafeProfilerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProfilerServer will
+// result in compilation errors.
+type UnsafeProfilerServer interface {
+	mustEmbedUnimplementedProfilerServer()
+}
+
+func RegisterProfilerServer(s grpc.ServiceRegistrar, srv ProfilerServer) {
+	s.RegisterService(&Profiler_ServiceDesc, srv)
+}
+
+func _Profiler_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProfilerServer).Subscribe(m, &profilerSubscribeServer{stream})
+}
+
+type Profiler_SubscribeServer interface {
+	Send(*Sample) error
+	grpc.ServerStream
+}
+
+type profilerSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *profilerSubscribeServer) Send(m *Sample) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Profiler_ServiceDesc is the grpc.ServiceDesc for Profiler service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Profiler_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cpuprocessprofiler.v1.Profiler",
+	HandlerType: (*ProfilerServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Profiler_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "profiler.proto",
+}
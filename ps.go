@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout of the ps lstart column in the C locale
+const psLstartLayout = "Mon Jan _2 15:04:05 2006"
+
+// Columns --ps-format accepts. lstart spans five fields and comm, which may
+// contain spaces, takes the rest of the line.
+const (
+	psColumnPID    = "pid"
+	psColumnTime   = "time"
+	psColumnEtime  = "etime"
+	psColumnLstart = "lstart"
+	psColumnComm   = "comm"
+)
+
+// Function to run ps in the C locale so its output can be parsed
+func runPS(args ...string) (io.Reader, error) {
+	return runCommand("ps", args...)
+}
+
+// Function to run a process listing command in the C locale so its output
+// can be parsed. The command is killed after --exec-timeout, and not waited
+// for any longer: a ps stuck in uninterruptible sleep cannot be killed until
+// its I/O completes, which must not hold up the check.
+func runCommand(name string, args ...string) (io.Reader, error) {
+	ctx := context.Background()
+	if plugin.execTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, plugin.execTimeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+
+	type output struct {
+		out []byte
+		err error
+	}
+	done := make(chan output, 1)
+	go func() {
+		out, err := cmd.Output()
+		done <- output{out, err}
+	}()
+	select {
+	case o := <-done:
+		if o.err != nil {
+			return nil, o.err
+		}
+		return bytes.NewReader(decodeCommandOutput(o.out)), nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%s did not finish within %s", name, plugin.execTimeout)
+	}
+}
+
+// Function to list every running process with the command given to
+// --ps-command, parsed according to the columns of --ps-format
+func commandProcesses() ([]ProcessInfo, error) {
+	now := time.Now()
+	out, err := runCommand(plugin.psCommand[0], plugin.psCommand[1:]...)
+	if err != nil {
+		return nil, err
+	}
+	return parsePSColumns(out, plugin.psColumns, now, time.Local)
+}
+
+// Struct to hold a process as listed by ps
+type psProcess struct {
+	Name    string
+	CPUTime float64
+}
+
+// Function to parse a ps CPU time of the form [[dd-]hh:]mm:ss[.cc] into
+// seconds. BSD ps lets minutes run past 59 instead of printing hours.
+func parsePSTime(s string) (float64, error) {
+	var days float64
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		d, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ps time %q", s)
+		}
+		days, s = d, s[i+1:]
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid ps time %q", s)
+	}
+	var seconds float64
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ps time %q", s)
+		}
+		seconds = seconds*60 + v
+	}
+	return days*86400 + seconds, nil
+}
+
+// Function to parse the output of "ps -axo pid=,time=,comm=" into the
+// processes by PID. The command may contain spaces and is reduced to the
+// name of the executable.
+func parsePSProcesses(r io.Reader) (map[int32]psProcess, error) {
+	processes := make(map[int32]psProcess)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err := strconv.ParseInt(fields[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ps line %q", scanner.Text())
+		}
+		cpuTime, err := parsePSTime(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		line := strings.TrimSpace(scanner.Text())
+		for _, f := range fields[:2] {
+			line = strings.TrimSpace(strings.TrimPrefix(line, f))
+		}
+		processes[int32(pid)] = psProcess{Name: path.Base(line), CPUTime: cpuTime}
+	}
+	return processes, scanner.Err()
+}
+
+// Function to parse the output of "ps -axo pid=,time=,lstart=,comm=" into
+// processes. lstart is the start time to the second in the local time zone.
+func parsePSStartProcesses(r io.Reader, loc *time.Location) ([]ProcessInfo, error) {
+	columns := []string{psColumnPID, psColumnTime, psColumnLstart, psColumnComm}
+	return parsePSColumns(r, columns, time.Time{}, loc)
+}
+
+// Function to parse the output of "ps -o pid=,time=,etime=,comm=" into
+// processes. etime is the time elapsed since the process started, to the
+// second, so the start time is taken back from when ps was run.
+func parsePSElapsedProcesses(r io.Reader, now time.Time) ([]ProcessInfo, error) {
+	columns := []string{psColumnPID, psColumnTime, psColumnEtime, psColumnComm}
+	return parsePSColumns(r, columns, now, time.Local)
+}
+
+// Function to parse a --ps-format column spec such as "pid,time,etime,comm".
+// pid and time are required, at most one of etime and lstart gives the start
+// time, and comm must come last.
+func parsePSFormat(spec string) ([]string, error) {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, c := range strings.Split(spec, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		switch c {
+		case psColumnPID, psColumnTime, psColumnEtime, psColumnLstart, psColumnComm:
+		default:
+			return nil, fmt.Errorf("unknown column %q", c)
+		}
+		if seen[c] {
+			return nil, fmt.Errorf("column %q given twice", c)
+		}
+		seen[c] = true
+		columns = append(columns, c)
+	}
+	if !seen[psColumnPID] || !seen[psColumnTime] || !seen[psColumnComm] {
+		return nil, fmt.Errorf("pid, time and comm columns are required")
+	}
+	if seen[psColumnEtime] && seen[psColumnLstart] {
+		return nil, fmt.Errorf("etime and lstart cannot be used together")
+	}
+	if columns[len(columns)-1] != psColumnComm {
+		return nil, fmt.Errorf("comm must be the last column")
+	}
+	return columns, nil
+}
+
+// Function to parse ps output with the given columns into processes. Start
+// times come from etime, taken back from now, or from lstart in loc; without
+// either column they are left unset. The output must not have a header.
+func parsePSColumns(r io.Reader, columns []string, now time.Time, loc *time.Location) ([]ProcessInfo, error) {
+	minFields := len(columns)
+	for _, c := range columns {
+		if c == psColumnLstart {
+			minFields += 4
+		}
+	}
+
+	var processList []ProcessInfo
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < minFields {
+			continue
+		}
+		var p ProcessInfo
+		i := 0
+		for _, c := range columns {
+			switch c {
+			case psColumnPID:
+				pid, err := strconv.ParseInt(fields[i], 10, 32)
+				if err != nil {
+					return nil, fmt.Errorf("invalid ps line %q", scanner.Text())
+				}
+				p.PID = int32(pid)
+			case psColumnTime:
+				cpuTime, err := parsePSTime(fields[i])
+				if err != nil {
+					return nil, err
+				}
+				p.CPUTime = cpuTime
+			case psColumnEtime:
+				elapsed, err := parsePSTime(fields[i])
+				if err != nil {
+					return nil, err
+				}
+				p.CreatedAt = now.Add(-time.Duration(elapsed) * time.Second)
+			case psColumnLstart:
+				started, err := time.ParseInLocation(psLstartLayout, strings.Join(fields[i:i+5], " "), loc)
+				if err != nil {
+					return nil, fmt.Errorf("invalid ps start time in %q", scanner.Text())
+				}
+				p.CreatedAt = started
+				i += 4
+			case psColumnComm:
+				p.Name = path.Base(strings.Join(fields[i:], " "))
+			}
+			i++
+		}
+		processList = append(processList, p)
+	}
+	return processList, scanner.Err()
+}
+
+// Function to parse the output of "ps -o pid=,ppid=" into the parent of
+// every process
+func parsePSParents(r io.Reader) (map[int32]int32, error) {
+	parents := make(map[int32]int32)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		pid, err := strconv.ParseInt(fields[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ps line %q", scanner.Text())
+		}
+		ppid, err := strconv.ParseInt(fields[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ps line %q", scanner.Text())
+		}
+		parents[int32(pid)] = int32(ppid)
+	}
+	return parents, scanner.Err()
+}
+
+// Function to parse the output of "ps -o pid=,rss=" into the resident memory
+// of every process in bytes, which ps gives in kilobytes
+func parsePSRSS(r io.Reader) (map[int32]uint64, error) {
+	rss := make(map[int32]uint64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		pid, err := strconv.ParseInt(fields[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ps line %q", scanner.Text())
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ps line %q", scanner.Text())
+		}
+		rss[int32(pid)] = kb * 1024
+	}
+	return rss, scanner.Err()
+}
+
+// Struct to hold the user and command line of a process listed by ps
+type psArgs struct {
+	User string
+	Args string
+}
+
+// Function to parse the output of "ps -o pid=,user=,args=" into the user and
+// command line of every process. The command line takes the rest of the line.
+func parsePSArgs(r io.Reader) (map[int32]psArgs, error) {
+	listed := make(map[int32]psArgs)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		pidField, rest, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		user, args, _ := strings.Cut(strings.TrimSpace(rest), " ")
+		if user == "" {
+			continue
+		}
+		pid, err := strconv.ParseInt(pidField, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ps line %q", scanner.Text())
+		}
+		listed[int32(pid)] = psArgs{User: user, Args: strings.TrimSpace(args)}
+	}
+	return listed, scanner.Err()
+}
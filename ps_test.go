@@ -0,0 +1,153 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePSTime(t *testing.T) {
+	assert := assert.New(t)
+	for s, want := range map[string]float64{
+		"0:00.02":     0.02,
+		"12:34.56":    754.56,
+		"125:03.00":   7503,
+		"1:02:03":     3723,
+		"2-01:00:00":  176400,
+		"00:00:01.50": 1.5,
+	} {
+		got, err := parsePSTime(s)
+		assert.NoError(err, s)
+		assert.InDelta(want, got, 0.001, s)
+	}
+	_, err := parsePSTime("soon")
+	assert.Error(err)
+	_, err = parsePSTime("12")
+	assert.Error(err)
+}
+
+func TestParsePSProcesses(t *testing.T) {
+	assert := assert.New(t)
+	out := "    1 12:34.56 /sbin/launchd\n" +
+		"  412   0:01.25 /Applications/Google Chrome.app/Contents/MacOS/Google Chrome\n" +
+		"  980   0:00.00 (kernel_task)\n"
+	processes, err := parsePSProcesses(strings.NewReader(out))
+	assert.NoError(err)
+	assert.Equal(map[int32]psProcess{
+		1:   {Name: "launchd", CPUTime: 754.56},
+		412: {Name: "Google Chrome", CPUTime: 1.25},
+		980: {Name: "(kernel_task)", CPUTime: 0},
+	}, processes)
+
+	_, err = parsePSProcesses(strings.NewReader("PID TIME COMM\n"))
+	assert.Error(err)
+}
+
+func TestParsePSStartProcesses(t *testing.T) {
+	assert := assert.New(t)
+	out := "    1   0:01.02 Mon Sep  2 10:00:00 2024 /sbin/init\n" +
+		"48211  12:00.50 Tue Sep 10 08:15:30 2024 postgres\n"
+	processes, err := parsePSStartProcesses(strings.NewReader(out), time.UTC)
+	assert.NoError(err)
+	assert.Equal([]ProcessInfo{
+		{PID: 1, Name: "init", CreatedAt: time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC), CPUTime: 1.02},
+		{PID: 48211, Name: "postgres", CreatedAt: time.Date(2024, 9, 10, 8, 15, 30, 0, time.UTC), CPUTime: 720.5},
+	}, processes)
+
+	_, err = parsePSStartProcesses(strings.NewReader("    1   0:01.02 2024-09-02 10:00:00 x y z /sbin/init\n"), time.UTC)
+	assert.Error(err)
+}
+
+func TestParsePSElapsedProcesses(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2024, 9, 10, 12, 0, 0, 0, time.UTC)
+	out := "    1     0:01  3-02:00:00 /sbin/init\n" +
+		"14305    12:00       05:30 /opt/local/sbin/nginx\n"
+	processes, err := parsePSElapsedProcesses(strings.NewReader(out), now)
+	assert.NoError(err)
+	assert.Equal([]ProcessInfo{
+		{PID: 1, Name: "init", CreatedAt: time.Date(2024, 9, 7, 10, 0, 0, 0, time.UTC), CPUTime: 1},
+		{PID: 14305, Name: "nginx", CreatedAt: time.Date(2024, 9, 10, 11, 54, 30, 0, time.UTC), CPUTime: 720},
+	}, processes)
+
+	_, err = parsePSElapsedProcesses(strings.NewReader("    1     0:01 yesterday /sbin/init\n"), now)
+	assert.Error(err)
+}
+
+func TestParsePSFormat(t *testing.T) {
+	assert := assert.New(t)
+	columns, err := parsePSFormat("pid, TIME,lstart,comm")
+	assert.NoError(err)
+	assert.Equal([]string{"pid", "time", "lstart", "comm"}, columns)
+
+	for _, spec := range []string{
+		"pid,time",
+		"pid,time,comm,etime",
+		"pid,time,etime,lstart,comm",
+		"pid,pid,time,comm",
+		"pid,time,rss,comm",
+	} {
+		_, err := parsePSFormat(spec)
+		assert.Error(err, spec)
+	}
+}
+
+func TestParsePSColumns(t *testing.T) {
+	assert := assert.New(t)
+	columns := []string{"time", "pid", "comm"}
+	processes, err := parsePSColumns(strings.NewReader("01:02:03     7 /usr/sbin/sshd -D\n"), columns, time.Time{}, time.UTC)
+	assert.NoError(err)
+	assert.Equal([]ProcessInfo{{PID: 7, Name: "sshd -D", CPUTime: 3723}}, processes)
+
+	_, err = parsePSColumns(strings.NewReader("TIME PID CMD\n"), columns, time.Time{}, time.UTC)
+	assert.Error(err)
+}
+
+func TestParsePSParents(t *testing.T) {
+	assert := assert.New(t)
+	parents, err := parsePSParents(strings.NewReader("    1     0\n  412     1\n  413   412\n"))
+	assert.NoError(err)
+	assert.Equal(map[int32]int32{1: 0, 412: 1, 413: 412}, parents)
+}
+
+func TestParsePSRSS(t *testing.T) {
+	assert := assert.New(t)
+	rss, err := parsePSRSS(strings.NewReader("    1   512\n  412 20480\n"))
+	assert.NoError(err)
+	assert.Equal(map[int32]uint64{1: 512 * 1024, 412: 20480 * 1024}, rss)
+
+	_, err = parsePSRSS(strings.NewReader("412 lots\n"))
+	assert.Error(err)
+}
+
+func TestParsePSArgs(t *testing.T) {
+	assert := assert.New(t)
+	listed, err := parsePSArgs(strings.NewReader("    1 root     /sbin/init\n  412 www      /usr/local/bin/java -Xmx2g -jar \"my  app.jar\"\n  413 _pflogd\n"))
+	assert.NoError(err)
+	assert.Equal(map[int32]psArgs{
+		1:   {User: "root", Args: "/sbin/init"},
+		412: {User: "www", Args: `/usr/local/bin/java -Xmx2g -jar "my  app.jar"`},
+		413: {User: "_pflogd"},
+	}, listed)
+
+	_, err = parsePSArgs(strings.NewReader("init root /sbin/init\n"))
+	assert.Error(err)
+}
+
+func TestRunCommandTimeout(t *testing.T) {
+	assert := assert.New(t)
+	defer func(d time.Duration) { plugin.execTimeout = d }(plugin.execTimeout)
+	plugin.execTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	_, err := runCommand("sleep", "5")
+	assert.Error(err)
+	assert.Contains(err.Error(), "did not finish within 100ms")
+	assert.Less(time.Since(start), 2*time.Second)
+
+	out, err := runCommand("echo", "ok")
+	assert.NoError(err)
+	assert.NotNil(out)
+}
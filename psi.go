@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Resources exposing pressure stall information
+var psiResources = []string{"cpu", "io", "memory"}
+
+// Struct to hold the averages of one pressure line
+type PSILine struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+}
+
+// Struct to hold the pressure stall information of a resource. Full is nil
+// when the kernel does not report it, as for cpu before Linux 5.13.
+type PSI struct {
+	Some PSILine
+	Full *PSILine
+}
+
+// Function to parse the contents of a /proc/pressure file
+func parsePSI(r io.Reader) (PSI, error) {
+	var psi PSI
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var line PSILine
+		for _, f := range fields[1:] {
+			kv := strings.SplitN(f, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			var dst *float64
+			switch kv[0] {
+			case "avg10":
+				dst = &line.Avg10
+			case "avg60":
+				dst = &line.Avg60
+			case "avg300":
+				dst = &line.Avg300
+			default:
+				continue
+			}
+			v, err := strconv.ParseFloat(kv[1], 64)
+			if err != nil {
+				return psi, err
+			}
+			*dst = v
+		}
+
+		switch fields[0] {
+		case "some":
+			psi.Some = line
+		case "full":
+			psi.Full = &line
+		}
+	}
+	return psi, scanner.Err()
+}
+
+// Function to validate the list of resources given to --psi
+func validatePSIResources(resources []string) error {
+	for _, r := range resources {
+		valid := false
+		for _, known := range psiResources {
+			if r == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unknown resource %q, expected one of %s", r, strings.Join(psiResources, ", "))
+		}
+	}
+	return nil
+}
+
+// Function to list the pressure metrics of a resource
+func psiMetrics(resource string, psi PSI) []Metric {
+	metrics := []Metric{
+		{"psi_" + resource + "_some_avg10", psi.Some.Avg10},
+		{"psi_" + resource + "_some_avg60", psi.Some.Avg60},
+	}
+	if psi.Full != nil {
+		metrics = append(metrics,
+			Metric{"psi_" + resource + "_full_avg10", psi.Full.Avg10},
+			Metric{"psi_" + resource + "_full_avg60", psi.Full.Avg60},
+		)
+	}
+	return metrics
+}
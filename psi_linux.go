@@ -0,0 +1,13 @@
+package main
+
+import "os"
+
+// Function to read the pressure stall information of a resource
+func readPSI(resource string) (PSI, error) {
+	f, err := os.Open(hostProc("pressure", resource))
+	if err != nil {
+		return PSI{}, err
+	}
+	defer f.Close()
+	return parsePSI(f)
+}
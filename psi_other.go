@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// Function to read the pressure stall information of a resource
+func readPSI(resource string) (PSI, error) {
+	return PSI{}, errUnsupported
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePSI(t *testing.T) {
+	assert := assert.New(t)
+	psi, err := parsePSI(strings.NewReader("some avg10=1.50 avg60=0.75 avg300=0.10 total=123456\nfull avg10=0.50 avg60=0.25 avg300=0.00 total=6543\n"))
+	assert.NoError(err)
+	assert.Equal(PSILine{1.5, 0.75, 0.1}, psi.Some)
+	assert.Equal(&PSILine{0.5, 0.25, 0}, psi.Full)
+	assert.Equal([]Metric{
+		{"psi_io_some_avg10", 1.5},
+		{"psi_io_some_avg60", 0.75},
+		{"psi_io_full_avg10", 0.5},
+		{"psi_io_full_avg60", 0.25},
+	}, psiMetrics("io", psi))
+
+	psi, err = parsePSI(strings.NewReader("some avg10=2.00 avg60=1.00 avg300=0.50 total=1\n"))
+	assert.NoError(err)
+	assert.Nil(psi.Full)
+	assert.Len(psiMetrics("cpu", psi), 2)
+}
+
+func TestValidatePSIResources(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError(validatePSIResources([]string{"cpu", "memory"}))
+	assert.Error(validatePSIResources([]string{"cpu", "disk"}))
+}
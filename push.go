@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+// Struct to hold a captured profile of a process to push to a continuous
+// profiling server
+type CapturedProfile struct {
+	File  string
+	PID   int32
+	Name  string
+	From  time.Time
+	Until time.Time
+}
+
+// Function to get the labels a profile is pushed with: the host, the process
+// and its PID, along with the --metric-tag tags
+func (p CapturedProfile) labels() []MetricTag {
+	return mergeMetricTags(plugin.metricTags, []MetricTag{
+		{"host", plugin.hostname},
+		{"process", p.Name},
+		{"pid", strconv.Itoa(int(p.PID))},
+	})
+}
+
+// Function to parse folded stacks as written by writeFoldedStacks
+func parseFoldedStacks(r io.Reader) (map[string]int, error) {
+	stacks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			continue
+		}
+		count, err := strconv.Atoi(line[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid folded stack line %q", line)
+		}
+		stacks[line[:i]] += count
+	}
+	return stacks, scanner.Err()
+}
+
+// Function to convert folded stacks sampled at the given frequency into a
+// gzipped pprof CPU profile, for servers that only take pprof
+func foldedToPprof(stacks map[string]int, frequency int, start time.Time, d time.Duration) ([]byte, error) {
+	period := int64(time.Second) / int64(frequency)
+	p := &profile.Profile{
+		SampleType:    []*profile.ValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}},
+		PeriodType:    &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:        period,
+		TimeNanos:     start.UnixNano(),
+		DurationNanos: d.Nanoseconds(),
+	}
+	functions := make(map[string]*profile.Location)
+	keys := make([]string, 0, len(stacks))
+	for k := range stacks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		frames := strings.Split(k, ";")
+		sample := &profile.Sample{Value: []int64{int64(stacks[k]), int64(stacks[k]) * period}}
+		// pprof lists the innermost frame first
+		for i := len(frames) - 1; i >= 0; i-- {
+			loc, ok := functions[frames[i]]
+			if !ok {
+				id := uint64(len(functions) + 1)
+				fn := &profile.Function{ID: id, Name: frames[i]}
+				loc = &profile.Location{ID: id, Line: []profile.Line{{Function: fn}}}
+				p.Function = append(p.Function, fn)
+				p.Location = append(p.Location, loc)
+				functions[frames[i]] = loc
+			}
+			sample.Location = append(sample.Location, loc)
+		}
+		p.Sample = append(p.Sample, sample)
+	}
+	var b bytes.Buffer
+	if err := p.Write(&b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Function to push a captured profile to the continuous profiling servers
+// configured
+func pushProfile(p CapturedProfile) error {
+	data, err := os.ReadFile(p.File)
+	if err != nil {
+		return err
+	}
+	folded := filepath.Ext(p.File) == ".folded"
+	if plugin.PyroscopeURL != "" {
+		if err := pushPyroscope(plugin.PyroscopeURL, p, data, folded); err != nil {
+			return fmt.Errorf("Pyroscope: %v", err)
+		}
+	}
+	if plugin.ParcaURL != "" {
+		if folded {
+			stacks, err := parseFoldedStacks(bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+			if data, err = foldedToPprof(stacks, captureFrequency, p.From, p.Until.Sub(p.From)); err != nil {
+				return err
+			}
+		}
+		if err := pushParca(plugin.ParcaURL, p, data); err != nil {
+			return fmt.Errorf("Parca: %v", err)
+		}
+	}
+	return nil
+}
+
+// Function to get the application name of a profile for Pyroscope, the
+// process name with the labels in braces. Characters the name syntax uses
+// are replaced.
+func pyroscopeName(p CapturedProfile) string {
+	clean := strings.NewReplacer("{", "_", "}", "_", ",", "_", "=", "_", " ", "_").Replace
+	var labels []string
+	for _, t := range p.labels() {
+		labels = append(labels, clean(t.Name)+"="+clean(t.Value))
+	}
+	return fmt.Sprintf("%s.cpu{%s}", clean(p.Name), strings.Join(labels, ","))
+}
+
+// Function to push a profile to the ingest API of a Pyroscope server, folded
+// stacks as they are and pprof profiles as a form upload
+func pushPyroscope(base string, p CapturedProfile, data []byte, folded bool) error {
+	q := url.Values{}
+	q.Set("name", pyroscopeName(p))
+	q.Set("from", strconv.FormatInt(p.From.Unix(), 10))
+	q.Set("until", strconv.FormatInt(p.Until.Unix(), 10))
+	q.Set("spyName", "cpu-process-profiler")
+
+	var body bytes.Buffer
+	contentType := "text/plain"
+	if folded {
+		q.Set("format", "folded")
+		q.Set("sampleRate", strconv.Itoa(captureFrequency))
+		q.Set("units", "samples")
+		q.Set("aggregationType", "sum")
+		body.Write(data)
+	} else {
+		q.Set("format", "pprof")
+		w := multipart.NewWriter(&body)
+		part, err := w.CreateFormFile("profile", "profile.pprof")
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(data); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		contentType = w.FormDataContentType()
+	}
+	return postProfile(strings.TrimSuffix(base, "/")+"/ingest?"+q.Encode(), contentType, &body)
+}
+
+// Structs to hold a write request of the Parca profile store API, in the JSON
+// form of its HTTP gateway
+type parcaLabel struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type parcaLabelSet struct {
+	Labels []parcaLabel `json:"labels"`
+}
+
+type parcaSample struct {
+	RawProfile []byte `json:"rawProfile"`
+}
+
+type parcaSeries struct {
+	Labels  parcaLabelSet `json:"labels"`
+	Samples []parcaSample `json:"samples"`
+}
+
+// Function to push a pprof profile to the profile store API of a Parca server
+func pushParca(base string, p CapturedProfile, data []byte) error {
+	var series parcaSeries
+	series.Labels.Labels = []parcaLabel{{"__name__", "process_cpu"}}
+	for _, t := range p.labels() {
+		series.Labels.Labels = append(series.Labels.Labels, parcaLabel{t.Name, t.Value})
+	}
+	series.Samples = []parcaSample{{RawProfile: data}}
+	body, err := json.Marshal(map[string][]parcaSeries{"series": {series}})
+	if err != nil {
+		return err
+	}
+	return postProfile(strings.TrimSuffix(base, "/")+"/profiles/writeraw", "application/json", bytes.NewReader(body))
+}
+
+// Function to post a profile to a server, which must answer with success
+func postProfile(url, contentType string, body io.Reader) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+	return nil
+}
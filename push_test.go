@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCapturedProfile(file string) CapturedProfile {
+	until := time.Date(2026, 9, 1, 12, 0, 5, 0, time.UTC)
+	return CapturedProfile{File: file, PID: 42, Name: "app", From: until.Add(-5 * time.Second), Until: until}
+}
+
+func TestFoldedToPprof(t *testing.T) {
+	assert := assert.New(t)
+	stacks, err := parseFoldedStacks(strings.NewReader("app;main;work 3\napp;main 1\n"))
+	assert.NoError(err)
+	assert.Equal(map[string]int{"app;main;work": 3, "app;main": 1}, stacks)
+	_, err = parseFoldedStacks(strings.NewReader("app;main many\n"))
+	assert.Error(err)
+
+	data, err := foldedToPprof(stacks, 100, time.Unix(1756728000, 0), 5*time.Second)
+	assert.NoError(err)
+	p, err := profile.Parse(bytes.NewReader(data))
+	assert.NoError(err)
+	assert.Equal(int64(10000000), p.Period)
+	assert.Len(p.Sample, 2)
+	assert.Len(p.Function, 3)
+	// app;main first, leaf first
+	assert.Equal([]int64{1, 10000000}, p.Sample[0].Value)
+	assert.Equal("main", p.Sample[0].Location[0].Line[0].Function.Name)
+	assert.Equal("work", p.Sample[1].Location[0].Line[0].Function.Name)
+	assert.Equal("app", p.Sample[1].Location[2].Line[0].Function.Name)
+}
+
+func TestPushPyroscope(t *testing.T) {
+	assert := assert.New(t)
+	plugin.hostname = "web01"
+	plugin.metricTags = []MetricTag{{"env", "prod"}}
+	defer func() { plugin.hostname, plugin.metricTags = "", nil }()
+
+	var query, contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ingest" {
+			http.NotFound(w, r)
+			return
+		}
+		query, contentType = r.URL.Query().Encode(), r.Header.Get("Content-Type")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	p := testCapturedProfile("app.folded")
+	assert.Equal("app.cpu{env=prod,host=web01,pid=42,process=app}", pyroscopeName(p))
+	assert.NoError(pushPyroscope(server.URL+"/", p, []byte("app;main 1\n"), true))
+	assert.Contains(query, "format=folded")
+	assert.Contains(query, "from=1788264000")
+	assert.Contains(query, "until=1788264005")
+	assert.Contains(query, "sampleRate=99")
+	assert.Equal("text/plain", contentType)
+	assert.Equal("app;main 1\n", body)
+
+	assert.NoError(pushPyroscope(server.URL, p, []byte("pprof"), false))
+	assert.Contains(query, "format=pprof")
+	assert.True(strings.HasPrefix(contentType, "multipart/form-data"))
+	assert.Contains(body, `name="profile"`)
+
+	assert.Error(pushPyroscope(server.URL+"/missing", p, nil, true))
+}
+
+func TestPushParca(t *testing.T) {
+	assert := assert.New(t)
+	plugin.hostname = "web01"
+	defer func() { plugin.hostname = "" }()
+
+	var path string
+	var request map[string][]parcaSeries
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		assert.NoError(json.NewDecoder(r.Body).Decode(&request))
+	}))
+	defer server.Close()
+
+	assert.NoError(pushParca(server.URL, testCapturedProfile("app.pprof"), []byte("pprof")))
+	assert.Equal("/profiles/writeraw", path)
+	assert.Len(request["series"], 1)
+	series := request["series"][0]
+	assert.Equal([]parcaLabel{
+		{"__name__", "process_cpu"},
+		{"host", "web01"},
+		{"pid", "42"},
+		{"process", "app"},
+	}, series.Labels.Labels)
+	assert.Equal([]parcaSample{{RawProfile: []byte("pprof")}}, series.Samples)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Struct to hold one reading of a RAPL energy counter. Domain is "package"
+// for the counter of a whole package and the zone name, such as "core",
+// "uncore" or "dram", for the ones within it. The counter wraps around at
+// MaxUJ.
+type RAPLZone struct {
+	ID       string
+	Package  int
+	Domain   string
+	EnergyUJ float64
+	MaxUJ    float64
+}
+
+// Function to get the energy used between two readings of a counter in
+// microjoules, allowing for it wrapping around once
+func raplEnergy(start, end RAPLZone) float64 {
+	if end.EnergyUJ >= start.EnergyUJ {
+		return end.EnergyUJ - start.EnergyUJ
+	}
+	return end.MaxUJ - start.EnergyUJ + end.EnergyUJ
+}
+
+// Function to compute the average power of every RAPL zone read at both ends
+// of the interval in watts, along with the total of the packages
+func raplMetrics(start, end []RAPLZone, elapsed time.Duration) []Metric {
+	if elapsed <= 0 {
+		return nil
+	}
+	ends := make(map[string]RAPLZone, len(end))
+	for _, z := range end {
+		ends[z.ID] = z
+	}
+	var metrics []Metric
+	var total float64
+	packages := 0
+	for _, s := range start {
+		e, ok := ends[s.ID]
+		if !ok {
+			continue
+		}
+		watts := raplEnergy(s, e) / 1e6 / elapsed.Seconds()
+		name := fmt.Sprintf("cpu_power_package%d_watts", s.Package)
+		if s.Domain == "package" {
+			total += watts
+			packages++
+		} else {
+			name = fmt.Sprintf("cpu_power_package%d_%s_watts", s.Package, s.Domain)
+		}
+		metrics = append(metrics, Metric{name, watts})
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+	if packages > 0 {
+		metrics = append(metrics, Metric{"cpu_power_watts", total})
+	}
+	return metrics
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Function to read the RAPL energy counters of every package zone in powercap
+// and of the zones within them. Other top level zones, such as the platform
+// one (psys), are left out as they are not part of a CPU. The counters are
+// only readable by root on recent kernels.
+func readRAPL() ([]RAPLZone, error) {
+	dirs, err := filepath.Glob(hostSys("class", "powercap", "intel-rapl:*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+	packages := map[string]int{}
+	var zones []RAPLZone
+	for _, dir := range dirs {
+		id := filepath.Base(dir)
+		name, err := os.ReadFile(filepath.Join(dir, "name"))
+		if err != nil {
+			return nil, err
+		}
+		zone := RAPLZone{ID: id, Domain: strings.TrimSpace(string(name))}
+		if strings.Count(id, ":") == 1 {
+			if _, err := fmt.Sscanf(zone.Domain, "package-%d", &zone.Package); err != nil {
+				continue
+			}
+			packages[id] = zone.Package
+			zone.Domain = "package"
+		} else {
+			pkg, ok := packages[id[:strings.LastIndex(id, ":")]]
+			if !ok {
+				continue
+			}
+			zone.Package = pkg
+		}
+		if zone.EnergyUJ, err = readMicrojoules(filepath.Join(dir, "energy_uj")); err != nil {
+			return nil, err
+		}
+		if zone.MaxUJ, err = readMicrojoules(filepath.Join(dir, "max_energy_range_uj")); err != nil {
+			return nil, err
+		}
+		zones = append(zones, zone)
+	}
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("no RAPL package zones in %s", hostSys("class", "powercap"))
+	}
+	return zones, nil
+}
+
+// Function to read an energy counter in microjoules from a powercap file
+func readMicrojoules(file string) (float64, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+}
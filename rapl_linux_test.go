@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadRAPL(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	t.Setenv("HOST_SYS", root)
+	write := func(zone, name, energy string) {
+		dir := filepath.Join(root, "class", "powercap", zone)
+		assert.NoError(os.MkdirAll(dir, 0755))
+		assert.NoError(os.WriteFile(filepath.Join(dir, "name"), []byte(name+"\n"), 0644))
+		assert.NoError(os.WriteFile(filepath.Join(dir, "energy_uj"), []byte(energy+"\n"), 0644))
+		assert.NoError(os.WriteFile(filepath.Join(dir, "max_energy_range_uj"), []byte("262143328850\n"), 0644))
+	}
+
+	_, err := readRAPL()
+	assert.Error(err)
+
+	write("intel-rapl:0", "package-0", "1000")
+	write("intel-rapl:0:0", "core", "400")
+	write("intel-rapl:0:1", "dram", "200")
+	write("intel-rapl:1", "psys", "5000")
+	write("intel-rapl:1:0", "core", "300")
+	zones, err := readRAPL()
+	assert.NoError(err)
+	assert.Equal([]RAPLZone{
+		{ID: "intel-rapl:0", Package: 0, Domain: "package", EnergyUJ: 1000, MaxUJ: 262143328850},
+		{ID: "intel-rapl:0:0", Package: 0, Domain: "core", EnergyUJ: 400, MaxUJ: 262143328850},
+		{ID: "intel-rapl:0:1", Package: 0, Domain: "dram", EnergyUJ: 200, MaxUJ: 262143328850},
+	}, zones)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// Function to read the RAPL energy counters of every CPU package
+func readRAPL() ([]RAPLZone, error) {
+	return nil, errUnsupported
+}
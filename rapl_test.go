@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRAPLMetrics(t *testing.T) {
+	assert := assert.New(t)
+	start := []RAPLZone{
+		{ID: "intel-rapl:0", Package: 0, Domain: "package", EnergyUJ: 1000000, MaxUJ: 262143328850},
+		{ID: "intel-rapl:0:0", Package: 0, Domain: "core", EnergyUJ: 500000, MaxUJ: 262143328850},
+		{ID: "intel-rapl:1", Package: 1, Domain: "package", EnergyUJ: 262142328850, MaxUJ: 262143328850},
+		{ID: "intel-rapl:1:0", Package: 1, Domain: "core", EnergyUJ: 0, MaxUJ: 262143328850},
+	}
+	// The core zone of package 1 went away, the counter of package 1 wrapped
+	end := []RAPLZone{
+		{ID: "intel-rapl:0", Package: 0, Domain: "package", EnergyUJ: 31000000, MaxUJ: 262143328850},
+		{ID: "intel-rapl:0:0", Package: 0, Domain: "core", EnergyUJ: 20500000, MaxUJ: 262143328850},
+		{ID: "intel-rapl:1", Package: 1, Domain: "package", EnergyUJ: 19000000, MaxUJ: 262143328850},
+	}
+	metrics := raplMetrics(start, end, 2*time.Second)
+	assert.Equal([]Metric{
+		{"cpu_power_package0_core_watts", 10},
+		{"cpu_power_package0_watts", 15},
+		{"cpu_power_package1_watts", 10},
+		{"cpu_power_watts", 25},
+	}, metrics)
+	assert.Nil(raplMetrics(start, end, 0))
+	assert.Nil(raplMetrics(nil, nil, time.Second))
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/sensu/sensu-go/types"
+)
+
+// Struct to hold the options of the recommend subcommand
+type RecommendConfig struct {
+	Since      string
+	Headroom   float64
+	MinSamples int
+
+	// Parsed form of Since, set by recommendArgs
+	sinceDuration time.Duration
+}
+
+// Length of the CFS period cpu.max quotas are given for, in microseconds
+const cfsPeriod = 100000
+
+var (
+	recommend = RecommendConfig{}
+
+	recommendOptions = []*sensu.PluginConfigOption{
+		{
+			Path:     "since",
+			Argument: "since",
+			Default:  "168h",
+			Usage:    "Base the recommendations on the results of --history-file or --history-db recorded within this long",
+			Value:    &recommend.Since,
+		},
+		{
+			Path:     "headroom",
+			Argument: "headroom",
+			Default:  float64(20),
+			Usage:    "Percentage added to the highest usage seen to get the recommended limit",
+			Value:    &recommend.Headroom,
+		},
+		{
+			Path:     "min-samples",
+			Argument: "min-samples",
+			Default:  10,
+			Usage:    "Number of results a process must be listed in to get a recommendation",
+			Value:    &recommend.MinSamples,
+		},
+	}
+)
+
+// Struct to hold the CPU usage seen for a process name in the recorded results,
+// as a percentage of one core, along with the request and limit recommended
+// for it in millicores
+type Recommendation struct {
+	Name    string
+	Samples int
+	Stats   SampleStats
+	Request int
+	Limit   int
+}
+
+// Function to recommend a CPU request and limit for every process name listed
+// in at least minSamples results: the request covers its p95 usage and the
+// limit its highest usage plus the headroom percentage. The busiest processes
+// come first.
+func recommendCPU(usage map[string][]float64, minSamples int, headroom float64) []Recommendation {
+	var recs []Recommendation
+	for name, values := range usage {
+		if len(values) < minSamples {
+			continue
+		}
+		stats := sampleStats(values)
+		recs = append(recs, Recommendation{
+			Name:    name,
+			Samples: len(values),
+			Stats:   stats,
+			Request: millicores(stats.P95),
+			Limit:   millicores(stats.Max * (1 + headroom/100)),
+		})
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Stats.P95 != recs[j].Stats.P95 {
+			return recs[i].Stats.P95 > recs[j].Stats.P95
+		}
+		return recs[i].Name < recs[j].Name
+	})
+	return recs
+}
+
+// Function to convert a percentage of one core into millicores, rounded up
+// and at least 1
+func millicores(pct float64) int {
+	return max(int(math.Ceil(pct*10)), 1)
+}
+
+// Function to format the recommendations as a table, with the limit given
+// both as a Kubernetes quantity and as a cgroup v2 cpu.max quota and period
+func formatRecommendations(recs []Recommendation) string {
+	rows := make([][]string, len(recs))
+	for i, r := range recs {
+		rows[i] = []string{
+			r.Name,
+			fmt.Sprint(r.Samples),
+			fmt.Sprintf("%.2f%%", r.Stats.Avg),
+			fmt.Sprintf("%.2f%%", r.Stats.P95),
+			fmt.Sprintf("%.2f%%", r.Stats.Max),
+			fmt.Sprintf("%dm", r.Request),
+			fmt.Sprintf("%dm", r.Limit),
+			fmt.Sprintf("%d %d", r.Limit*cfsPeriod/1000, cfsPeriod),
+		}
+	}
+	return formatTable([]string{"NAME", "SAMPLES", "AVG", "P95", "MAX", "REQUEST", "LIMIT", "CPU.MAX"}, rows)
+}
+
+// Function to get the CPU usage of every process name listed in a list of
+// records, summed over the processes sharing the name in each record
+func recordProcessUsage(records []HistoryRecord) map[string][]float64 {
+	usage := make(map[string][]float64)
+	for _, r := range records {
+		sums := make(map[string]float64)
+		for _, p := range r.Processes {
+			sums[p.Name] += p.CPU
+		}
+		for name, cpu := range sums {
+			usage[name] = append(usage[name], cpu)
+		}
+	}
+	return usage
+}
+
+// Function to validate the arguments of the recommend subcommand, which only
+// reads the recorded results and takes no thresholds
+func recommendArgs(event *types.Event) (int, error) {
+	warnDeprecatedAnnotations(event)
+	if status, err := historySourceArgs(); err != nil {
+		return status, err
+	}
+	since, err := time.ParseDuration(recommend.Since)
+	if err != nil {
+		return sensu.CheckStateWarning, fmt.Errorf("--since: %v", err)
+	}
+	if since <= 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--since must be positive")
+	}
+	recommend.sinceDuration = since
+	if recommend.Headroom < 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--headroom cannot be negative")
+	}
+	if recommend.MinSamples < 1 {
+		return sensu.CheckStateWarning, fmt.Errorf("--min-samples must be at least 1")
+	}
+	return sensu.CheckStateOK, nil
+}
+
+// Function to print CPU request and limit recommendations for the processes
+// of the recorded results
+func executeRecommend(event *types.Event) (int, error) {
+	now := time.Now()
+	records, err := readHistory(now.Add(-recommend.sinceDuration), now)
+	if err != nil {
+		return sensu.CheckStateCritical, err
+	}
+	recs := recommendCPU(recordProcessUsage(records), recommend.MinSamples, recommend.Headroom)
+	if len(recs) == 0 {
+		fmt.Printf("%s OK: no process listed in at least %d of %d results in the last %s\n", plugin.PluginConfig.Name, recommend.MinSamples, len(records), recommend.sinceDuration)
+		return sensu.CheckStateOK, nil
+	}
+	fmt.Printf("%s OK: CPU recommendations from %d results in the last %s\n\n", plugin.PluginConfig.Name, len(records), recommend.sinceDuration)
+	fmt.Print(formatRecommendations(recs))
+	return sensu.CheckStateOK, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecommendCPU(t *testing.T) {
+	assert := assert.New(t)
+	usage := map[string][]float64{
+		"java":  {100, 120, 150, 110},
+		"nginx": {10, 12.5, 11, 9},
+		"cron":  {1},
+	}
+	recs := recommendCPU(usage, 2, 20)
+	assert.Len(recs, 2)
+	assert.Equal("java", recs[0].Name)
+	assert.Equal(4, recs[0].Samples)
+	assert.Equal(1500, recs[0].Request)
+	assert.Equal(1800, recs[0].Limit)
+	assert.Equal("nginx", recs[1].Name)
+	assert.Equal(125, recs[1].Request)
+	assert.Equal(150, recs[1].Limit)
+
+	assert.Empty(recommendCPU(usage, 5, 20))
+	assert.Equal(1, millicores(0))
+	assert.Equal(1, millicores(0.01))
+	assert.Equal(3, millicores(0.25))
+}
+
+func TestFormatRecommendations(t *testing.T) {
+	out := formatRecommendations(recommendCPU(map[string][]float64{"java": {100, 150}}, 1, 20))
+	assert.Equal(t, "NAME  SAMPLES  AVG      P95      MAX      REQUEST  LIMIT  CPU.MAX\n"+
+		"java  2        125.00%  150.00%  150.00%  1500m    1800m  180000 100000\n", out)
+}
+
+func TestRecordProcessUsage(t *testing.T) {
+	records := []HistoryRecord{
+		{Processes: []HistoryProcess{{PID: 7, Name: "cron", CPU: 1}}},
+		{Processes: []HistoryProcess{{PID: 42, Name: "java", CPU: 150}, {PID: 43, Name: "nginx", CPU: 10}, {PID: 44, Name: "nginx", CPU: 2.5}}},
+		{Processes: []HistoryProcess{{PID: 42, Name: "java", CPU: 120}}},
+	}
+	assert.Equal(t, map[string][]float64{"cron": {1}, "java": {150, 120}, "nginx": {12.5}}, recordProcessUsage(records))
+}
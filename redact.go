@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Text secrets in command lines are masked with
+const redacted = "***"
+
+// Patterns --redact-args masks in command lines: the values of password,
+// secret, token and key options and assignments, credentials in URLs and
+// authorization headers. The first group of each pattern is masked.
+var defaultRedactPatterns = []string{
+	`(?i)-{1,2}[\w-]*(?:password|passwd|pwd|secret|token|api[-_]?key|access[-_]?key|credentials?)[\w-]*[= ]+(\S+)`,
+	`(?i)[\w.-]*(?:password|passwd|pwd|secret|token|api[-_]?key|access[-_]?key)[\w.-]*=(\S+)`,
+	`://[^:/@\s]+:([^@/\s]+)@`,
+	`(?i)authorization:\s*(?:bearer|basic)\s+(\S+)`,
+}
+
+// Function to compile the patterns secrets are masked with, the default ones
+// if asked for followed by the given ones
+func parseRedactPatterns(defaults bool, patterns []string) ([]*regexp.Regexp, error) {
+	if defaults {
+		patterns = append(append([]string(nil), defaultRedactPatterns...), patterns...)
+	}
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a regular expression: %v", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Function to mask the secrets in a command line. The text matched by the
+// first group of a pattern is masked, or the whole match for a pattern
+// without groups.
+func redact(s string, patterns []*regexp.Regexp) string {
+	for _, re := range patterns {
+		var b strings.Builder
+		last := 0
+		for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+			start, end := m[0], m[1]
+			if len(m) > 2 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(redacted)
+			last = end
+		}
+		b.WriteString(s[last:])
+		s = b.String()
+	}
+	return s
+}
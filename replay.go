@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/sensu/sensu-go/types"
+)
+
+// Struct to hold a recorded result along with the status the
+// current thresholds give it
+type ReplayedRecord struct {
+	HistoryRecord
+	Replayed int
+}
+
+// Function to evaluate the overall CPU thresholds, threshold profiles and
+// breach count against recorded results, oldest first. Only the used CPU
+// percentage is recorded, so the other thresholds of a run are not replayed.
+func replayRecords(records []HistoryRecord, profiles []ThresholdProfile, warning, critical float64, breachCount int) []ReplayedRecord {
+	var state State
+	replayed := make([]ReplayedRecord, len(records))
+	for i, r := range records {
+		var eval Evaluation
+		w, c, _ := profileThresholds(profiles, r.Timestamp, warning, critical)
+		if r.Usage.Used > c {
+			eval.breach("cpu_critical", sensu.CheckStateCritical)
+		} else if r.Usage.Used > w {
+			eval.breach("cpu_warning", sensu.CheckStateWarning)
+		}
+		if breachCount > 1 {
+			eval.dampen(&state, breachCount)
+		}
+		replayed[i] = ReplayedRecord{r, eval.Status}
+	}
+	return replayed
+}
+
+// Function to format the replayed records that alerted either when recorded
+// or when replayed as a table
+func formatReplayedRecords(replayed []ReplayedRecord) string {
+	var rows [][]string
+	for _, r := range replayed {
+		if r.Status == sensu.CheckStateOK && r.Replayed == sensu.CheckStateOK {
+			continue
+		}
+		rows = append(rows, []string{
+			r.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			fmt.Sprintf("%.2f%%", r.Usage.Used),
+			stateLabel(r.Status),
+			stateLabel(r.Replayed),
+			formatHistoryTop(r.HistoryRecord),
+		})
+	}
+	return formatTable([]string{"TIME", "USED", "RECORDED", "REPLAYED", "TOP"}, rows)
+}
+
+// Function to count the records of each status, recorded and replayed
+func countReplayed(replayed []ReplayedRecord) (map[int]int, map[int]int) {
+	recorded, now := make(map[int]int), make(map[int]int)
+	for _, r := range replayed {
+		recorded[r.Status]++
+		now[r.Replayed]++
+	}
+	return recorded, now
+}
+
+// Function to validate the arguments of the replay subcommand: the
+// thresholds, as for a check, and the time range of the records
+func replayArgs(event *types.Event) (int, error) {
+	if status, err := checkArgs(event); err != nil {
+		return status, err
+	}
+	return historyRangeArgs(time.Now())
+}
+
+// Function to replay the recorded results in the time range
+// against the current thresholds, to see how a change of thresholds would
+// have alerted
+func executeReplay(event *types.Event) (int, error) {
+	records, err := readHistory(historyRange.from, historyRange.to)
+	if err != nil {
+		return sensu.CheckStateCritical, err
+	}
+	replayed := replayRecords(records, plugin.thresholdProfiles, plugin.Warning, plugin.Critical, plugin.BreachCount)
+	recorded, now := countReplayed(replayed)
+	fmt.Printf("%s OK: replayed %d results from %s to %s, %d Warning and %d Critical against %d and %d recorded\n", plugin.PluginConfig.Name, len(records),
+		historyRange.from.Format(time.RFC3339), historyRange.to.Format(time.RFC3339),
+		now[sensu.CheckStateWarning], now[sensu.CheckStateCritical], recorded[sensu.CheckStateWarning], recorded[sensu.CheckStateCritical])
+	if table := formatReplayedRecords(replayed); table != "" {
+		fmt.Printf("\n%s", table)
+	}
+	return sensu.CheckStateOK, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayRecords(t *testing.T) {
+	assert := assert.New(t)
+	start := time.Date(2024, 5, 2, 2, 0, 0, 0, time.Local)
+	var records []HistoryRecord
+	for i, used := range []float64{50, 80, 95, 96, 40, 80} {
+		records = append(records, HistoryRecord{Timestamp: start.Add(time.Duration(i) * time.Hour), Usage: CPUUsage{Used: used}})
+	}
+	records[2].Status = sensu.CheckStateCritical
+
+	var statuses []int
+	for _, r := range replayRecords(records, nil, 75, 90, 1) {
+		statuses = append(statuses, r.Replayed)
+	}
+	assert.Equal([]int{0, 1, 2, 2, 0, 1}, statuses)
+
+	// A profile raising the thresholds from 03:00 to 05:00
+	profiles, err := parseThresholdProfiles([]string{"03:00-05:00=warn:85,crit:99"})
+	assert.NoError(err)
+	statuses = nil
+	for _, r := range replayRecords(records, profiles, 75, 90, 1) {
+		statuses = append(statuses, r.Replayed)
+	}
+	assert.Equal([]int{0, 0, 1, 2, 0, 1}, statuses)
+
+	// Two breaches in a row before alerting
+	statuses = nil
+	for _, r := range replayRecords(records, nil, 75, 90, 2) {
+		statuses = append(statuses, r.Replayed)
+	}
+	assert.Equal([]int{0, 0, 2, 2, 0, 0}, statuses)
+
+	replayed := replayRecords(records, nil, 75, 90, 2)
+	recorded, now := countReplayed(replayed)
+	assert.Equal(1, recorded[sensu.CheckStateCritical])
+	assert.Equal(2, now[sensu.CheckStateCritical])
+
+	replayed[2].Processes = []HistoryProcess{{PID: 42, Name: "java", CPU: 150}}
+	assert.Equal("TIME                 USED    RECORDED  REPLAYED  TOP\n"+
+		"2024-05-02 04:00:00  95.00%  Critical  Critical  java (150.00%)\n"+
+		"2024-05-02 05:00:00  96.00%  OK        Critical  \n", formatReplayedRecords(replayed))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Struct to hold how many times the processes of a watched name restarted
+// since the previous run
+type ProcessRestarts struct {
+	Name     string
+	Restarts int
+}
+
+// Function to count the restarts of the watched process names since the
+// previous run, from the processes running under each name then and now. A
+// process that is gone counts as restarted when a new one took its place, so
+// a name that went down is not counted until it comes back. The processes
+// running under each name are stored in the state, those of a name with
+// none running being kept until one is.
+func watchRestarts(processList []ProcessInfo, names []string, state *State) []ProcessRestarts {
+	current := make(map[string][]string, len(names))
+	for _, name := range names {
+		current[name] = nil
+	}
+	for _, p := range processList {
+		if keys, ok := current[p.Name]; ok {
+			current[p.Name] = append(keys, processKey(p))
+		}
+	}
+
+	watched := make(map[string][]string, len(names))
+	restarts := make([]ProcessRestarts, 0, len(names))
+	for _, name := range names {
+		previous, now := state.Watched[name], current[name]
+		if len(now) == 0 {
+			if previous != nil {
+				watched[name] = previous
+			}
+			restarts = append(restarts, ProcessRestarts{Name: name})
+			continue
+		}
+		sort.Strings(now)
+		watched[name] = now
+		gone, started := keysMissing(previous, now), keysMissing(now, previous)
+		if started < gone {
+			gone = started
+		}
+		restarts = append(restarts, ProcessRestarts{Name: name, Restarts: gone})
+	}
+	state.Watched = watched
+	return restarts
+}
+
+// Function to count the keys of a that are not in b
+func keysMissing(a, b []string) int {
+	in := make(map[string]bool, len(b))
+	for _, k := range b {
+		in[k] = true
+	}
+	missing := 0
+	for _, k := range a {
+		if !in[k] {
+			missing++
+		}
+	}
+	return missing
+}
+
+// Function to list the restart metrics, in total and for every watched name
+func restartMetrics(restarts []ProcessRestarts) []Metric {
+	total := 0
+	metrics := make([]Metric, 0, len(restarts)+1)
+	for _, r := range restarts {
+		total += r.Restarts
+		metrics = append(metrics, Metric{"process_restarts_" + entityNameInvalid.ReplaceAllString(r.Name, "_"), float64(r.Restarts)})
+	}
+	return append([]Metric{{"process_restarts", float64(total)}}, metrics...)
+}
+
+// Function to describe the watched names that restarted, for the summary
+func (r ProcessRestarts) String() string {
+	if r.Restarts == 1 {
+		return fmt.Sprintf("%s restarted", r.Name)
+	}
+	return fmt.Sprintf("%s restarted %d times", r.Name, r.Restarts)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchRestarts(t *testing.T) {
+	assert := assert.New(t)
+	started := time.Date(2024, 9, 2, 12, 0, 0, 0, time.UTC)
+	nginx := []ProcessInfo{
+		{PID: 10, Name: "nginx", CreatedAt: started},
+		{PID: 11, Name: "nginx", CreatedAt: started},
+	}
+	worker := ProcessInfo{PID: 20, Name: "worker", CreatedAt: started}
+	var state State
+
+	// Nothing is known on the first run
+	restarts := watchRestarts(append(nginx, worker), []string{"nginx", "worker"}, &state)
+	assert.Equal([]ProcessRestarts{{Name: "nginx"}, {Name: "worker"}}, restarts)
+
+	// The worker crashed and came back, under a reused PID
+	worker.CreatedAt = started.Add(time.Minute)
+	restarts = watchRestarts(append(nginx, worker), []string{"nginx", "worker"}, &state)
+	assert.Equal([]ProcessRestarts{{Name: "nginx"}, {Name: "worker", Restarts: 1}}, restarts)
+
+	// A worker that is down is not counted until it comes back
+	restarts = watchRestarts(nginx, []string{"nginx", "worker"}, &state)
+	assert.Equal([]ProcessRestarts{{Name: "nginx"}, {Name: "worker"}}, restarts)
+	worker.PID = 21
+	restarts = watchRestarts([]ProcessInfo{nginx[0], worker}, []string{"nginx", "worker"}, &state)
+	assert.Equal([]ProcessRestarts{{Name: "nginx"}, {Name: "worker", Restarts: 1}}, restarts)
+
+	// Names no longer watched are forgotten
+	watchRestarts(nginx, []string{"nginx"}, &state)
+	assert.Len(state.Watched, 1)
+}
+
+func TestRestartMetrics(t *testing.T) {
+	assert := assert.New(t)
+	restarts := []ProcessRestarts{{Name: "nginx", Restarts: 2}, {Name: "php-fpm: pool www", Restarts: 1}}
+	assert.Equal([]Metric{
+		{"process_restarts", 3},
+		{"process_restarts_nginx", 2},
+		{"process_restarts_php-fpm_pool_www", 1},
+	}, restartMetrics(restarts))
+	assert.Equal("nginx restarted 2 times", restarts[0].String())
+	assert.Equal("php-fpm: pool www restarted", restarts[1].String())
+}
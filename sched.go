@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// Real-time scheduling policies, as numbered by the kernel
+const (
+	schedFIFO = 1
+	schedRR   = 2
+)
+
+// Struct to hold the real-time scheduling policy of a process and its
+// priority within it, from 1 to 99
+type SchedInfo struct {
+	Policy   string `json:"policy"`
+	Priority int    `json:"priority"`
+}
+
+// Function to parse the real-time priority and scheduling policy of a task
+// from its stat file in /proc, the 40th and 41st fields. Only SCHED_FIFO and
+// SCHED_RR are returned, nil otherwise.
+func parseStatSched(data []byte) (*SchedInfo, error) {
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return nil, fmt.Errorf("invalid stat line %q", data)
+	}
+	// The fields after the name start with the third, the state
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) < 39 {
+		return nil, fmt.Errorf("invalid stat line %q", data)
+	}
+	priority, err := strconv.Atoi(string(fields[37]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid stat line %q", data)
+	}
+	policy, err := strconv.Atoi(string(fields[38]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid stat line %q", data)
+	}
+	switch policy {
+	case schedFIFO:
+		return &SchedInfo{Policy: "SCHED_FIFO", Priority: priority}, nil
+	case schedRR:
+		return &SchedInfo{Policy: "SCHED_RR", Priority: priority}, nil
+	}
+	return nil, nil
+}
+
+// Function to read the real-time scheduling policies of the processes,
+// leaving them unset for those that are not real-time or cannot be read
+func readProcessSched(processes []ProcessInfo) {
+	for i := range processes {
+		if sched, err := readSched(processes[i].PID); err == nil {
+			processes[i].Sched = sched
+		}
+	}
+}
+
+// Function to get the busiest real-time process using more CPU than the
+// threshold, as a percentage of one core
+func runawayRealtime(processes []ProcessInfo, threshold float64) *ProcessInfo {
+	var runaway *ProcessInfo
+	for i, p := range processes {
+		if p.Sched != nil && p.CPU > threshold && (runaway == nil || p.CPU > runaway.CPU) {
+			runaway = &processes[i]
+		}
+	}
+	return runaway
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+// Function to read the real-time scheduling policy of a process from
+// /proc/<pid>/stat, nil when it is not real-time
+func readSched(pid int32) (*SchedInfo, error) {
+	data, err := os.ReadFile(hostProc(strconv.Itoa(int(pid)), "stat"))
+	if err != nil {
+		return nil, err
+	}
+	return parseStatSched(data)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// Function to read the real-time scheduling policy of a process
+func readSched(pid int32) (*SchedInfo, error) {
+	return nil, errUnsupported
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Stat line of a task, with the real-time priority and policy to fill in
+const testStatLine = "4321 (irq/42-eth0) S 2 0 0 0 -1 2129984 0 0 0 0 0 1234 0 0 -51 0 1 0 35 0 0 18446744073709551615 0 0 0 0 0 0 0 2147483647 0 0 0 0 17 3 %s %s 0 0 0 0 0 0 0 0 0 0 0"
+
+func TestParseStatSched(t *testing.T) {
+	assert := assert.New(t)
+	sched, err := parseStatSched([]byte(fmt.Sprintf(testStatLine, "50", "1")))
+	assert.NoError(err)
+	assert.Equal(&SchedInfo{Policy: "SCHED_FIFO", Priority: 50}, sched)
+	sched, err = parseStatSched([]byte(fmt.Sprintf(testStatLine, "10", "2")))
+	assert.NoError(err)
+	assert.Equal(&SchedInfo{Policy: "SCHED_RR", Priority: 10}, sched)
+	sched, err = parseStatSched([]byte(fmt.Sprintf(testStatLine, "0", "0")))
+	assert.NoError(err)
+	assert.Nil(sched)
+	_, err = parseStatSched([]byte("4321 (irq/42-eth0) S 2 0 0"))
+	assert.Error(err)
+}
+
+func TestRunawayRealtime(t *testing.T) {
+	assert := assert.New(t)
+	fifo := &SchedInfo{Policy: "SCHED_FIFO", Priority: 50}
+	processes := []ProcessInfo{
+		{PID: 10, Name: "java", CPU: 99},
+		{PID: 20, Name: "irq/42-eth0", CPU: 80, Sched: fifo},
+		{PID: 30, Name: "audio", CPU: 20, Sched: fifo},
+	}
+	assert.Equal(int32(20), runawayRealtime(processes, 50).PID)
+	assert.Nil(runawayRealtime(processes, 90))
+}
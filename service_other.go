@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+// Function to list the processes of a Windows service
+func servicePIDs(name string) ([]int32, error) {
+	return nil, errUnsupported
+}
+
+// Function to check that the service control manager can be queried
+func probeServices() error {
+	return errUnsupported
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Function to list the processes of a Windows service, from the process the
+// service control manager started for it and its descendants. A stopped
+// service has no processes.
+func servicePIDs(name string) ([]int32, error) {
+	scm, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to the service control manager: %v", err)
+	}
+	defer windows.CloseServiceHandle(scm)
+	serviceName, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	service, err := windows.OpenService(scm, serviceName, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open service %s: %v", name, err)
+	}
+	defer windows.CloseServiceHandle(service)
+	var status windows.SERVICE_STATUS_PROCESS
+	var needed uint32
+	if err := windows.QueryServiceStatusEx(service, windows.SC_STATUS_PROCESS_INFO, (*byte)(unsafe.Pointer(&status)), uint32(unsafe.Sizeof(status)), &needed); err != nil {
+		return nil, fmt.Errorf("cannot query service %s: %v", name, err)
+	}
+	if status.ProcessId == 0 {
+		return nil, nil
+	}
+	parents, err := processParents()
+	if err != nil {
+		return nil, err
+	}
+	return descendants(parents, int32(status.ProcessId)), nil
+}
+
+// Function to check that the service control manager can be queried
+func probeServices() error {
+	scm, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return err
+	}
+	return windows.CloseServiceHandle(scm)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/sensu/sensu-go/types"
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// Struct to hold the full CPU and process state captured by the snapshot
+// subcommand
+type Snapshot struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Host      string        `json:"host"`
+	Interval  string        `json:"interval"`
+	Usage     CPUUsage      `json:"usage"`
+	PerCPU    []CPUUsage    `json:"per_cpu"`
+	LoadAvg   *LoadTriplet  `json:"load_avg,omitempty"`
+	Processes []ProcessInfo `json:"processes"`
+}
+
+// Struct to hold the options of the snapshot subcommand
+type SnapshotConfig struct {
+	Out string
+}
+
+// Number of processes listed in each section of a snapshot diff
+const diffProcessLimit = 10
+
+var (
+	snapshot = SnapshotConfig{}
+
+	snapshotOptions = []*sensu.PluginConfigOption{
+		{
+			Path:     "out",
+			Argument: "out",
+			Default:  "",
+			Usage:    "File to write the snapshot to instead of stdout",
+			Value:    &snapshot.Out,
+		},
+	}
+)
+
+// Function to capture the CPU usage, overall and per logical CPU, and the CPU
+// usage of every process over one sample interval
+func takeSnapshot() (*Snapshot, error) {
+	start, err := cpu.Times(false)
+	if err != nil {
+		return nil, fmt.Errorf("Error obtaining CPU timings: %v", err)
+	}
+	perStart, err := cpu.Times(true)
+	if err != nil {
+		return nil, fmt.Errorf("Error obtaining CPU timings: %v", err)
+	}
+	processTimes, err := processCPUTimes()
+	if err != nil {
+		return nil, fmt.Errorf("Error obtaining process CPU timings: %v", err)
+	}
+	startTime := time.Now()
+	time.Sleep(plugin.intervalDuration)
+
+	end, err := cpu.Times(false)
+	if err != nil {
+		return nil, fmt.Errorf("Error obtaining CPU timings: %v", err)
+	}
+	perEnd, err := cpu.Times(true)
+	if err != nil {
+		return nil, fmt.Errorf("Error obtaining CPU timings: %v", err)
+	}
+	processList, err := getProcesses(processTimes, time.Since(startTime))
+	if err != nil {
+		return nil, fmt.Errorf("Error obtaining process CPU timings: %v", err)
+	}
+
+	s := &Snapshot{
+		Timestamp: time.Now(),
+		Host:      plugin.hostname,
+		Interval:  plugin.intervalDuration.String(),
+		Usage:     cpuUsage(start[0], end[0]),
+		Processes: topCPUProcesses(processList, len(processList)),
+	}
+	s.PerCPU = perCPUUsage(perStart, perEnd)
+	// Load averages are not available everywhere
+	if load, err := readLoadAvg(false, len(perEnd)); err == nil {
+		s.LoadAvg = &load
+	}
+	return s, nil
+}
+
+// Function to read a snapshot written by the snapshot subcommand
+func readSnapshot(file string) (*Snapshot, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return &s, nil
+}
+
+// Struct to hold the change in CPU usage of a process between two snapshots
+type ProcessDelta struct {
+	PID    int32
+	Name   string
+	Before float64
+	After  float64
+}
+
+// Function to format the differences between two snapshots: the change in
+// CPU usage, overall and per CPU, and the processes that started, exited or
+// changed their CPU usage the most in between
+func formatSnapshotDiff(a, b *Snapshot) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s diff: %s (%s) -> %s (%s), %s apart\n\n", plugin.PluginConfig.Name,
+		a.Timestamp.Format(time.RFC3339), a.Host, b.Timestamp.Format(time.RFC3339), b.Host, b.Timestamp.Sub(a.Timestamp).Round(time.Second))
+
+	fmt.Fprintf(&out, "CPU usage: %s\n", formatDelta(a.Usage.Used, b.Usage.Used, "%"))
+	for _, f := range []struct {
+		name          string
+		before, after float64
+	}{
+		{"user", a.Usage.User, b.Usage.User},
+		{"system", a.Usage.System, b.Usage.System},
+		{"iowait", a.Usage.Iowait, b.Usage.Iowait},
+		{"steal", a.Usage.Steal, b.Usage.Steal},
+	} {
+		fmt.Fprintf(&out, "  %s: %s\n", f.name, formatDelta(f.before, f.after, "%"))
+	}
+	if a.LoadAvg != nil && b.LoadAvg != nil {
+		fmt.Fprintf(&out, "Load average: %s\n", formatDelta(a.LoadAvg[0], b.LoadAvg[0], ""))
+	}
+	if len(a.PerCPU) != len(b.PerCPU) {
+		fmt.Fprintf(&out, "Logical CPUs: %d -> %d\n", len(a.PerCPU), len(b.PerCPU))
+	} else {
+		for i := range a.PerCPU {
+			fmt.Fprintf(&out, "  cpu%d: %s\n", i, formatDelta(a.PerCPU[i].Used, b.PerCPU[i].Used, "%"))
+		}
+	}
+
+	before := make(map[string]ProcessInfo, len(a.Processes))
+	for _, p := range a.Processes {
+		before[processKey(p)] = p
+	}
+	var started, exited, changed []ProcessDelta
+	for _, p := range b.Processes {
+		key := processKey(p)
+		if old, ok := before[key]; ok {
+			changed = append(changed, ProcessDelta{p.PID, p.Name, old.CPU, p.CPU})
+			delete(before, key)
+		} else {
+			started = append(started, ProcessDelta{p.PID, p.Name, 0, p.CPU})
+		}
+	}
+	for _, p := range before {
+		exited = append(exited, ProcessDelta{p.PID, p.Name, p.CPU, 0})
+	}
+
+	out.WriteString(formatProcessDeltas("Started processes", started))
+	out.WriteString(formatProcessDeltas("Exited processes", exited))
+	out.WriteString(formatProcessDeltas("Changed processes", changed))
+	return out.String()
+}
+
+// Function to format a value before and after along with the change
+func formatDelta(before, after float64, unit string) string {
+	return fmt.Sprintf("%.2f%s -> %.2f%s (%+.2f)", before, unit, after, unit, after-before)
+}
+
+// Function to format a section of a snapshot diff, listing the processes with
+// the largest change in CPU usage first. Processes whose usage did not change
+// are left out.
+func formatProcessDeltas(title string, deltas []ProcessDelta) string {
+	sort.SliceStable(deltas, func(i, j int) bool {
+		di, dj := math.Abs(deltas[i].After-deltas[i].Before), math.Abs(deltas[j].After-deltas[j].Before)
+		if di != dj {
+			return di > dj
+		}
+		return deltas[i].PID < deltas[j].PID
+	})
+	var lines []string
+	for _, d := range deltas {
+		if math.Abs(d.After-d.Before) < 0.005 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("PID %d (%s): %s\n", d.PID, d.Name, formatDelta(d.Before, d.After, "%")))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	out := fmt.Sprintf("\n%s (%d):\n", title, len(lines))
+	if len(lines) > diffProcessLimit {
+		out += strings.Join(lines[:diffProcessLimit], "")
+		return out + fmt.Sprintf("and %d more\n", len(lines)-diffProcessLimit)
+	}
+	return out + strings.Join(lines, "")
+}
+
+// Function to take a snapshot and write it as JSON to --out or stdout
+func executeSnapshot(event *types.Event) (int, error) {
+	s, err := takeSnapshot()
+	if err != nil {
+		return sensu.CheckStateCritical, err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return sensu.CheckStateCritical, fmt.Errorf("Error encoding snapshot: %v", err)
+	}
+	data = append(data, '\n')
+	if snapshot.Out == "" {
+		os.Stdout.Write(data)
+		return sensu.CheckStateOK, nil
+	}
+	if err := os.WriteFile(snapshot.Out, data, 0644); err != nil {
+		return sensu.CheckStateCritical, fmt.Errorf("Error writing snapshot: %v", err)
+	}
+	fmt.Printf("%s OK: snapshot of %d processes written to %s\n", plugin.PluginConfig.Name, len(s.Processes), snapshot.Out)
+	return sensu.CheckStateOK, nil
+}
+
+// Function to validate the arguments of the diff subcommand, the two
+// snapshot files to compare
+func diffArgs(event *types.Event) (int, error) {
+	if len(commandArgs) != 2 {
+		return sensu.CheckStateWarning, fmt.Errorf("diff takes the two snapshot files to compare")
+	}
+	return sensu.CheckStateOK, nil
+}
+
+// Function to print the differences between two snapshots
+func executeDiff(event *types.Event) (int, error) {
+	a, err := readSnapshot(commandArgs[0])
+	if err != nil {
+		return sensu.CheckStateCritical, fmt.Errorf("Error reading snapshot: %v", err)
+	}
+	b, err := readSnapshot(commandArgs[1])
+	if err != nil {
+		return sensu.CheckStateCritical, fmt.Errorf("Error reading snapshot: %v", err)
+	}
+	fmt.Print(formatSnapshotDiff(a, b))
+	return sensu.CheckStateOK, nil
+}
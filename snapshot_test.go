@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSnapshots() (*Snapshot, *Snapshot) {
+	at := time.Date(2024, 9, 2, 12, 0, 0, 0, time.UTC)
+	created := at.Add(-time.Hour)
+	a := &Snapshot{
+		Timestamp: at,
+		Host:      "web01",
+		Usage:     CPUUsage{Used: 20, User: 15, System: 5},
+		PerCPU:    []CPUUsage{{Used: 30}, {Used: 10}},
+		LoadAvg:   &LoadTriplet{0.5, 0.4, 0.3},
+		Processes: []ProcessInfo{
+			{PID: 42, Name: "java", CPU: 15, CreatedAt: created},
+			{PID: 7, Name: "backup", CPU: 5, CreatedAt: created},
+			{PID: 1, Name: "init", CPU: 0, CreatedAt: created},
+		},
+	}
+	b := &Snapshot{
+		Timestamp: at.Add(5 * time.Minute),
+		Host:      "web01",
+		Usage:     CPUUsage{Used: 80, User: 70, System: 10},
+		PerCPU:    []CPUUsage{{Used: 90}, {Used: 70}},
+		LoadAvg:   &LoadTriplet{1.5, 0.8, 0.4},
+		Processes: []ProcessInfo{
+			{PID: 42, Name: "java", CPU: 140, CreatedAt: created},
+			{PID: 99, Name: "importer", CPU: 20, CreatedAt: at.Add(time.Minute)},
+			{PID: 1, Name: "init", CPU: 0, CreatedAt: created},
+		},
+	}
+	return a, b
+}
+
+func TestFormatSnapshotDiff(t *testing.T) {
+	assert := assert.New(t)
+	a, b := testSnapshots()
+	assert.Equal("cpu-process-profiler diff: 2024-09-02T12:00:00Z (web01) -> 2024-09-02T12:05:00Z (web01), 5m0s apart\n"+
+		"\nCPU usage: 20.00% -> 80.00% (+60.00)\n"+
+		"  user: 15.00% -> 70.00% (+55.00)\n"+
+		"  system: 5.00% -> 10.00% (+5.00)\n"+
+		"  iowait: 0.00% -> 0.00% (+0.00)\n"+
+		"  steal: 0.00% -> 0.00% (+0.00)\n"+
+		"Load average: 0.50 -> 1.50 (+1.00)\n"+
+		"  cpu0: 30.00% -> 90.00% (+60.00)\n"+
+		"  cpu1: 10.00% -> 70.00% (+60.00)\n"+
+		"\nStarted processes (1):\n"+
+		"PID 99 (importer): 0.00% -> 20.00% (+20.00)\n"+
+		"\nExited processes (1):\n"+
+		"PID 7 (backup): 5.00% -> 0.00% (-5.00)\n"+
+		"\nChanged processes (1):\n"+
+		"PID 42 (java): 15.00% -> 140.00% (+125.00)\n", formatSnapshotDiff(a, b))
+
+	b.PerCPU = b.PerCPU[:1]
+	assert.Contains(formatSnapshotDiff(a, b), "Logical CPUs: 2 -> 1\n")
+}
+
+func TestFormatProcessDeltas(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("", formatProcessDeltas("Changed processes", []ProcessDelta{{1, "init", 1, 1}}))
+
+	var deltas []ProcessDelta
+	for i := 1; i <= 12; i++ {
+		deltas = append(deltas, ProcessDelta{int32(i), "worker", 0, float64(i)})
+	}
+	out := formatProcessDeltas("Started processes", deltas)
+	assert.Contains(out, "\nStarted processes (12):\nPID 12 (worker): 0.00% -> 12.00% (+12.00)\n")
+	assert.Contains(out, "and 2 more\n")
+	assert.NotContains(out, fmt.Sprintf("PID %d (worker)", 2))
+}
+
+func TestReadSnapshot(t *testing.T) {
+	assert := assert.New(t)
+	a, _ := testSnapshots()
+	data, err := json.Marshal(a)
+	assert.NoError(err)
+	file := filepath.Join(t.TempDir(), "a.json")
+	assert.NoError(os.WriteFile(file, data, 0644))
+	read, err := readSnapshot(file)
+	assert.NoError(err)
+	assert.Equal(a.Processes, read.Processes)
+	assert.Equal(a.LoadAvg, read.LoadAvg)
+
+	assert.NoError(os.WriteFile(file, []byte("not json"), 0644))
+	_, err = readSnapshot(file)
+	assert.Error(err)
+}
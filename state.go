@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Struct to hold the state persisted between check runs
+type State struct {
+	ConsecutiveBreaches int                      `json:"consecutive_breaches"`
+	Suppressions        map[string]time.Time     `json:"suppressions,omitempty"`
+	Processes           map[string]ProcessSample `json:"processes,omitempty"`
+	ProcessesAt         time.Time                `json:"processes_at"`
+	AlertingGroups      []string                 `json:"alerting_groups,omitempty"`
+	Baseline            []BaselineBucket         `json:"baseline,omitempty"`
+	EWMA                float64                  `json:"ewma,omitempty"`
+	EWMAAt              time.Time                `json:"ewma_at"`
+	Watched             map[string][]string      `json:"watched,omitempty"`
+}
+
+// Function to get the default location of the state file
+func defaultStateFile() string {
+	return filepath.Join(os.TempDir(), "cpu-process-profiler.state.json")
+}
+
+// Function to get the exponentially weighted moving average of CPU usage
+// including a new value, which is taken as is when there is no average yet
+func (s *State) smoothedUsage(v, alpha float64) float64 {
+	if s.EWMAAt.IsZero() {
+		return v
+	}
+	return alpha*v + (1-alpha)*s.EWMA
+}
+
+// Function to load the state file, a missing file yields an empty state
+func loadState(path string) (State, error) {
+	var state State
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, err
+	}
+	return state, nil
+}
+
+// Function to save the state file, written to a temporary file first so a
+// concurrent run never reads a partial file
+func saveState(path string, state State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	state, err := loadState(path)
+	assert.NoError(err)
+	assert.Equal(State{}, state)
+
+	state.ConsecutiveBreaches = 2
+	assert.NoError(saveState(path, state))
+	loaded, err := loadState(path)
+	assert.NoError(err)
+	assert.Equal(state, loaded)
+}
+
+func TestSmoothedUsage(t *testing.T) {
+	assert := assert.New(t)
+
+	var state State
+	assert.Equal(float64(80), state.smoothedUsage(80, 0.25))
+	state.EWMA, state.EWMAAt = 40, time.Now()
+	assert.Equal(float64(50), state.smoothedUsage(80, 0.25))
+	assert.Equal(float64(80), state.smoothedUsage(80, 1))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"time"
+)
+
+// How long to wait for the agent to write the event to stdin
+const stdinEventTimeout = time.Second
+
+// Function to find out whether the agent passed the event on stdin, as it
+// does for checks with stdin enabled, so annotation overrides and entity
+// labels can be used. The SDK fails on an empty stdin and blocks on one that
+// is never closed once told to read the event, so stdin is read up front,
+// giving up after a moment, and replaced by a pipe replaying the event when
+// there is one.
+func replayStdinEvent() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+		return false
+	}
+	read := make(chan []byte, 1)
+	go func() {
+		data, _ := io.ReadAll(os.Stdin)
+		read <- data
+	}()
+	var data []byte
+	select {
+	case data = <-read:
+	case <-time.After(stdinEventTimeout):
+		return false
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return false
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return false
+	}
+	go func() {
+		w.Write(data)
+		w.Close()
+	}()
+	os.Stdin = r
+	return true
+}
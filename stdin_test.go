@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayStdinEvent(t *testing.T) {
+	assert := assert.New(t)
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+
+	event := `{"entity": {"metadata": {"name": "web01"}}}`
+	path := filepath.Join(t.TempDir(), "event.json")
+	assert.NoError(os.WriteFile(path, []byte(event), 0644))
+	f, err := os.Open(path)
+	assert.NoError(err)
+	defer f.Close()
+	os.Stdin = f
+	assert.True(replayStdinEvent())
+	data, err := io.ReadAll(os.Stdin)
+	assert.NoError(err)
+	assert.Equal(event, string(data))
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	assert.NoError(os.WriteFile(empty, []byte("\n"), 0644))
+	f, err = os.Open(empty)
+	assert.NoError(err)
+	defer f.Close()
+	os.Stdin = f
+	assert.False(replayStdinEvent())
+
+	// A pipe that is never closed
+	r, w, err := os.Pipe()
+	assert.NoError(err)
+	defer w.Close()
+	os.Stdin = r
+	assert.False(replayStdinEvent())
+
+	devNull, err := os.Open(os.DevNull)
+	assert.NoError(err)
+	defer devNull.Close()
+	os.Stdin = devNull
+	assert.False(replayStdinEvent())
+}
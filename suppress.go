@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Struct to hold an offender suppression resolved to its expiry time
+type Suppression struct {
+	Pattern string
+	Expires time.Time
+}
+
+// Function to read suppression specs from a file, one per line, ignoring
+// blank lines and # comments. The file is read on every run so it can be
+// edited during an incident without touching the check definition. A missing
+// file means no suppressions.
+func readSuppressFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var specs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		specs = append(specs, line)
+	}
+	return specs, scanner.Err()
+}
+
+// Function to resolve suppression specs of the form pattern=duration or
+// pattern=RFC3339-timestamp. A duration is a TTL counted from the first run
+// that saw the spec, which is remembered in the state so it does not restart
+// on every run. Specs that are no longer configured are forgotten.
+func resolveSuppressions(specs []string, state *State, now time.Time) ([]Suppression, error) {
+	seen := make(map[string]time.Time, len(specs))
+	var suppressions []Suppression
+	for _, spec := range specs {
+		i := strings.LastIndex(spec, "=")
+		if i <= 0 || i == len(spec)-1 {
+			return nil, fmt.Errorf("invalid suppression %q, expected pattern=duration", spec)
+		}
+		pattern, value := spec[:i], spec[i+1:]
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid suppression pattern %q: %v", pattern, err)
+		}
+
+		expires, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			ttl, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid suppression %q, expected pattern=duration", spec)
+			}
+			var ok bool
+			if expires, ok = state.Suppressions[spec]; !ok {
+				expires = now.Add(ttl)
+			}
+			seen[spec] = expires
+		}
+		suppressions = append(suppressions, Suppression{Pattern: pattern, Expires: expires})
+	}
+
+	if len(seen) > 0 {
+		state.Suppressions = seen
+	} else {
+		state.Suppressions = nil
+	}
+	return suppressions, nil
+}
+
+// Function to check whether a process name is suppressed, returning the
+// expiry of the matching suppression
+func suppressedUntil(suppressions []Suppression, name string, now time.Time) (time.Time, bool) {
+	for _, s := range suppressions {
+		if ok, _ := path.Match(s.Pattern, name); ok && now.Before(s.Expires) {
+			return s.Expires, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Struct to hold a window during which the check is forced OK, either once
+// between two times or every day between two times of day in local time,
+// optionally on one weekday only. A daily window ending before it starts runs
+// past midnight and belongs to the day it starts on.
+type SuppressWindow struct {
+	Spec    string
+	From    time.Time
+	To      time.Time
+	Weekday int
+	Start   int
+	End     int
+}
+
+// Function to parse a suppress window given as RFC3339/RFC3339 for a one-off
+// window or [Mon ]HH:MM-HH:MM for a daily or weekly one
+func parseSuppressWindow(spec string) (SuppressWindow, error) {
+	w := SuppressWindow{Spec: spec, Weekday: -1}
+	if from, to, ok := strings.Cut(spec, "/"); ok {
+		var err error
+		if w.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return w, fmt.Errorf("invalid suppress window %q: %v", spec, err)
+		}
+		if w.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return w, fmt.Errorf("invalid suppress window %q: %v", spec, err)
+		}
+		if !w.To.After(w.From) {
+			return w, fmt.Errorf("invalid suppress window %q, it must end after it starts", spec)
+		}
+		return w, nil
+	}
+
+	clock := spec
+	if day, rest, ok := strings.Cut(spec, " "); ok {
+		d, ok := parseWeekday(day)
+		if !ok {
+			return w, fmt.Errorf("invalid suppress window %q, %q is not a weekday such as Sun", spec, day)
+		}
+		w.Weekday = int(d)
+		clock = strings.TrimSpace(rest)
+	}
+	from, to, ok := strings.Cut(clock, "-")
+	if !ok {
+		return w, fmt.Errorf("invalid suppress window %q, expected [Mon ]HH:MM-HH:MM or RFC3339/RFC3339", spec)
+	}
+	var err error
+	if w.Start, err = parseClock(from); err != nil {
+		return w, fmt.Errorf("invalid suppress window %q: %v", spec, err)
+	}
+	if w.End, err = parseClock(to); err != nil {
+		return w, fmt.Errorf("invalid suppress window %q: %v", spec, err)
+	}
+	if w.Start == w.End {
+		return w, fmt.Errorf("invalid suppress window %q, it cannot start and end at the same time", spec)
+	}
+	return w, nil
+}
+
+// Function to parse the three letter abbreviation of a weekday
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()[:3]) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// Function to tell whether a time falls within a suppress window
+func (w SuppressWindow) covers(t time.Time) bool {
+	if !w.From.IsZero() {
+		return !t.Before(w.From) && t.Before(w.To)
+	}
+	m := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	switch {
+	case w.Start < w.End && m >= w.Start && m < w.End:
+	case w.Start > w.End && m >= w.Start:
+	case w.Start > w.End && m < w.End:
+		day = t.AddDate(0, 0, -1).Weekday()
+	default:
+		return false
+	}
+	return w.Weekday < 0 || time.Weekday(w.Weekday) == day
+}
+
+// Function to get the suppress window a time falls within, if any
+func activeSuppressWindow(windows []SuppressWindow, t time.Time) (SuppressWindow, bool) {
+	for _, w := range windows {
+		if w.covers(t) {
+			return w, true
+		}
+	}
+	return SuppressWindow{}, false
+}
+
+// Function to gather the offender suppressions and suppress windows of
+// --suppress, --suppress-window and --suppress-file. Lines of the file
+// without an = are windows.
+func readSuppressions() ([]string, []SuppressWindow, error) {
+	specs := plugin.Suppress
+	windows := plugin.suppressWindows
+	if plugin.SuppressFile == "" {
+		return specs, windows, nil
+	}
+	lines, err := readSuppressFile(plugin.SuppressFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error reading suppress file: %v", err)
+	}
+	specs = append([]string(nil), specs...)
+	windows = append([]SuppressWindow(nil), windows...)
+	for _, line := range lines {
+		if strings.Contains(line, "=") {
+			specs = append(specs, line)
+			continue
+		}
+		w, err := parseSuppressWindow(line)
+		if err != nil {
+			return nil, nil, err
+		}
+		windows = append(windows, w)
+	}
+	return specs, windows, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSuppressions(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2024, 9, 2, 12, 0, 0, 0, time.UTC)
+	var state State
+
+	list, err := resolveSuppressions([]string{"java*=1h", "backup=2024-09-02T13:30:00Z"}, &state, now)
+	assert.NoError(err)
+	assert.Len(list, 2)
+	assert.Equal(now.Add(time.Hour), list[0].Expires)
+	assert.Equal(now.Add(90*time.Minute), list[1].Expires)
+	assert.Equal(map[string]time.Time{"java*=1h": now.Add(time.Hour)}, state.Suppressions)
+
+	// The TTL counts from the first run that saw the spec
+	later := now.Add(30 * time.Minute)
+	list, err = resolveSuppressions([]string{"java*=1h"}, &state, later)
+	assert.NoError(err)
+	assert.Equal(now.Add(time.Hour), list[0].Expires)
+
+	_, ok := suppressedUntil(list, "java-app", later)
+	assert.True(ok)
+	_, ok = suppressedUntil(list, "nginx", later)
+	assert.False(ok)
+	_, ok = suppressedUntil(list, "java-app", now.Add(2*time.Hour))
+	assert.False(ok)
+
+	// Specs that are removed are forgotten
+	_, err = resolveSuppressions(nil, &state, later)
+	assert.NoError(err)
+	assert.Nil(state.Suppressions)
+
+	for _, spec := range []string{"java", "=1h", "java=", "java=soon", "[=1h"} {
+		_, err = resolveSuppressions([]string{spec}, &state, now)
+		assert.Error(err, spec)
+	}
+}
+
+func TestReadSuppressFile(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "suppress")
+
+	specs, err := readSuppressFile(path)
+	assert.NoError(err)
+	assert.Empty(specs)
+
+	assert.NoError(os.WriteFile(path, []byte("# noisy during incident 123\njava*=1h\n\n  backup=30m  \n"), 0644))
+	specs, err = readSuppressFile(path)
+	assert.NoError(err)
+	assert.Equal([]string{"java*=1h", "backup=30m"}, specs)
+}
+
+func TestParseSuppressWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	w, err := parseSuppressWindow("2024-09-02T22:00:00Z/2024-09-03T02:00:00Z")
+	assert.NoError(err)
+	assert.Equal(time.Date(2024, 9, 2, 22, 0, 0, 0, time.UTC), w.From.UTC())
+	assert.Equal(time.Date(2024, 9, 3, 2, 0, 0, 0, time.UTC), w.To.UTC())
+
+	w, err = parseSuppressWindow("02:00-04:30")
+	assert.NoError(err)
+	assert.Equal(SuppressWindow{Spec: "02:00-04:30", Weekday: -1, Start: 120, End: 270}, w)
+
+	w, err = parseSuppressWindow("sun 23:00-01:00")
+	assert.NoError(err)
+	assert.Equal(SuppressWindow{Spec: "sun 23:00-01:00", Weekday: int(time.Sunday), Start: 1380, End: 60}, w)
+
+	for _, spec := range []string{
+		"",
+		"02:00",
+		"02:00-02:00",
+		"Sunday 02:00-04:00",
+		"2024-09-03T02:00:00Z/2024-09-02T22:00:00Z",
+		"2024-09-02/2024-09-03",
+	} {
+		_, err := parseSuppressWindow(spec)
+		assert.Error(err, spec)
+	}
+}
+
+func TestActiveSuppressWindow(t *testing.T) {
+	assert := assert.New(t)
+	var windows []SuppressWindow
+	for _, spec := range []string{"2024-09-02T10:00:00Z/2024-09-02T11:00:00Z", "02:00-04:00", "Sun 23:00-01:00"} {
+		w, err := parseSuppressWindow(spec)
+		assert.NoError(err)
+		windows = append(windows, w)
+	}
+	at := func(day, hour, min int) time.Time {
+		// 2024-09-01 was a Sunday
+		return time.Date(2024, 9, day, hour, min, 0, 0, time.Local)
+	}
+
+	cases := []struct {
+		time time.Time
+		spec string
+	}{
+		{time.Date(2024, 9, 2, 10, 30, 0, 0, time.UTC), "2024-09-02T10:00:00Z/2024-09-02T11:00:00Z"},
+		{time.Date(2024, 9, 2, 11, 0, 0, 0, time.UTC), ""},
+		{at(4, 2, 0), "02:00-04:00"},
+		{at(4, 3, 59), "02:00-04:00"},
+		{at(4, 4, 0), ""},
+		{at(1, 23, 30), "Sun 23:00-01:00"},
+		{at(2, 0, 30), "Sun 23:00-01:00"},
+		{at(2, 23, 30), ""},
+		{at(3, 0, 30), ""},
+	}
+	for _, c := range cases {
+		w, ok := activeSuppressWindow(windows, c.time)
+		assert.Equal(c.spec != "", ok, c.time)
+		assert.Equal(c.spec, w.Spec, c.time)
+	}
+}
+
+func TestReadSuppressions(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "suppress")
+	defer func() { plugin.Suppress, plugin.SuppressFile, plugin.suppressWindows = nil, "", nil }()
+
+	daily, err := parseSuppressWindow("02:00-04:00")
+	assert.NoError(err)
+	plugin.Suppress = []string{"java*=1h"}
+	plugin.suppressWindows = []SuppressWindow{daily}
+	plugin.SuppressFile = path
+	assert.NoError(os.WriteFile(path, []byte("backup=30m\n# patching\nSat 22:00-23:00\n"), 0644))
+	specs, windows, err := readSuppressions()
+	assert.NoError(err)
+	assert.Equal([]string{"java*=1h", "backup=30m"}, specs)
+	assert.Len(windows, 2)
+	assert.Equal("Sat 22:00-23:00", windows[1].Spec)
+	assert.Len(plugin.suppressWindows, 1)
+
+	assert.NoError(os.WriteFile(path, []byte("weekends\n"), 0644))
+	_, _, err = readSuppressions()
+	assert.Error(err)
+}
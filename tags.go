@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sensu/sensu-go/types"
+)
+
+// Struct to hold a tag attached to every emitted metric point
+type MetricTag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Function to parse key=value metric tag specs
+func parseMetricTags(specs []string) ([]MetricTag, error) {
+	tags := make([]MetricTag, 0, len(specs))
+	for _, spec := range specs {
+		name, value, ok := strings.Cut(spec, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%q is not a key=value tag", spec)
+		}
+		tags = append(tags, MetricTag{strings.TrimSpace(name), value})
+	}
+	return tags, nil
+}
+
+// Function to get the given labels of an entity as metric tags. Labels the
+// entity does not have are left out.
+func entityLabelTags(entity *types.Entity, labels []string) []MetricTag {
+	var tags []MetricTag
+	for _, label := range labels {
+		if value, ok := entity.Labels[label]; ok {
+			tags = append(tags, MetricTag{label, value})
+		}
+	}
+	return tags
+}
+
+// Function to combine metric tags, a tag given later overriding an earlier
+// one of the same name. The result is sorted by name.
+func mergeMetricTags(sets ...[]MetricTag) []MetricTag {
+	byName := make(map[string]string)
+	for _, set := range sets {
+		for _, t := range set {
+			byName[t.Name] = t.Value
+		}
+	}
+	tags := make([]MetricTag, 0, len(byName))
+	for name, value := range byName {
+		tags = append(tags, MetricTag{name, value})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMetricTags(t *testing.T) {
+	assert := assert.New(t)
+	tags, err := parseMetricTags([]string{"team=web", "env = prod", "empty="})
+	assert.NoError(err)
+	assert.Equal([]MetricTag{{"team", "web"}, {"env", " prod"}, {"empty", ""}}, tags)
+	_, err = parseMetricTags([]string{"team"})
+	assert.Error(err)
+	_, err = parseMetricTags([]string{"=web"})
+	assert.Error(err)
+}
+
+func TestEntityLabelTags(t *testing.T) {
+	assert := assert.New(t)
+	entity := types.FixtureEntity("web01")
+	entity.Labels = map[string]string{"team": "web", "region": "eu"}
+	assert.Equal([]MetricTag{{"team", "web"}}, entityLabelTags(entity, []string{"team", "service"}))
+	assert.Empty(entityLabelTags(entity, nil))
+}
+
+func TestMergeMetricTags(t *testing.T) {
+	assert := assert.New(t)
+	labels := []MetricTag{{"team", "web"}, {"region", "eu"}}
+	flags := []MetricTag{{"team", "shop"}}
+	assert.Equal([]MetricTag{{"region", "eu"}, {"team", "shop"}}, mergeMetricTags(labels, flags))
+	assert.Empty(mergeMetricTags())
+}
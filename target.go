@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+)
+
+// Struct to hold the CFS throttling counters of a cgroup
+type CgroupThrottling struct {
+	Periods   uint64
+	Throttled uint64
+	Time      time.Duration
+}
+
+// Struct to hold the CPU time of one process of the target
+type TargetProcess struct {
+	Name    string
+	CPUTime float64
+}
+
+// Struct to hold the context switch counts of a process, summed over its
+// threads
+type CtxtSwitches struct {
+	Voluntary   uint64
+	Involuntary uint64
+}
+
+// Struct to hold a read of everything collected about the target
+type TargetSample struct {
+	Processes  map[int32]TargetProcess
+	Threads    map[int32]float64
+	ThreadPIDs map[int32]int32
+	Ctxt       map[int32]CtxtSwitches
+	HasCtxt    bool
+	Throttling *CgroupThrottling
+}
+
+// Struct to hold the CPU usage of a thread of the target
+type ThreadInfo struct {
+	TID  int32   `json:"tid"`
+	PID  int32   `json:"pid"`
+	Name string  `json:"name"`
+	CPU  float64 `json:"cpu"`
+}
+
+// Function to parse the throttling counters of a cgroup cpu.stat file, which
+// gives the throttled time as throttled_usec on cgroup v2 and as
+// throttled_time in nanoseconds on cgroup v1
+func parseCPUThrottling(r io.Reader) (CgroupThrottling, error) {
+	var t CgroupThrottling
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "nr_periods":
+			t.Periods = v
+		case "nr_throttled":
+			t.Throttled = v
+		case "throttled_usec":
+			t.Time = time.Duration(v) * time.Microsecond
+		case "throttled_time":
+			t.Time = time.Duration(v)
+		}
+	}
+	return t, scanner.Err()
+}
+
+// Function to parse the context switch counts of a /proc/<pid>/status file
+func parseCtxtSwitches(r io.Reader) (uint64, uint64, error) {
+	var voluntary, involuntary uint64
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "voluntary_ctxt_switches:":
+			voluntary, _ = strconv.ParseUint(fields[1], 10, 64)
+		case "nonvoluntary_ctxt_switches:":
+			involuntary, _ = strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return voluntary, involuntary, scanner.Err()
+}
+
+// Function to list a process and all of its descendants, given the parent of
+// every process
+func descendants(parents map[int32]int32, root int32) []int32 {
+	children := make(map[int32][]int32)
+	for pid, ppid := range parents {
+		if pid != ppid {
+			children[ppid] = append(children[ppid], pid)
+		}
+	}
+	pids := []int32{root}
+	for i := 0; i < len(pids); i++ {
+		kids := children[pids[i]]
+		sort.Slice(kids, func(a, b int) bool { return kids[a] < kids[b] })
+		pids = append(pids, kids...)
+	}
+	return pids
+}
+
+// Function to find the processes of the target, returning them along with a
+// description of the target
+func targetPIDs() ([]int32, string, error) {
+	if plugin.TargetUnit != "" {
+		pids, err := unitPIDs(plugin.TargetUnit)
+		if err == nil && len(pids) == 0 {
+			err = fmt.Errorf("%s has no running processes", plugin.TargetUnit)
+		}
+		return pids, plugin.TargetUnit, err
+	}
+
+	root := int32(plugin.TargetPID)
+	target := fmt.Sprintf("PID %d", root)
+	parents, err := processParents()
+	if err != nil {
+		return nil, target, err
+	}
+	if _, ok := parents[root]; !ok {
+		return nil, target, fmt.Errorf("%s not found", target)
+	}
+	return descendants(parents, root), target, nil
+}
+
+// Function to read the CPU time, threads, context switches and throttling of
+// the processes of the target. Processes that exited are skipped, and
+// whatever is not available on this platform is left out.
+func readTargetSample(pids []int32) TargetSample {
+	sample := TargetSample{
+		Processes:  make(map[int32]TargetProcess, len(pids)),
+		Threads:    make(map[int32]float64),
+		ThreadPIDs: make(map[int32]int32),
+		Ctxt:       make(map[int32]CtxtSwitches, len(pids)),
+		HasCtxt:    true,
+	}
+	for _, pid := range pids {
+		p, threads, err := readTargetProcess(pid)
+		if err != nil {
+			continue
+		}
+		sample.Processes[pid] = p
+		for tid, cpuTime := range threads {
+			sample.Threads[tid] = cpuTime
+			sample.ThreadPIDs[tid] = pid
+		}
+		voluntary, involuntary, err := readThreadCtxtSwitches(pid)
+		if err != nil {
+			sample.HasCtxt = false
+		}
+		sample.Ctxt[pid] = CtxtSwitches{voluntary, involuntary}
+	}
+	if len(pids) > 0 {
+		if t, err := readTargetThrottling(pids[0]); err == nil {
+			sample.Throttling = &t
+		}
+	}
+	return sample
+}
+
+// Function to get the top n threads by CPU usage between two reads
+func topTargetThreads(start, end TargetSample, elapsed time.Duration, n int) []ThreadInfo {
+	var threads []ThreadInfo
+	for tid, cpuTime := range end.Threads {
+		prev, ok := start.Threads[tid]
+		if !ok {
+			continue
+		}
+		pid := end.ThreadPIDs[tid]
+		threads = append(threads, ThreadInfo{
+			TID:  tid,
+			PID:  pid,
+			Name: end.Processes[pid].Name,
+			CPU:  (cpuTime - prev) / elapsed.Seconds() * 100,
+		})
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		if threads[i].CPU != threads[j].CPU {
+			return threads[i].CPU > threads[j].CPU
+		}
+		return threads[i].TID < threads[j].TID
+	})
+	if len(threads) > n {
+		threads = threads[:n]
+	}
+	return threads
+}
+
+// Function to get the voluntary and involuntary context switches per second
+// between two reads. Only the processes found in both count, and a process
+// whose count went backwards, as when threads exit, counts as 0, so the
+// target losing processes in between does not make the rates wrap around.
+func ctxtSwitchRates(start, end TargetSample, elapsed time.Duration) (float64, float64) {
+	var voluntary, involuntary float64
+	for pid, c := range end.Ctxt {
+		prev, ok := start.Ctxt[pid]
+		if !ok {
+			continue
+		}
+		voluntary += counterRate(prev.Voluntary, c.Voluntary, elapsed)
+		involuntary += counterRate(prev.Involuntary, c.Involuntary, elapsed)
+	}
+	return voluntary, involuntary
+}
+
+// Function to collect and evaluate a check run scoped to --target-pid or
+// --target-unit. CPU usage is given as a percentage of one core, so a
+// multi-threaded target can exceed 100%.
+func runTargetCheck(progress *checkProgress) (*Result, error) {
+	progress.setStage("sampling the target")
+	pids, target, err := targetPIDs()
+	if err != nil {
+		return nil, fmt.Errorf("Error finding target processes: %v", err)
+	}
+
+	startClocks := readClocks()
+	start := readTargetSample(pids)
+	time.Sleep(plugin.intervalDuration)
+	end := readTargetSample(pids)
+	endClocks := readClocks()
+	elapsed := endClocks.Wall.Sub(startClocks.Wall)
+	if len(end.Processes) == 0 {
+		return nil, fmt.Errorf("Error sampling target: %s exited", target)
+	}
+
+	var processes []ProcessInfo
+	var used float64
+	for pid, p := range end.Processes {
+		prev, ok := start.Processes[pid]
+		if !ok {
+			continue
+		}
+		cpu := (p.CPUTime - prev.CPUTime) / elapsed.Seconds() * 100
+		used += cpu
+		processes = append(processes, ProcessInfo{PID: pid, CPU: cpu, Name: p.Name})
+	}
+	processes = topCPUProcesses(processes, 10)
+	threads := topTargetThreads(start, end, elapsed, 10)
+
+	metrics := []Metric{
+		{"target_cpu", used},
+		{"target_processes", float64(len(end.Processes))},
+		{"target_threads", float64(len(end.Threads))},
+	}
+	summary := fmt.Sprintf("%s: %.2f%% CPU across %d processes", target, used, len(end.Processes))
+	if end.HasCtxt && start.HasCtxt {
+		voluntary, involuntary := ctxtSwitchRates(start, end, elapsed)
+		metrics = append(metrics,
+			Metric{"target_ctxt_voluntary_per_sec", voluntary},
+			Metric{"target_ctxt_involuntary_per_sec", involuntary},
+		)
+	}
+	if start.Throttling != nil && end.Throttling != nil {
+		periods := end.Throttling.Throttled - start.Throttling.Throttled
+		metrics = append(metrics,
+			Metric{"target_throttled_periods", float64(periods)},
+			Metric{"target_throttled_seconds", (end.Throttling.Time - start.Throttling.Time).Seconds()},
+		)
+		if periods > 0 {
+			summary += fmt.Sprintf(", throttled in %d periods", periods)
+		}
+	}
+
+	progress.update("evaluating thresholds", Result{Summary: summary, Metrics: metrics, Processes: processes})
+
+	_, windows, err := readSuppressions()
+	if err != nil {
+		return nil, err
+	}
+	var eval Evaluation
+	if plugin.TargetCritical > 0 && used > plugin.TargetCritical {
+		eval.breach("target_critical", sensu.CheckStateCritical)
+	} else if plugin.TargetWarning > 0 && used > plugin.TargetWarning {
+		eval.breach("target_warning", sensu.CheckStateWarning)
+	}
+
+	anomaly := clockAnomaly(startClocks, endClocks)
+	metrics = append(metrics, Metric{"clock_anomaly_seconds", anomaly.Seconds()})
+	if anomaly > clockAnomalyTolerance {
+		eval = Evaluation{}
+		summary += fmt.Sprintf(" (clocks diverged by %s during the sample, suspected suspend; thresholds not evaluated)", anomaly.Round(time.Second))
+	} else if window, ok := activeSuppressWindow(windows, time.Now()); ok {
+		eval = Evaluation{}
+		summary += fmt.Sprintf(" (suppress window %s, status forced OK)", window.Spec)
+	}
+
+	var state State
+	if plugin.BreachCount > 1 && anomaly <= clockAnomalyTolerance {
+		if state, err = loadState(plugin.StateFile); err != nil {
+			return nil, fmt.Errorf("Error reading state file: %v", err)
+		}
+		if eval.dampen(&state, plugin.BreachCount) {
+			summary += fmt.Sprintf(" (threshold breached %d of %d consecutive runs)", state.ConsecutiveBreaches, plugin.BreachCount)
+		}
+		if err := saveState(plugin.StateFile, state); err != nil {
+			return nil, fmt.Errorf("Error writing state file: %v", err)
+		}
+	}
+
+	result := &Result{
+		Timestamp: time.Now(),
+		Status:    eval.Status,
+		Summary:   summary,
+		Metrics:   metrics,
+		Processes: processes,
+		Threads:   threads,
+		Breached:  eval.Breached,
+		Disabled:  plugin.disabled,
+	}
+	if eval.Status != sensu.CheckStateOK && len(processes) > 0 {
+		result.Fingerprint = alertFingerprint(eval.Breached, processes[0].Name)
+	}
+	return result, nil
+}
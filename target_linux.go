@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Function to find the cgroup hierarchy systemd places units in, which is
+// a hierarchy of its own on cgroup v1 hosts
+func unitCgroupBase() string {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err == nil {
+		return cgroupRoot
+	}
+	return filepath.Join(cgroupRoot, "systemd")
+}
+
+// Function to check that systemd units can be found on this host
+func probeUnitCgroups() error {
+	_, err := os.Stat(filepath.Join(unitCgroupBase(), "system.slice"))
+	return err
+}
+
+// Function to list the processes of a systemd unit from its cgroup
+func unitPIDs(unit string) ([]int32, error) {
+	base := unitCgroupBase()
+
+	dir := filepath.Join(base, "system.slice", unit)
+	if _, err := os.Stat(dir); err != nil {
+		dir = ""
+		// Units of other slices and user sessions are found by name
+		filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d.IsDir() && d.Name() == unit {
+				dir = path
+				return filepath.SkipAll
+			}
+			return nil
+		})
+		if dir == "" {
+			return nil, errors.New("no cgroup found for " + unit)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return nil, err
+	}
+	var pids []int32
+	for _, line := range strings.Fields(string(data)) {
+		pid, err := strconv.ParseInt(line, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		pids = append(pids, int32(pid))
+	}
+	return pids, nil
+}
+
+// Function to read the throttling counters of the cgroup a process runs in
+func readTargetThrottling(pid int32) (CgroupThrottling, error) {
+	f, err := os.Open(hostProc(strconv.Itoa(int(pid)), "cgroup"))
+	if err != nil {
+		return CgroupThrottling{}, err
+	}
+	v2, v1cpu, err := parseProcCgroup(f)
+	f.Close()
+	if err != nil {
+		return CgroupThrottling{}, err
+	}
+
+	var file string
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err == nil && v2 != "" {
+		file = filepath.Join(cgroupRoot, v2, "cpu.stat")
+	} else if base := cgroupMount("cpu,cpuacct", "cpu"); base != "" && v1cpu != "" {
+		file = filepath.Join(base, v1cpu, "cpu.stat")
+	} else {
+		return CgroupThrottling{}, errors.New("no cgroup cpu controller found")
+	}
+	cf, err := os.Open(file)
+	if err != nil {
+		return CgroupThrottling{}, err
+	}
+	defer cf.Close()
+	return parseCPUThrottling(cf)
+}
+
+// Function to sum the context switches of every thread of a process, as the
+// counts in /proc/<pid>/status only cover the main thread
+func readThreadCtxtSwitches(pid int32) (uint64, uint64, error) {
+	tasks, err := os.ReadDir(hostProc(strconv.Itoa(int(pid)), "task"))
+	if err != nil {
+		return 0, 0, err
+	}
+	var voluntary, involuntary uint64
+	for _, task := range tasks {
+		f, err := os.Open(hostProc(strconv.Itoa(int(pid)), "task", task.Name(), "status"))
+		if err != nil {
+			continue
+		}
+		v, i, err := parseCtxtSwitches(f)
+		f.Close()
+		if err != nil {
+			continue
+		}
+		voluntary += v
+		involuntary += i
+	}
+	return voluntary, involuntary, nil
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package main
+
+// Function to check that systemd units can be found on this host
+func probeUnitCgroups() error {
+	return errUnsupported
+}
+
+// Function to list the processes of a systemd unit from its cgroup
+func unitPIDs(unit string) ([]int32, error) {
+	return nil, errUnsupported
+}
+
+// Function to read the throttling counters of the cgroup a process runs in
+func readTargetThrottling(pid int32) (CgroupThrottling, error) {
+	return CgroupThrottling{}, errUnsupported
+}
+
+// Function to sum the context switches of every thread of a process
+func readThreadCtxtSwitches(pid int32) (uint64, uint64, error) {
+	return 0, 0, errUnsupported
+}
@@ -0,0 +1,43 @@
+//go:build !openbsd && !solaris
+
+package main
+
+import "github.com/shirou/gopsutil/v3/process"
+
+// Function to get the parent of every running process
+func processParents() (map[int32]int32, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[int32]int32, len(procs))
+	for _, p := range procs {
+		if ppid, err := p.Ppid(); err == nil {
+			parents[p.Pid] = ppid
+		}
+	}
+	return parents, nil
+}
+
+// Function to read the name and CPU time of a process of the target, along
+// with the CPU time of each of its threads where available
+func readTargetProcess(pid int32) (TargetProcess, map[int32]float64, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return TargetProcess{}, nil, err
+	}
+	times, err := p.Times()
+	if err != nil {
+		return TargetProcess{}, nil, err
+	}
+	name, _ := p.Name()
+
+	var threads map[int32]float64
+	if stats, err := p.Threads(); err == nil {
+		threads = make(map[int32]float64, len(stats))
+		for tid, t := range stats {
+			threads[tid] = t.User + t.System
+		}
+	}
+	return TargetProcess{Name: name, CPUTime: times.User + times.System}, threads, nil
+}
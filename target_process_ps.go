@@ -0,0 +1,35 @@
+//go:build openbsd || solaris
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Function to get the parent of every running process
+func processParents() (map[int32]int32, error) {
+	out, err := runPS(psEveryProcess, "-o", "pid=,ppid=")
+	if err != nil {
+		return nil, err
+	}
+	return parsePSParents(out)
+}
+
+// Function to read the name and CPU time of a process of the target.
+// Per-thread CPU time is not available from ps.
+func readTargetProcess(pid int32) (TargetProcess, map[int32]float64, error) {
+	out, err := runPS("-o", "pid=,time=,comm=", "-p", strconv.Itoa(int(pid)))
+	if err != nil {
+		return TargetProcess{}, nil, err
+	}
+	listed, err := parsePSProcesses(out)
+	if err != nil {
+		return TargetProcess{}, nil, err
+	}
+	p, ok := listed[pid]
+	if !ok {
+		return TargetProcess{}, nil, fmt.Errorf("PID %d not found", pid)
+	}
+	return TargetProcess{Name: p.Name, CPUTime: p.CPUTime}, nil, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCPUThrottling(t *testing.T) {
+	assert := assert.New(t)
+	v2, err := parseCPUThrottling(strings.NewReader("usage_usec 123456\nnr_periods 100\nnr_throttled 7\nthrottled_usec 250000\n"))
+	assert.NoError(err)
+	assert.Equal(CgroupThrottling{Periods: 100, Throttled: 7, Time: 250 * time.Millisecond}, v2)
+
+	v1, err := parseCPUThrottling(strings.NewReader("nr_periods 50\nnr_throttled 2\nthrottled_time 1500000000\n"))
+	assert.NoError(err)
+	assert.Equal(CgroupThrottling{Periods: 50, Throttled: 2, Time: 1500 * time.Millisecond}, v1)
+}
+
+func TestParseCtxtSwitches(t *testing.T) {
+	assert := assert.New(t)
+	voluntary, involuntary, err := parseCtxtSwitches(strings.NewReader("Name:\tnginx\nState:\tS (sleeping)\nvoluntary_ctxt_switches:\t1520\nnonvoluntary_ctxt_switches:\t37\n"))
+	assert.NoError(err)
+	assert.Equal(uint64(1520), voluntary)
+	assert.Equal(uint64(37), involuntary)
+}
+
+func TestDescendants(t *testing.T) {
+	assert := assert.New(t)
+	parents := map[int32]int32{1: 0, 10: 1, 11: 10, 12: 10, 13: 11, 20: 1}
+	assert.Equal([]int32{10, 11, 12, 13}, descendants(parents, 10))
+	assert.Equal([]int32{13}, descendants(parents, 13))
+}
+
+func TestTopTargetThreads(t *testing.T) {
+	assert := assert.New(t)
+	start := TargetSample{
+		Processes:  map[int32]TargetProcess{10: {Name: "java", CPUTime: 5}},
+		Threads:    map[int32]float64{10: 1, 11: 3},
+		ThreadPIDs: map[int32]int32{10: 10, 11: 10},
+	}
+	end := TargetSample{
+		Processes:  map[int32]TargetProcess{10: {Name: "java", CPUTime: 7}},
+		Threads:    map[int32]float64{10: 1.5, 11: 4.5, 12: 1},
+		ThreadPIDs: map[int32]int32{10: 10, 11: 10, 12: 10},
+	}
+	threads := topTargetThreads(start, end, 2*time.Second, 10)
+	assert.Equal([]ThreadInfo{
+		{TID: 11, PID: 10, Name: "java", CPU: 75},
+		{TID: 10, PID: 10, Name: "java", CPU: 25},
+	}, threads)
+	assert.Len(topTargetThreads(start, end, 2*time.Second, 1), 1)
+}
+
+func TestCtxtSwitchRates(t *testing.T) {
+	assert := assert.New(t)
+	start := TargetSample{Ctxt: map[int32]CtxtSwitches{
+		10: {Voluntary: 100, Involuntary: 10},
+		11: {Voluntary: 5000, Involuntary: 800},
+		12: {Voluntary: 40, Involuntary: 4},
+	}}
+	// 11 exited, 13 started and 12 lost a thread with most of its switches
+	end := TargetSample{Ctxt: map[int32]CtxtSwitches{
+		10: {Voluntary: 300, Involuntary: 30},
+		12: {Voluntary: 20, Involuntary: 6},
+		13: {Voluntary: 7000, Involuntary: 900},
+	}}
+	voluntary, involuntary := ctxtSwitchRates(start, end, 2*time.Second)
+	assert.Equal(float64(100), voluntary)
+	assert.Equal(float64(11), involuntary)
+
+	voluntary, involuntary = ctxtSwitchRates(start, TargetSample{}, 2*time.Second)
+	assert.Zero(voluntary)
+	assert.Zero(involuntary)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Function to parse the number of threads on the host from /proc/loadavg,
+// the total after the slash in its fourth field
+func parseLoadavgThreads(data string) (int, error) {
+	fields := strings.Fields(data)
+	if len(fields) < 4 {
+		return 0, fmt.Errorf("invalid loadavg line %q", data)
+	}
+	_, total, ok := strings.Cut(fields[3], "/")
+	if !ok {
+		return 0, fmt.Errorf("invalid loadavg line %q", data)
+	}
+	threads, err := strconv.Atoi(total)
+	if err != nil {
+		return 0, fmt.Errorf("invalid loadavg line %q", data)
+	}
+	return threads, nil
+}
+
+// Function to list the total process and thread count metrics, leaving the
+// thread count out when it is not known
+func taskMetrics(processes, threads int) []Metric {
+	metrics := []Metric{{"processes_total", float64(processes)}}
+	if threads >= 0 {
+		metrics = append(metrics, Metric{"threads_total", float64(threads)})
+	}
+	return metrics
+}
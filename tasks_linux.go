@@ -0,0 +1,13 @@
+package main
+
+import "os"
+
+// Function to read the number of threads on the host from /proc/loadavg,
+// which the kernel keeps without walking every process
+func readThreadCount() (int, error) {
+	data, err := os.ReadFile(hostProc("loadavg"))
+	if err != nil {
+		return 0, err
+	}
+	return parseLoadavgThreads(string(data))
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// Function to read the number of threads on the host
+func readThreadCount() (int, error) {
+	return 0, errUnsupported
+}
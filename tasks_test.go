@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLoadavgThreads(t *testing.T) {
+	assert := assert.New(t)
+	threads, err := parseLoadavgThreads("0.42 0.51 0.60 3/1287 98765\n")
+	assert.NoError(err)
+	assert.Equal(1287, threads)
+	_, err = parseLoadavgThreads("0.42 0.51 0.60")
+	assert.Error(err)
+	_, err = parseLoadavgThreads("0.42 0.51 0.60 1287 98765")
+	assert.Error(err)
+}
+
+func TestTaskMetrics(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]Metric{{"processes_total", 312}, {"threads_total", 1287}}, taskMetrics(312, 1287))
+	assert.Equal([]Metric{{"processes_total", 312}}, taskMetrics(312, -1))
+}
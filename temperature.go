@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Struct to hold the temperature of one CPU package in degrees Celsius
+type PackageTemperature struct {
+	Package int
+	Celsius float64
+}
+
+// Function to build the metrics of the package temperatures
+func temperatureMetrics(temps []PackageTemperature) []Metric {
+	var metrics []Metric
+	for _, t := range temps {
+		metrics = append(metrics, Metric{fmt.Sprintf("cpu_temperature_package%d_celsius", t.Package), t.Celsius})
+	}
+	return metrics
+}
+
+// Function to get the hottest CPU package
+func hottestPackage(temps []PackageTemperature) PackageTemperature {
+	var hottest PackageTemperature
+	for i, t := range temps {
+		if i == 0 || t.Celsius > hottest.Celsius {
+			hottest = t
+		}
+	}
+	return hottest
+}
+
+// Function to get the package number of a coretemp sensor label such as
+// "Package id 1"
+func coretempPackage(label string) (int, bool) {
+	var n int
+	if _, err := fmt.Sscanf(strings.TrimSpace(label), "Package id %d", &n); err != nil {
+		return 0, false
+	}
+	return n, true
+}
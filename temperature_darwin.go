@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// Function to read the CPU temperature from the SMC, from the die sensor or
+// else the proximity one. macOS machines have a single CPU package. The SMC
+// is only reachable from builds with cgo.
+func readCPUTemperatures() ([]PackageTemperature, error) {
+	sensors, err := host.SensorsTemperatures()
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]float64, len(sensors))
+	for _, s := range sensors {
+		if s.Temperature > 0 {
+			found[s.SensorKey] = s.Temperature
+		}
+	}
+	for _, key := range []string{"TC0D", "TC0P"} {
+		if c, ok := found[key]; ok {
+			return []PackageTemperature{{Package: 0, Celsius: c}}, nil
+		}
+	}
+	return nil, fmt.Errorf("no CPU temperature sensor found in the SMC")
+}
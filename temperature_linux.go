@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Function to read the temperature of every CPU package from hwmon, through
+// the coretemp driver on Intel and k10temp or zenpower on AMD. The AMD drivers
+// have one device per package, numbered in the order they are found, with the
+// die temperature (Tdie) preferred over the control one (Tctl).
+func readCPUTemperatures() ([]PackageTemperature, error) {
+	devices, err := filepath.Glob(hostSys("class", "hwmon", "hwmon*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(devices)
+	var temps []PackageTemperature
+	amdPackage := 0
+	for _, dev := range devices {
+		name, err := os.ReadFile(filepath.Join(dev, "name"))
+		if err != nil {
+			continue
+		}
+		labels, err := hwmonLabels(dev)
+		if err != nil {
+			return nil, err
+		}
+		switch strings.TrimSpace(string(name)) {
+		case "coretemp":
+			for label, input := range labels {
+				if n, ok := coretempPackage(label); ok {
+					c, err := readMilliCelsius(input)
+					if err != nil {
+						return nil, err
+					}
+					temps = append(temps, PackageTemperature{Package: n, Celsius: c})
+				}
+			}
+		case "k10temp", "zenpower":
+			input, ok := labels["Tdie"]
+			if !ok {
+				input, ok = labels["Tctl"]
+			}
+			if !ok {
+				continue
+			}
+			c, err := readMilliCelsius(input)
+			if err != nil {
+				return nil, err
+			}
+			temps = append(temps, PackageTemperature{Package: amdPackage, Celsius: c})
+			amdPackage++
+		}
+	}
+	if len(temps) == 0 {
+		return nil, fmt.Errorf("no coretemp, k10temp or zenpower sensors in %s", hostSys("class", "hwmon"))
+	}
+	sort.Slice(temps, func(i, j int) bool {
+		return temps[i].Package < temps[j].Package
+	})
+	return temps, nil
+}
+
+// Function to map the labels of the temperature sensors of a hwmon device to
+// their input files
+func hwmonLabels(dev string) (map[string]string, error) {
+	files, err := filepath.Glob(filepath.Join(dev, "temp*_label"))
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[string]string, len(files))
+	for _, file := range files {
+		label, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		labels[strings.TrimSpace(string(label))] = strings.TrimSuffix(file, "_label") + "_input"
+	}
+	return labels, nil
+}
+
+// Function to read a hwmon temperature in millidegrees Celsius
+func readMilliCelsius(file string) (float64, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil {
+		return 0, err
+	}
+	return v / 1000, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadCPUTemperatures(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	t.Setenv("HOST_SYS", root)
+	write := func(dev, file, content string) {
+		dir := filepath.Join(root, "class", "hwmon", dev)
+		assert.NoError(os.MkdirAll(dir, 0755))
+		assert.NoError(os.WriteFile(filepath.Join(dir, file), []byte(content+"\n"), 0644))
+	}
+
+	write("hwmon0", "name", "acpitz")
+	write("hwmon0", "temp1_input", "27800")
+	_, err := readCPUTemperatures()
+	assert.Error(err)
+
+	write("hwmon1", "name", "coretemp")
+	write("hwmon1", "temp1_label", "Package id 1")
+	write("hwmon1", "temp1_input", "71000")
+	write("hwmon1", "temp2_label", "Core 0")
+	write("hwmon1", "temp2_input", "90000")
+	write("hwmon2", "name", "coretemp")
+	write("hwmon2", "temp1_label", "Package id 0")
+	write("hwmon2", "temp1_input", "64500")
+	temps, err := readCPUTemperatures()
+	assert.NoError(err)
+	assert.Equal([]PackageTemperature{{0, 64.5}, {1, 71}}, temps)
+
+	// AMD packages are numbered in the order their devices are found
+	assert.NoError(os.RemoveAll(filepath.Join(root, "class")))
+	write("hwmon3", "name", "k10temp")
+	write("hwmon3", "temp1_label", "Tctl")
+	write("hwmon3", "temp1_input", "58000")
+	write("hwmon4", "name", "k10temp")
+	write("hwmon4", "temp1_label", "Tctl")
+	write("hwmon4", "temp1_input", "60000")
+	write("hwmon4", "temp2_label", "Tdie")
+	write("hwmon4", "temp2_input", "50000")
+	temps, err = readCPUTemperatures()
+	assert.NoError(err)
+	assert.Equal([]PackageTemperature{{0, 58}, {1, 50}}, temps)
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package main
+
+// Function to read the temperature of every CPU package
+func readCPUTemperatures() ([]PackageTemperature, error) {
+	return nil, errUnsupported
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemperatureMetrics(t *testing.T) {
+	assert := assert.New(t)
+	temps := []PackageTemperature{{0, 61.5}, {1, 78}}
+	assert.Equal([]Metric{
+		{"cpu_temperature_package0_celsius", 61.5},
+		{"cpu_temperature_package1_celsius", 78},
+	}, temperatureMetrics(temps))
+	assert.Equal(PackageTemperature{1, 78}, hottestPackage(temps))
+	assert.Equal(PackageTemperature{}, hottestPackage(nil))
+}
+
+func TestCoretempPackage(t *testing.T) {
+	assert := assert.New(t)
+	n, ok := coretempPackage("Package id 1\n")
+	assert.True(ok)
+	assert.Equal(1, n)
+	_, ok = coretempPackage("Core 3")
+	assert.False(ok)
+}
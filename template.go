@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Struct to hold what --output-template is executed with: every field of the
+// result, along with the values the default output is built from
+type TemplateData struct {
+	*Result
+	Name     string
+	State    string
+	PerfData string
+}
+
+// Functions available to output templates
+var templateFuncs = template.FuncMap{
+	"join":    strings.Join,
+	"rfc3339": func(t time.Time) string { return t.Format(time.RFC3339) },
+}
+
+// Function to parse an output template file
+func loadOutputTemplate(file string) (*template.Template, error) {
+	tmpl := template.New(file).Funcs(templateFuncs).Option("missingkey=error")
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.Parse(string(data))
+}
+
+// Function to format the human-readable check output with a template
+func formatTemplate(tmpl *template.Template, result *Result) (string, error) {
+	var out strings.Builder
+	err := tmpl.Execute(&out, TemplateData{
+		Result:   result,
+		Name:     plugin.PluginConfig.Name,
+		State:    stateLabel(result.Status),
+		PerfData: formatPerfData(result.Metrics),
+	})
+	return out.String(), err
+}
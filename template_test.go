@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTemplate(t *testing.T, text string) string {
+	file := filepath.Join(t.TempDir(), "alert.tmpl")
+	if err := os.WriteFile(file, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestFormatTemplate(t *testing.T) {
+	assert := assert.New(t)
+	tmpl, err := loadOutputTemplate(writeTemplate(t, `{{.Name}} {{.State}}: {{.Summary}} | {{.PerfData}}
+Breached: {{join .Breached ", "}} at {{rfc3339 .Timestamp}}
+{{range .Processes}}{{.Name}} {{printf "%.1f" .CPU}}
+{{end}}`))
+	if !assert.NoError(err) {
+		return
+	}
+	out, err := formatTemplate(tmpl, testResult())
+	assert.NoError(err)
+	assert.Equal("cpu-process-profiler Critical: 95.00% CPU usage | cpu_idle=5.00, cpu_user=95.00\n"+
+		"Breached: cpu_critical at 2024-09-02T12:00:00Z\n"+
+		"java 90.0\nbackup 5.0\n", out)
+}
+
+func TestLoadOutputTemplate(t *testing.T) {
+	assert := assert.New(t)
+	_, err := loadOutputTemplate(writeTemplate(t, "{{.Summary"))
+	assert.Error(err)
+	_, err = loadOutputTemplate(filepath.Join(t.TempDir(), "missing.tmpl"))
+	assert.Error(err)
+
+	// Fields that do not exist fail when the output is formatted
+	tmpl, err := loadOutputTemplate(writeTemplate(t, "{{.Nope}}"))
+	assert.NoError(err)
+	_, err = formatTemplate(tmpl, testResult())
+	assert.Error(err)
+}
@@ -0,0 +1,61 @@
+package main
+
+// Struct to hold one reading of the thermal throttling event counters, per
+// physical core keyed by package and core id ("0:3"), and per package
+type ThrottleCounts struct {
+	Core    map[string]float64
+	Package map[string]float64
+}
+
+// Struct to hold the throttling events counted during the interval
+type ThrottleEvents struct {
+	Core     float64
+	Package  float64
+	Cores    int
+	Packages int
+}
+
+// Function to count the throttling events between two readings, along with
+// how many cores and packages were throttled. A counter that went backwards,
+// such as after a CPU was brought back online, counts from zero.
+func throttleEvents(start, end ThrottleCounts) ThrottleEvents {
+	var events ThrottleEvents
+	delta := func(s, e float64) float64 {
+		if e < s {
+			return e
+		}
+		return e - s
+	}
+	for key, e := range end.Core {
+		if s, ok := start.Core[key]; ok {
+			if d := delta(s, e); d > 0 {
+				events.Core += d
+				events.Cores++
+			}
+		}
+	}
+	for key, e := range end.Package {
+		if s, ok := start.Package[key]; ok {
+			if d := delta(s, e); d > 0 {
+				events.Package += d
+				events.Packages++
+			}
+		}
+	}
+	return events
+}
+
+// Function to tell whether any throttling happened
+func (e ThrottleEvents) throttled() bool {
+	return e.Core > 0 || e.Package > 0
+}
+
+// Function to get the metrics of the throttling events
+func (e ThrottleEvents) metrics() []Metric {
+	return []Metric{
+		{"cpu_throttle_core_events", e.Core},
+		{"cpu_throttle_package_events", e.Package},
+		{"cpu_throttled_cores", float64(e.Cores)},
+		{"cpu_throttled_packages", float64(e.Packages)},
+	}
+}
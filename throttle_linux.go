@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Function to read the thermal throttling event counters the kernel keeps in
+// sysfs for every logical CPU. The core counter is shared by the SMT siblings
+// of a core and the package counter by every CPU of a package, so they are
+// keyed by the CPU topology to count each once.
+func readThrottleCounts() (ThrottleCounts, error) {
+	counts := ThrottleCounts{Core: map[string]float64{}, Package: map[string]float64{}}
+	dirs, err := filepath.Glob(hostSys("devices", "system", "cpu", "cpu[0-9]*", "thermal_throttle"))
+	if err != nil {
+		return counts, err
+	}
+	if len(dirs) == 0 {
+		return counts, fmt.Errorf("no thermal throttling counters in %s", hostSys("devices", "system", "cpu"))
+	}
+	for _, dir := range dirs {
+		topology := filepath.Join(filepath.Dir(dir), "topology")
+		pkg, err := readSysfsString(filepath.Join(topology, "physical_package_id"))
+		if err != nil {
+			return counts, err
+		}
+		core, err := readSysfsString(filepath.Join(topology, "core_id"))
+		if err != nil {
+			return counts, err
+		}
+		if counts.Core[pkg+":"+core], err = readCounter(filepath.Join(dir, "core_throttle_count")); err != nil {
+			return counts, err
+		}
+		if counts.Package[pkg], err = readCounter(filepath.Join(dir, "package_throttle_count")); err != nil {
+			return counts, err
+		}
+	}
+	return counts, nil
+}
+
+// Function to read a sysfs file holding a single value
+func readSysfsString(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Function to read a sysfs file holding a counter
+func readCounter(file string) (float64, error) {
+	s, err := readSysfsString(file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(s, 64)
+}
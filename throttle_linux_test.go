@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadThrottleCounts(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	t.Setenv("HOST_SYS", root)
+	write := func(cpu, file, content string) {
+		path := filepath.Join(root, "devices", "system", "cpu", cpu, file)
+		assert.NoError(os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(os.WriteFile(path, []byte(content+"\n"), 0644))
+	}
+
+	_, err := readThrottleCounts()
+	assert.Error(err)
+
+	// cpu0 and cpu1 are SMT siblings of core 0, cpu2 is core 1
+	for cpu, core := range map[string]string{"cpu0": "0", "cpu1": "0", "cpu2": "1"} {
+		write(cpu, "topology/physical_package_id", "0")
+		write(cpu, "topology/core_id", core)
+		write(cpu, "thermal_throttle/package_throttle_count", "12")
+	}
+	write("cpu0", "thermal_throttle/core_throttle_count", "3")
+	write("cpu1", "thermal_throttle/core_throttle_count", "3")
+	write("cpu2", "thermal_throttle/core_throttle_count", "0")
+	counts, err := readThrottleCounts()
+	assert.NoError(err)
+	assert.Equal(ThrottleCounts{
+		Core:    map[string]float64{"0:0": 3, "0:1": 0},
+		Package: map[string]float64{"0": 12},
+	}, counts)
+}
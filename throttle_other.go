@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// Function to read the thermal throttling event counters of every CPU
+func readThrottleCounts() (ThrottleCounts, error) {
+	return ThrottleCounts{}, errUnsupported
+}
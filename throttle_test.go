@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottleEvents(t *testing.T) {
+	assert := assert.New(t)
+	start := ThrottleCounts{
+		Core:    map[string]float64{"0:0": 10, "0:1": 4, "1:0": 7},
+		Package: map[string]float64{"0": 100, "1": 50},
+	}
+	end := ThrottleCounts{
+		Core:    map[string]float64{"0:0": 13, "0:1": 4, "1:0": 2, "1:1": 9},
+		Package: map[string]float64{"0": 100, "1": 50},
+	}
+	events := throttleEvents(start, end)
+	assert.Equal(ThrottleEvents{Core: 5, Cores: 2}, events)
+	assert.True(events.throttled())
+	assert.Equal([]Metric{
+		{"cpu_throttle_core_events", 5},
+		{"cpu_throttle_package_events", 0},
+		{"cpu_throttled_cores", 2},
+		{"cpu_throttled_packages", 0},
+	}, events.metrics())
+
+	assert.False(throttleEvents(start, start).throttled())
+}
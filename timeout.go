@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+)
+
+// Struct to hold what a check run has collected so far, so a run cut short
+// by --timeout can still report it
+type checkProgress struct {
+	mu     sync.Mutex
+	stage  string
+	result Result
+}
+
+// Function to record the stage a check run has reached
+func (p *checkProgress) setStage(stage string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stage = stage
+}
+
+// Function to record the stage a check run has reached along with the
+// results collected so far. The slices are copied as the run goes on to
+// append to them.
+func (p *checkProgress) update(stage string, r Result) {
+	r.Metrics = append([]Metric(nil), r.Metrics...)
+	r.Processes = append([]ProcessInfo(nil), r.Processes...)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stage, p.result = stage, r
+}
+
+// Function to build the UNKNOWN result of a run that did not finish within
+// the timeout, from what it had collected by then
+func (p *checkProgress) partial(timeout time.Duration, now time.Time) *Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := p.result
+	result.Timestamp = now
+	result.Status = sensu.CheckStateUnknown
+	result.Summary = fmt.Sprintf("check did not finish within %s while %s", timeout, p.stage)
+	if p.result.Summary != "" {
+		result.Summary += ", partial result: " + p.result.Summary
+	}
+	result.Breached, result.Fingerprint = nil, ""
+	result.Disabled = plugin.disabled
+	return &result
+}
+
+// Function to run the check, returning the partial result collected so far
+// as UNKNOWN when it does not finish within the timeout, rather than leaving
+// the agent to kill it without output. The run is abandoned, not stopped.
+func runCheckWithin(timeout time.Duration) (*Result, error) {
+	progress := &checkProgress{stage: "starting"}
+	if timeout <= 0 {
+		return runCheck(progress)
+	}
+
+	type outcome struct {
+		result *Result
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := runCheck(progress)
+		done <- outcome{result, err}
+	}()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-time.After(timeout):
+		return progress.partial(timeout, time.Now()), nil
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckProgressPartial(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2024, 9, 2, 12, 0, 0, 0, time.UTC)
+	progress := &checkProgress{}
+	progress.setStage("sampling CPU")
+	result := progress.partial(30*time.Second, now)
+	assert.Equal(sensu.CheckStateUnknown, result.Status)
+	assert.Equal("check did not finish within 30s while sampling CPU", result.Summary)
+	assert.Equal(now, result.Timestamp)
+
+	metrics := []Metric{{"cpu_idle", 40}}
+	progress.update("listing processes", Result{Summary: "60.00% CPU usage", Metrics: metrics})
+	metrics[0].Value = 0
+	result = progress.partial(30*time.Second, now)
+	assert.Equal("check did not finish within 30s while listing processes, partial result: 60.00% CPU usage", result.Summary)
+	assert.Equal([]Metric{{"cpu_idle", 40}}, result.Metrics)
+}
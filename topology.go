@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Function to get the usage of every physical core from the usage of its
+// logical CPUs, given the logical CPUs sharing each core. A core is taken to
+// be as busy as its busiest hardware thread, as SMT siblings share its
+// execution units: one sibling at 100% leaves little for the other.
+func physicalCoreUsage(cores []CoreUsage, siblings [][]int) []CoreUsage {
+	used := make(map[int]float64, len(cores))
+	for _, c := range cores {
+		if n, err := strconv.Atoi(strings.TrimPrefix(c.Name, "cpu")); err == nil {
+			used[n] = c.Used
+		}
+	}
+	var physical []CoreUsage
+	for _, group := range siblings {
+		var names []string
+		var busiest float64
+		found := false
+		for _, n := range group {
+			u, ok := used[n]
+			if !ok {
+				continue
+			}
+			names = append(names, fmt.Sprintf("cpu%d", n))
+			if !found || u > busiest {
+				busiest = u
+			}
+			found = true
+		}
+		if found {
+			physical = append(physical, CoreUsage{Name: strings.Join(names, "+"), Used: busiest})
+		}
+	}
+	return physical
+}
+
+// Function to get the average usage of a list of cores
+func averageCoreUsage(cores []CoreUsage) float64 {
+	if len(cores) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, c := range cores {
+		sum += c.Used
+	}
+	return sum / float64(len(cores))
+}
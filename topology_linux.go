@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Function to read the logical CPUs sharing each physical core from the
+// sysfs CPU topology, one group per core ordered by their first CPU
+func readCoreSiblings() ([][]int, error) {
+	files, err := filepath.Glob(hostSys("devices", "system", "cpu", "cpu[0-9]*", "topology", "thread_siblings_list"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no CPU topology found in %s", hostSys("devices", "system", "cpu"))
+	}
+	seen := make(map[string]bool, len(files))
+	var groups [][]int
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		cpus, err := parseCPUList(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		key := fmt.Sprint(cpus)
+		if len(cpus) > 0 && !seen[key] {
+			seen[key] = true
+			groups = append(groups, cpus)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i][0] < groups[j][0]
+	})
+	return groups, nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadCoreSiblings(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	t.Setenv("HOST_SYS", root)
+
+	_, err := readCoreSiblings()
+	assert.Error(err)
+
+	for cpu, siblings := range map[string]string{"cpu0": "0,2", "cpu1": "1-1,3", "cpu2": "0,2", "cpu3": "1,3", "cpu10": "10"} {
+		dir := filepath.Join(root, "devices", "system", "cpu", cpu, "topology")
+		assert.NoError(os.MkdirAll(dir, 0755))
+		assert.NoError(os.WriteFile(filepath.Join(dir, "thread_siblings_list"), []byte(siblings+"\n"), 0644))
+	}
+	groups, err := readCoreSiblings()
+	assert.NoError(err)
+	assert.Equal([][]int{{0, 2}, {1, 3}, {10}}, groups)
+}
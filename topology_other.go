@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// Function to read the logical CPUs sharing each physical core
+func readCoreSiblings() ([][]int, error) {
+	return nil, errUnsupported
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhysicalCoreUsage(t *testing.T) {
+	assert := assert.New(t)
+	cores := []CoreUsage{{"cpu0", 100}, {"cpu1", 20}, {"cpu2", 0}, {"cpu3", 30}}
+
+	// cpu0 and cpu2 share a core, as do cpu1 and cpu3; 37.5% logical is 65%
+	// physical
+	physical := physicalCoreUsage(cores, [][]int{{0, 2}, {1, 3}})
+	assert.Equal([]CoreUsage{{"cpu0+cpu2", 100}, {"cpu1+cpu3", 30}}, physical)
+	assert.Equal(float64(65), averageCoreUsage(physical))
+	assert.Equal(37.5, averageCoreUsage(cores))
+
+	// CPUs without timings are left out
+	physical = physicalCoreUsage(cores[:2], [][]int{{0}, {1}, {4, 5}})
+	assert.Equal([]CoreUsage{{"cpu0", 100}, {"cpu1", 20}}, physical)
+	assert.Equal(float64(0), averageCoreUsage(nil))
+}
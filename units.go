@@ -0,0 +1,157 @@
+package main
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+)
+
+// Kinds of services the CPU usage is reported for, which their metrics are
+// named after
+const (
+	unitKindSystemd = "unit"
+	unitKindWindows = "service"
+	unitKindLaunchd = "launchd"
+)
+
+// Struct to hold the CPU usage of the processes of a service, as a
+// percentage of one core. The service is a systemd unit or a Windows service
+// depending on its kind.
+type UnitUsage struct {
+	Kind      string  `json:"kind"`
+	Unit      string  `json:"unit"`
+	CPU       float64 `json:"cpu"`
+	Processes int     `json:"processes"`
+}
+
+// Function to sum the CPU usage of the processes of a service, given by PID
+func unitUsage(processList []ProcessInfo, kind, unit string, pids []int32) UnitUsage {
+	in := make(map[int32]bool, len(pids))
+	for _, pid := range pids {
+		in[pid] = true
+	}
+	usage := UnitUsage{Kind: kind, Unit: unit}
+	for _, p := range processList {
+		if in[p.PID] {
+			usage.CPU += p.CPU
+			usage.Processes++
+		}
+	}
+	return usage
+}
+
+// Function to parse the systemd unit a process belongs to from its cgroup
+// file in /proc, the deepest service or scope of its path in the systemd
+// hierarchy, which is the unified one on cgroup v2 hosts. Processes outside
+// of any unit, such as kernel threads, yield an empty name.
+func parseCgroupUnit(data string) string {
+	var cgroup string
+	for _, line := range strings.Split(data, "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[1] == "name=systemd" {
+			cgroup = parts[2]
+			break
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			cgroup = parts[2]
+		}
+	}
+	for cgroup != "/" && cgroup != "." && cgroup != "" {
+		name := path.Base(cgroup)
+		if strings.HasSuffix(name, ".service") || strings.HasSuffix(name, ".scope") {
+			return name
+		}
+		cgroup = path.Dir(cgroup)
+	}
+	return ""
+}
+
+// Function to sum the CPU usage of every process by the systemd unit it
+// belongs to, given by PID, the busiest units first. Processes outside of any
+// unit are left out.
+func unitRollup(processList []ProcessInfo, owners map[int32]string) []UnitUsage {
+	byUnit := make(map[string]*UnitUsage)
+	for _, p := range processList {
+		unit := owners[p.PID]
+		if unit == "" {
+			continue
+		}
+		u, ok := byUnit[unit]
+		if !ok {
+			u = &UnitUsage{Kind: unitKindSystemd, Unit: unit}
+			byUnit[unit] = u
+		}
+		u.CPU += p.CPU
+		u.Processes++
+	}
+	units := make([]UnitUsage, 0, len(byUnit))
+	for _, u := range byUnit {
+		units = append(units, *u)
+	}
+	sort.Slice(units, func(i, j int) bool {
+		if units[i].CPU != units[j].CPU {
+			return units[i].CPU > units[j].CPU
+		}
+		return units[i].Unit < units[j].Unit
+	})
+	return units
+}
+
+// Function to read the systemd unit of every process, skipping those whose
+// cgroup cannot be read
+func processUnits(processList []ProcessInfo) map[int32]string {
+	owners := make(map[int32]string, len(processList))
+	for _, p := range processList {
+		if unit, err := readCgroupUnit(p.PID); err == nil {
+			owners[p.PID] = unit
+		}
+	}
+	return owners
+}
+
+// Function to get the name the metrics of a service are emitted under, after
+// its kind and without the .service suffix most systemd units have
+func (u UnitUsage) metricName() string {
+	name := u.Unit
+	if u.Kind == unitKindSystemd {
+		name = strings.TrimSuffix(name, ".service")
+	}
+	return u.Kind + "_" + entityNameInvalid.ReplaceAllString(name, "_")
+}
+
+// Function to list the CPU usage and process count metrics of the units
+func unitMetrics(units []UnitUsage) []Metric {
+	metrics := make([]Metric, 0, 2*len(units))
+	for _, u := range units {
+		name := u.metricName()
+		metrics = append(metrics, Metric{name + "_cpu", u.CPU}, Metric{name + "_processes", float64(u.Processes)})
+	}
+	return metrics
+}
+
+// Function to evaluate the CPU usage of the units against the thresholds,
+// returning the worst state along with the busiest unit over a threshold
+func unitBreach(units []UnitUsage, warning, critical float64) (int, *UnitUsage) {
+	status := sensu.CheckStateOK
+	var offender *UnitUsage
+	for i, u := range units {
+		s := sensu.CheckStateOK
+		if critical > 0 && u.CPU > critical {
+			s = sensu.CheckStateCritical
+		} else if warning > 0 && u.CPU > warning {
+			s = sensu.CheckStateWarning
+		}
+		if s == sensu.CheckStateOK {
+			continue
+		}
+		if s > status || (s == status && u.CPU > offender.CPU) {
+			status, offender = s, &units[i]
+		}
+	}
+	return status, offender
+}
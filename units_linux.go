@@ -0,0 +1,15 @@
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+// Function to read the systemd unit of a process from /proc/<pid>/cgroup
+func readCgroupUnit(pid int32) (string, error) {
+	data, err := os.ReadFile(hostProc(strconv.Itoa(int(pid)), "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	return parseCgroupUnit(string(data)), nil
+}
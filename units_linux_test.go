@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadCgroupUnit(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	t.Setenv("HOST_PROC", root)
+	assert.NoError(os.MkdirAll(filepath.Join(root, "42"), 0755))
+	assert.NoError(os.WriteFile(filepath.Join(root, "42", "cgroup"), []byte("0::/system.slice/nginx.service\n"), 0644))
+
+	unit, err := readCgroupUnit(42)
+	assert.NoError(err)
+	assert.Equal("nginx.service", unit)
+	_, err = readCgroupUnit(43)
+	assert.Error(err)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// Function to read the systemd unit of a process
+func readCgroupUnit(pid int32) (string, error) {
+	return "", errUnsupported
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sensu-community/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitUsage(t *testing.T) {
+	assert := assert.New(t)
+	processList := []ProcessInfo{
+		{PID: 10, Name: "nginx", CPU: 2},
+		{PID: 11, Name: "nginx", CPU: 40},
+		{PID: 20, Name: "java", CPU: 90},
+	}
+	// PIDs that exited since the process list was taken are not counted
+	assert.Equal(UnitUsage{Kind: unitKindSystemd, Unit: "nginx.service", CPU: 42, Processes: 2}, unitUsage(processList, unitKindSystemd, "nginx.service", []int32{10, 11, 12}))
+}
+
+func TestUnitMetrics(t *testing.T) {
+	assert := assert.New(t)
+	units := []UnitUsage{
+		{Kind: unitKindSystemd, Unit: "nginx.service", CPU: 42, Processes: 2},
+		{Kind: unitKindSystemd, Unit: "user@1000.service", CPU: 1.5, Processes: 3},
+		{Kind: unitKindSystemd, Unit: "session-4.scope", CPU: 0.5, Processes: 1},
+		{Kind: unitKindWindows, Unit: "W3SVC", CPU: 12, Processes: 4},
+		{Kind: unitKindLaunchd, Unit: "com.openssh.sshd", CPU: 3, Processes: 1},
+	}
+	assert.Equal([]Metric{
+		{"unit_nginx_cpu", 42},
+		{"unit_nginx_processes", 2},
+		{"unit_user_1000_cpu", 1.5},
+		{"unit_user_1000_processes", 3},
+		{"unit_session-4.scope_cpu", 0.5},
+		{"unit_session-4.scope_processes", 1},
+		{"service_W3SVC_cpu", 12},
+		{"service_W3SVC_processes", 4},
+		{"launchd_com.openssh.sshd_cpu", 3},
+		{"launchd_com.openssh.sshd_processes", 1},
+	}, unitMetrics(units))
+}
+
+func TestUnitBreach(t *testing.T) {
+	assert := assert.New(t)
+	units := []UnitUsage{
+		{Kind: unitKindSystemd, Unit: "nginx.service", CPU: 42},
+		{Kind: unitKindSystemd, Unit: "postgresql.service", CPU: 180},
+	}
+	status, offender := unitBreach(units, 40, 150)
+	assert.Equal(sensu.CheckStateCritical, status)
+	assert.Equal("postgresql.service", offender.Unit)
+	status, offender = unitBreach(units, 0, 0)
+	assert.Equal(sensu.CheckStateOK, status)
+	assert.Nil(offender)
+}
+
+func TestParseCgroupUnit(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("nginx.service", parseCgroupUnit("0::/system.slice/nginx.service\n"))
+	assert.Equal("gnome-terminal-server.service", parseCgroupUnit("0::/user.slice/user-1000.slice/user@1000.service/app.slice/gnome-terminal-server.service\n"))
+	assert.Equal("docker-0123abcd.scope", parseCgroupUnit("0::/system.slice/docker-0123abcd.scope/init\n"))
+	// The systemd hierarchy wins over the unified one on hybrid hosts
+	assert.Equal("cron.service", parseCgroupUnit("12:name=systemd:/system.slice/cron.service\n4:memory:/system.slice\n0::/\n"))
+	assert.Equal("", parseCgroupUnit("0::/\n"))
+	assert.Equal("", parseCgroupUnit("0::/system.slice\n"))
+}
+
+func TestUnitRollup(t *testing.T) {
+	assert := assert.New(t)
+	processList := []ProcessInfo{
+		{PID: 2, Name: "kthreadd", CPU: 5},
+		{PID: 10, Name: "nginx", CPU: 2},
+		{PID: 11, Name: "nginx", CPU: 40},
+		{PID: 20, Name: "java", CPU: 90},
+	}
+	owners := map[int32]string{2: "", 10: "nginx.service", 11: "nginx.service", 20: "tomcat.service"}
+	assert.Equal([]UnitUsage{
+		{Kind: unitKindSystemd, Unit: "tomcat.service", CPU: 90, Processes: 1},
+		{Kind: unitKindSystemd, Unit: "nginx.service", CPU: 42, Processes: 2},
+	}, unitRollup(processList, owners))
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+// Function to list processes through WMI
+func wmiProcesses() ([]ProcessInfo, error) {
+	return nil, errUnsupported
+}
+
+// Function to check that processes can be listed through WMI
+func probeWMI() error {
+	return errUnsupported
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWMIProcesses(t *testing.T) {
+	assert := assert.New(t)
+	started := time.Date(2024, 9, 2, 12, 0, 0, 0, time.UTC).Local()
+	elapsed := uint64(started.UnixNano()/100) + filetimeUnixOffset
+	rows := []win32PerfRawProcess{
+		{Name: "Idle", IDProcess: 0, PercentProcessorTime: 1e9},
+		{Name: "_Total", IDProcess: 0, PercentProcessorTime: 2e9},
+		{Name: "svchost", IDProcess: 812, PercentProcessorTime: 25e6, ElapsedTime: elapsed},
+		{Name: "svchost#3", IDProcess: 1460, PercentProcessorTime: 5e6, ElapsedTime: elapsed},
+	}
+	assert.Equal([]ProcessInfo{
+		{PID: 812, Name: "svchost", CreatedAt: started, CPUTime: 2.5},
+		{PID: 1460, Name: "svchost", CreatedAt: started, CPUTime: 0.5},
+	}, parseWMIProcesses(rows))
+}
@@ -0,0 +1,19 @@
+package main
+
+import "github.com/StackExchange/wmi"
+
+// Function to list processes through WMI, for hosts where the native process
+// APIs are locked down
+func wmiProcesses() ([]ProcessInfo, error) {
+	var rows []win32PerfRawProcess
+	if err := wmi.Query("SELECT Name, IDProcess, PercentProcessorTime, ElapsedTime FROM Win32_PerfRawData_PerfProc_Process", &rows); err != nil {
+		return nil, err
+	}
+	return parseWMIProcesses(rows), nil
+}
+
+// Function to check that processes can be listed through WMI
+func probeWMI() error {
+	_, err := wmiProcesses()
+	return err
+}
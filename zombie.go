@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Struct to hold a process with zombie children it has not reaped
+type ZombieParent struct {
+	PID     int32  `json:"pid"`
+	Name    string `json:"name"`
+	Zombies int    `json:"zombies"`
+}
+
+// Function to parse the parent PID of a task from its stat file in /proc,
+// the field after the state
+func parseStatParent(data []byte) (int32, error) {
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return 0, fmt.Errorf("invalid stat line %q", data)
+	}
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("invalid stat line %q", data)
+	}
+	ppid, err := strconv.ParseInt(string(fields[1]), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid stat line %q", data)
+	}
+	return int32(ppid), nil
+}
+
+// Function to sort the parents of zombies by how many they have, the most
+// first, and keep the first n
+func topZombieParents(parents []ZombieParent, n int) []ZombieParent {
+	sort.Slice(parents, func(i, j int) bool {
+		if parents[i].Zombies != parents[j].Zombies {
+			return parents[i].Zombies > parents[j].Zombies
+		}
+		return parents[i].PID < parents[j].PID
+	})
+	if len(parents) > n {
+		parents = parents[:n]
+	}
+	return parents
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Function to read the zombie processes from /proc, grouped by the parent
+// that has not reaped them
+func readZombieParents() ([]ZombieParent, error) {
+	files, err := filepath.Glob(hostProc("[0-9]*", "stat"))
+	if err != nil {
+		return nil, err
+	}
+	byParent := make(map[int32]*ZombieParent)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			// The process exited or was reaped since
+			continue
+		}
+		if _, state, err := parseStatState(data); err != nil || state != 'Z' {
+			continue
+		}
+		ppid, err := parseStatParent(data)
+		if err != nil {
+			continue
+		}
+		parent, ok := byParent[ppid]
+		if !ok {
+			parent = &ZombieParent{PID: ppid}
+			if data, err := os.ReadFile(hostProc(strconv.Itoa(int(ppid)), "stat")); err == nil {
+				parent.Name, _, _ = parseStatState(data)
+			}
+			byParent[ppid] = parent
+		}
+		parent.Zombies++
+	}
+	parents := make([]ZombieParent, 0, len(byParent))
+	for _, p := range byParent {
+		parents = append(parents, *p)
+	}
+	return parents, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadZombieParents(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	t.Setenv("HOST_PROC", root)
+	write := func(pid, content string) {
+		assert.NoError(os.MkdirAll(filepath.Join(root, pid), 0755))
+		assert.NoError(os.WriteFile(filepath.Join(root, pid, "stat"), []byte(content), 0644))
+	}
+	write("10", "10 (ci-runner) S 1 10 10 0 -1")
+	write("11", "11 (make) Z 10 11 11 0 -1")
+	write("12", "12 (cc) Z 10 12 12 0 -1")
+	// The parent of this one has exited, so init has yet to reap it
+	write("13", "13 (sh) Z 99 13 13 0 -1")
+	write("14", "14 (sshd) S 1 14 14 0 -1")
+
+	parents, err := readZombieParents()
+	assert.NoError(err)
+	assert.ElementsMatch([]ZombieParent{
+		{PID: 10, Name: "ci-runner", Zombies: 2},
+		{PID: 99, Zombies: 1},
+	}, parents)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// Function to read the zombie processes, grouped by the parent that has not
+// reaped them
+func readZombieParents() ([]ZombieParent, error) {
+	return nil, errUnsupported
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStatParent(t *testing.T) {
+	assert := assert.New(t)
+	ppid, err := parseStatParent([]byte("4321 (worker) Z 1234 4321 4321 0 -1"))
+	assert.NoError(err)
+	assert.Equal(int32(1234), ppid)
+	_, err = parseStatParent([]byte("4321 (worker) Z"))
+	assert.Error(err)
+}
+
+func TestTopZombieParents(t *testing.T) {
+	assert := assert.New(t)
+	parents := []ZombieParent{
+		{PID: 30, Name: "cron", Zombies: 1},
+		{PID: 10, Name: "ci-runner", Zombies: 12},
+		{PID: 20, Name: "bash", Zombies: 1},
+	}
+	assert.Equal([]ZombieParent{
+		{PID: 10, Name: "ci-runner", Zombies: 12},
+		{PID: 20, Name: "bash", Zombies: 1},
+	}, topZombieParents(parents, 2))
+}